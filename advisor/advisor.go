@@ -0,0 +1,221 @@
+package advisor
+
+import (
+	"agent/data"
+	"agent/db"
+	"sync"
+)
+
+const (
+	// how long metric samples are kept to compare against settings
+	observationWindowSeconds = 24 * 60 * 60
+
+	// minimum observation time before recommendations are made
+	minObservationSeconds = 60 * 60
+
+	// reducing max_connections needs a longer observation window to see daily peaks
+	minConnectionObservationSeconds = 12 * 60 * 60
+
+	// recommendations are reported at most once per interval since they change slowly
+	adviseIntervalSeconds = 60 * 60
+)
+
+// metrics that are compared with settings
+var observedMetrics = map[string]bool{
+	"connections.used":     true,
+	"disk.temp.files":      true,
+	"disk.temp.bytes":      true,
+	"cache.table.hit.rate": true,
+}
+
+// Advisor observes settings and workload over time and compares them
+// to produce configuration tuning recommendations
+type Advisor struct {
+	servers       map[db.ServerID]*serverObservations
+	lastAdvisedAt int64
+	mu            sync.Mutex
+}
+
+type serverObservations struct {
+	serverID db.ServerID
+	platform string
+	settings map[string]db.Setting
+	samples  []sample
+	tables   []*db.Table
+
+	// latest heroku memory-total sample in bytes
+	memoryBytes int64
+}
+
+type sample struct {
+	name       string
+	value      float64
+	measuredAt int64
+}
+
+func New() *Advisor {
+	return &Advisor{
+		servers: make(map[db.ServerID]*serverObservations),
+	}
+}
+
+// Observe keeps the latest settings and schema and a window of workload metrics from reported data
+func (a *Advisor) Observe(d *data.Data, now int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, server := range d.PostgresServers {
+		if server.Platform != "" {
+			a.observations(*server.ServerID).platform = server.Platform
+		}
+	}
+
+	for _, setting := range d.Settings {
+		a.observations(*setting.ServerID).settings[setting.Name] = setting
+	}
+
+	for _, metric := range d.Metrics {
+		if !observedMetrics[metric.Name] {
+			continue
+		}
+
+		observations := a.observations(metric.ServerID)
+		observations.samples = append(observations.samples, sample{
+			name:       metric.Name,
+			value:      metric.Value,
+			measuredAt: metric.MeasuredAt,
+		})
+	}
+
+	for _, database := range d.Databases {
+		var tables []*db.Table
+		for _, schema := range database.Schemas {
+			tables = append(tables, schema.Tables...)
+		}
+		a.observations(*database.ServerID).tables = tables
+	}
+
+	for _, logMetrics := range d.LogMetrics {
+		memoryBytes, ok := parseHerokuBytes(logMetrics["memory-total"])
+		if !ok {
+			continue
+		}
+
+		for _, observations := range a.servers {
+			if isHerokuSource(logMetrics["source"], observations.serverID) {
+				observations.memoryBytes = memoryBytes
+			}
+		}
+	}
+
+	for _, observations := range a.servers {
+		observations.pruneSamples(now - observationWindowSeconds)
+	}
+}
+
+// Advise returns recommendations for all observed servers at most once per advise interval
+func (a *Advisor) Advise(now int64) []*data.Recommendation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if now-a.lastAdvisedAt < adviseIntervalSeconds {
+		return []*data.Recommendation{}
+	}
+	a.lastAdvisedAt = now
+
+	var recommendations []*data.Recommendation
+
+	for _, observations := range a.servers {
+		for _, rule := range rules {
+			recommendation := rule(observations)
+			if recommendation == nil {
+				continue
+			}
+
+			serverID := observations.serverID
+			recommendation.ServerID = &serverID
+			recommendation.CreatedAt = now
+			recommendations = append(recommendations, recommendation)
+		}
+	}
+
+	return recommendations
+}
+
+func (a *Advisor) observations(serverID db.ServerID) *serverObservations {
+	observations, ok := a.servers[serverID]
+	if !ok {
+		observations = &serverObservations{
+			serverID: serverID,
+			settings: make(map[string]db.Setting),
+		}
+		a.servers[serverID] = observations
+	}
+	return observations
+}
+
+func (o *serverObservations) pruneSamples(oldest int64) {
+	var samples []sample
+	for _, s := range o.samples {
+		if s.measuredAt >= oldest {
+			samples = append(samples, s)
+		}
+	}
+	o.samples = samples
+}
+
+// returns the number of seconds between the first and last metric samples
+func (o *serverObservations) observedSeconds() int64 {
+	if len(o.samples) == 0 {
+		return 0
+	}
+
+	first := o.samples[0].measuredAt
+	last := o.samples[0].measuredAt
+	for _, s := range o.samples {
+		if s.measuredAt < first {
+			first = s.measuredAt
+		}
+		if s.measuredAt > last {
+			last = s.measuredAt
+		}
+	}
+	return last - first
+}
+
+func (o *serverObservations) sum(name string) float64 {
+	var total float64
+	for _, s := range o.samples {
+		if s.name == name {
+			total += s.value
+		}
+	}
+	return total
+}
+
+func (o *serverObservations) max(name string) (float64, bool) {
+	var max float64
+	var found bool
+	for _, s := range o.samples {
+		if s.name == name && (!found || s.value > max) {
+			max = s.value
+			found = true
+		}
+	}
+	return max, found
+}
+
+func (o *serverObservations) average(name string) (float64, bool) {
+	var total float64
+	var count int
+	for _, s := range o.samples {
+		if s.name == name {
+			total += s.value
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
@@ -0,0 +1,79 @@
+package advisor
+
+import (
+	"agent/data"
+	"agent/db"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testServerID = db.ServerID{
+	ConfigName:    "GREEN",
+	ConfigVarName: "HEROKU_POSTGRESQL_GREEN_URL",
+	Database:      "test",
+}
+
+func testSetting(name string, value string, unit string) db.Setting {
+	setting := db.Setting{
+		ServerID: &testServerID,
+		Name:     name,
+		Value:    value,
+	}
+	if unit != "" {
+		setting.Unit = sql.NullString{Valid: true, String: unit}
+	}
+	return setting
+}
+
+func TestAdvisorObserve(t *testing.T) {
+	advisor := New()
+
+	advisor.Observe(&data.Data{
+		PostgresServers: []db.PostgresServer{{ServerID: &testServerID, Platform: db.HerokuPlatform}},
+		Settings:        []db.Setting{testSetting("work_mem", "4096", "kB")},
+		Metrics: []db.Metric{
+			{Name: "connections.used", Value: 10, ServerID: testServerID, MeasuredAt: 1000},
+			{Name: "query.rows.fetched", Value: 10, ServerID: testServerID, MeasuredAt: 1000},
+		},
+		LogMetrics: []data.LogMetrics{
+			{"source": "HEROKU_POSTGRESQL_GREEN", "memory-total": "31328356kB"},
+			{"source": "HEROKU_POSTGRESQL_RED", "memory-total": "1024kB"},
+		},
+	}, 1000)
+
+	observations := advisor.servers[testServerID]
+	assert.NotNil(t, observations)
+	assert.Equal(t, db.HerokuPlatform, observations.platform)
+	assert.Equal(t, "4096", observations.settings["work_mem"].Value)
+	// only metrics used by rules are kept
+	assert.Equal(t, 1, len(observations.samples))
+	assert.Equal(t, int64(31328356*1024), observations.memoryBytes)
+
+	// samples outside of the observation window are pruned
+	advisor.Observe(&data.Data{
+		Metrics: []db.Metric{{Name: "connections.used", Value: 12, ServerID: testServerID, MeasuredAt: 1000 + observationWindowSeconds + 1}},
+	}, 1000+observationWindowSeconds+1)
+	assert.Equal(t, 1, len(observations.samples))
+	assert.Equal(t, float64(12), observations.samples[0].value)
+}
+
+func TestAdvisorAdviseInterval(t *testing.T) {
+	advisor := New()
+
+	advisor.Observe(&data.Data{
+		PostgresServers: []db.PostgresServer{{ServerID: &testServerID, Platform: db.HerokuPlatform}},
+		Settings:        []db.Setting{testSetting("random_page_cost", "4", "")},
+	}, 1000)
+
+	recommendations := advisor.Advise(adviseIntervalSeconds)
+	assert.Equal(t, 1, len(recommendations))
+	assert.Equal(t, "random_page_cost", recommendations[0].Setting)
+	assert.Equal(t, "GREEN", recommendations[0].ServerID.ConfigName)
+	assert.Equal(t, int64(adviseIntervalSeconds), recommendations[0].CreatedAt)
+
+	// recommendations aren't repeated until the next interval
+	assert.Empty(t, advisor.Advise(adviseIntervalSeconds+60))
+	assert.Equal(t, 1, len(advisor.Advise(2*adviseIntervalSeconds)))
+}
@@ -0,0 +1,441 @@
+package advisor
+
+import (
+	"agent/data"
+	"agent/db"
+	"agent/util"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// temp files per hour before work_mem is considered too low
+	minTempFilesPerHour = 10
+
+	// fraction of memory that work_mem can use across all connections
+	workMemMemoryFraction = 0.25
+
+	// table cache hit rate below which shared_buffers is considered too low
+	minTableCacheHitRate = 0.99
+
+	// recommended shared_buffers as a fraction of memory
+	sharedBuffersMemoryFraction = 0.25
+
+	minSharedBuffersMemoryFraction = 0.15
+	maxSharedBuffersMemoryFraction = 0.4
+
+	// connection usage fractions of max_connections
+	highConnectionUsage = 0.9
+	lowConnectionUsage  = 0.25
+
+	// only recommend lowering max_connections when it's set high
+	minMaxConnectionsToLower = 200
+
+	// only large tables are checked for dead row build up
+	minAutovacuumTableRows = 1000000
+
+	recommendedAutovacuumScaleFactor = "0.01"
+
+	// random_page_cost for ssd backed storage
+	ssdRandomPageCost = 1.1
+
+	maxEvidenceTables = 5
+)
+
+type rule func(o *serverObservations) *data.Recommendation
+
+var rules = []rule{
+	workMemRule,
+	sharedBuffersRule,
+	maxConnectionsRule,
+	autovacuumScaleFactorRule,
+	randomPageCostRule,
+}
+
+// recommends raising work_mem when queries frequently spill to temp files
+func workMemRule(o *serverObservations) *data.Recommendation {
+	workMem, ok := o.settingBytes("work_mem")
+	if !ok {
+		return nil
+	}
+
+	seconds := o.observedSeconds()
+	if seconds < minObservationSeconds {
+		return nil
+	}
+
+	hours := float64(seconds) / 3600
+	tempFiles := o.sum("disk.temp.files")
+	tempBytes := o.sum("disk.temp.bytes")
+	if tempFiles/hours < minTempFilesPerHour {
+		return nil
+	}
+
+	averageTempFileBytes := int64(tempBytes / tempFiles)
+	if averageTempFileBytes <= workMem {
+		return nil
+	}
+
+	recommended := nextPowerOfTwo(averageTempFileBytes)
+
+	evidence := []data.Evidence{
+		{Name: "temp_files_per_hour", Value: formatFloat(tempFiles / hours)},
+		{Name: "temp_bytes_per_hour", Value: formatFloat(tempBytes / hours)},
+		{Name: "avg_temp_file_bytes", Value: strconv.FormatInt(averageTempFileBytes, 10)},
+	}
+
+	// cap work_mem so that all connections can't exhaust memory
+	maxConnections, ok := o.settingInt("max_connections")
+	if o.memoryBytes > 0 && ok && maxConnections > 0 {
+		limit := int64(float64(o.memoryBytes) * workMemMemoryFraction / float64(maxConnections))
+		if recommended > limit {
+			recommended = limit
+		}
+		evidence = append(evidence,
+			data.Evidence{Name: "memory_bytes", Value: strconv.FormatInt(o.memoryBytes, 10)},
+			data.Evidence{Name: "max_connections", Value: strconv.FormatInt(maxConnections, 10)},
+		)
+	}
+
+	if recommended <= workMem {
+		return nil
+	}
+
+	return &data.Recommendation{
+		Setting:          "work_mem",
+		CurrentValue:     formatBytes(workMem),
+		RecommendedValue: formatBytes(recommended),
+		Reason:           "Queries are frequently writing temp files larger than work_mem",
+		Evidence:         evidence,
+	}
+}
+
+// recommends sizing shared_buffers relative to memory
+func sharedBuffersRule(o *serverObservations) *data.Recommendation {
+	sharedBuffers, ok := o.settingBytes("shared_buffers")
+	if !ok || o.memoryBytes == 0 {
+		return nil
+	}
+
+	fraction := float64(sharedBuffers) / float64(o.memoryBytes)
+	recommended := int64(float64(o.memoryBytes) * sharedBuffersMemoryFraction)
+
+	evidence := []data.Evidence{
+		{Name: "memory_bytes", Value: strconv.FormatInt(o.memoryBytes, 10)},
+		{Name: "shared_buffers_memory_fraction", Value: formatFloat(fraction)},
+	}
+
+	if fraction > maxSharedBuffersMemoryFraction {
+		return &data.Recommendation{
+			Setting:          "shared_buffers",
+			CurrentValue:     formatBytes(sharedBuffers),
+			RecommendedValue: formatBytes(recommended),
+			Reason:           "shared_buffers is a large fraction of memory leaving little for the OS cache and connections",
+			Evidence:         evidence,
+		}
+	}
+
+	hitRate, ok := o.average("cache.table.hit.rate")
+	if !ok || o.observedSeconds() < minObservationSeconds {
+		return nil
+	}
+
+	if fraction < minSharedBuffersMemoryFraction && hitRate < minTableCacheHitRate {
+		return &data.Recommendation{
+			Setting:          "shared_buffers",
+			CurrentValue:     formatBytes(sharedBuffers),
+			RecommendedValue: formatBytes(recommended),
+			Reason:           "shared_buffers is a small fraction of memory and the table cache hit rate is low",
+			Evidence:         append(evidence, data.Evidence{Name: "table_cache_hit_rate", Value: formatFloat(hitRate)}),
+		}
+	}
+
+	return nil
+}
+
+// compares max_connections with peak connection usage
+func maxConnectionsRule(o *serverObservations) *data.Recommendation {
+	maxConnections, ok := o.settingInt("max_connections")
+	if !ok || maxConnections == 0 {
+		return nil
+	}
+
+	peak, ok := o.max("connections.used")
+	seconds := o.observedSeconds()
+	if !ok || seconds < minObservationSeconds {
+		return nil
+	}
+
+	usage := peak / float64(maxConnections)
+	evidence := []data.Evidence{
+		{Name: "peak_connections_used", Value: formatFloat(peak)},
+		{Name: "peak_connection_usage", Value: formatFloat(usage)},
+		{Name: "observed_seconds", Value: strconv.FormatInt(seconds, 10)},
+	}
+
+	if usage >= highConnectionUsage {
+		return &data.Recommendation{
+			Setting:          "max_connections",
+			CurrentValue:     strconv.FormatInt(maxConnections, 10),
+			RecommendedValue: strconv.FormatInt(int64(math.Ceil(peak*1.25)), 10),
+			Reason:           "Peak connections are close to max_connections - consider a connection pooler or raising the limit",
+			Evidence:         evidence,
+		}
+	}
+
+	if usage <= lowConnectionUsage && maxConnections >= minMaxConnectionsToLower && seconds >= minConnectionObservationSeconds {
+		return &data.Recommendation{
+			Setting:          "max_connections",
+			CurrentValue:     strconv.FormatInt(maxConnections, 10),
+			RecommendedValue: strconv.FormatInt(int64(math.Max(100, math.Ceil(peak*2))), 10),
+			Reason:           "Peak connections are far below max_connections which reserves memory per connection",
+			Evidence:         evidence,
+		}
+	}
+
+	return nil
+}
+
+// recommends a lower scale factor when large tables build up dead rows
+// beyond what autovacuum_vacuum_scale_factor allows
+func autovacuumScaleFactorRule(o *serverObservations) *data.Recommendation {
+	scaleFactor, ok := o.settingFloat("autovacuum_vacuum_scale_factor")
+	if !ok {
+		return nil
+	}
+	threshold, _ := o.settingFloat("autovacuum_vacuum_threshold")
+	recommendedScaleFactor, _ := strconv.ParseFloat(recommendedAutovacuumScaleFactor, 64)
+
+	// dead rows build up because autovacuum never runs and not because of the scale factor
+	if o.settings["autovacuum"].Value == "off" {
+		return nil
+	}
+
+	var tables []*db.Table
+	for _, table := range o.tables {
+		if table.LiveRowEstimate < minAutovacuumTableRows || table.StorageOptions["autovacuum_enabled"] == "false" {
+			continue
+		}
+
+		tableThreshold, tableScaleFactor := autovacuumVacuumSettings(table, threshold, scaleFactor)
+
+		// lowering the scale factor further won't help tables that already override it
+		if tableScaleFactor <= recommendedScaleFactor {
+			continue
+		}
+
+		// dead rows are well past the point where autovacuum should have run
+		if float64(table.DeadRowEstimate) > 2*(tableThreshold+tableScaleFactor*float64(table.LiveRowEstimate)) {
+			tables = append(tables, table)
+		}
+	}
+
+	if len(tables) == 0 {
+		return nil
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].DeadRowEstimate > tables[j].DeadRowEstimate
+	})
+
+	var evidence []data.Evidence
+	for i, table := range tables {
+		if i == maxEvidenceTables {
+			break
+		}
+		evidence = append(evidence, data.Evidence{
+			Name:   "dead_row_fraction",
+			Entity: "table/" + table.Schema + "." + table.Name,
+			Value:  formatFloat(float64(table.DeadRowEstimate) / float64(table.LiveRowEstimate)),
+		})
+	}
+
+	current := o.settings["autovacuum_vacuum_scale_factor"].Value
+
+	return &data.Recommendation{
+		Setting:          "autovacuum_vacuum_scale_factor",
+		CurrentValue:     current,
+		RecommendedValue: recommendedAutovacuumScaleFactor,
+		Reason:           "Large tables are building up dead rows - lower the scale factor on these tables with ALTER TABLE ... SET (autovacuum_vacuum_scale_factor = " + recommendedAutovacuumScaleFactor + ")",
+		Evidence:         evidence,
+	}
+}
+
+// autovacuum uses per table storage options over the global settings
+func autovacuumVacuumSettings(table *db.Table, threshold float64, scaleFactor float64) (float64, float64) {
+	if value, err := strconv.ParseFloat(table.StorageOptions["autovacuum_vacuum_threshold"], 64); err == nil {
+		threshold = value
+	}
+	if value, err := strconv.ParseFloat(table.StorageOptions["autovacuum_vacuum_scale_factor"], 64); err == nil {
+		scaleFactor = value
+	}
+	return threshold, scaleFactor
+}
+
+// recommends a lower random_page_cost on platforms backed by ssds
+func randomPageCostRule(o *serverObservations) *data.Recommendation {
+	if o.platform != db.HerokuPlatform {
+		return nil
+	}
+
+	randomPageCost, ok := o.settingFloat("random_page_cost")
+	if !ok || randomPageCost <= ssdRandomPageCost*2 {
+		return nil
+	}
+
+	return &data.Recommendation{
+		Setting:          "random_page_cost",
+		CurrentValue:     o.settings["random_page_cost"].Value,
+		RecommendedValue: formatFloat(ssdRandomPageCost),
+		Reason:           "Random reads on SSD storage cost about the same as sequential reads",
+		Evidence: []data.Evidence{
+			{Name: "platform", Value: o.platform},
+		},
+	}
+}
+
+func (o *serverObservations) settingInt(name string) (int64, bool) {
+	setting, ok := o.settings[name]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(setting.Value, 10, 64)
+	return value, err == nil
+}
+
+func (o *serverObservations) settingFloat(name string) (float64, bool) {
+	setting, ok := o.settings[name]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(setting.Value, 64)
+	return value, err == nil
+}
+
+// converts a memory setting to bytes using its unit - ex. 16384 with unit 8kB
+func (o *serverObservations) settingBytes(name string) (int64, bool) {
+	value, ok := o.settingInt(name)
+	if !ok {
+		return 0, false
+	}
+
+	setting := o.settings[name]
+	if !setting.Unit.Valid {
+		return 0, false
+	}
+
+	multiplier, ok := unitBytes(setting.Unit.String)
+	if !ok {
+		return 0, false
+	}
+
+	return value * multiplier, true
+}
+
+// https://www.postgresql.org/docs/current/config-setting.html#CONFIG-SETTING-NAMES-VALUES
+func unitBytes(unit string) (int64, bool) {
+	digits := strings.TrimRightFunc(unit, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	suffix := strings.TrimPrefix(unit, digits)
+
+	count := int64(1)
+	if digits != "" {
+		parsed, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		count = parsed
+	}
+
+	var multiplier int64
+	switch suffix {
+	case "B":
+		multiplier = 1
+	case "kB":
+		multiplier = 1024
+	case "MB":
+		multiplier = 1024 * 1024
+	case "GB":
+		multiplier = 1024 * 1024 * 1024
+	case "TB":
+		multiplier = 1024 * 1024 * 1024 * 1024
+	default:
+		return 0, false
+	}
+
+	return count * multiplier, true
+}
+
+// parses heroku sample sizes - ex. 31328356kB
+func parseHerokuBytes(value string) (int64, bool) {
+	digits := strings.TrimRightFunc(value, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if digits == "" {
+		return 0, false
+	}
+
+	count, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	multiplier, ok := unitBytes(strings.TrimPrefix(value, digits))
+	if !ok {
+		return 0, false
+	}
+
+	return count * multiplier, true
+}
+
+// heroku log metric sources are config var names without the _URL suffix
+// ex. HEROKU_POSTGRESQL_GREEN
+func isHerokuSource(source string, serverID db.ServerID) bool {
+	if source == "" {
+		return false
+	}
+	if source+"_URL" == serverID.ConfigVarName || strings.TrimPrefix(source, "HEROKU_POSTGRESQL_") == serverID.ConfigName {
+		return true
+	}
+
+	for _, alias := range serverID.Aliases() {
+		if source+"_URL" == alias {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nextPowerOfTwo(value int64) int64 {
+	power := int64(1)
+	for power < value {
+		power *= 2
+	}
+	return power
+}
+
+// formats bytes in the largest whole postgres memory unit - ex. 64MB
+func formatBytes(value int64) string {
+	units := []string{"TB", "GB", "MB", "kB"}
+	for _, unit := range units {
+		multiplier, _ := unitBytes(unit)
+		if value >= multiplier && value%multiplier == 0 {
+			return fmt.Sprintf("%d%s", value/multiplier, unit)
+		}
+	}
+
+	// round down to the nearest kB since settings don't accept bytes
+	if value >= 1024 {
+		return fmt.Sprintf("%dkB", value/1024)
+	}
+	return fmt.Sprintf("%dB", value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(util.Round4(value), 'f', -1, 64)
+}
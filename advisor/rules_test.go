@@ -0,0 +1,201 @@
+package advisor
+
+import (
+	"agent/data"
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testObservations(settings ...db.Setting) *serverObservations {
+	observations := &serverObservations{
+		serverID: testServerID,
+		settings: make(map[string]db.Setting),
+	}
+	for _, setting := range settings {
+		observations.settings[setting.Name] = setting
+	}
+	return observations
+}
+
+func TestWorkMemRule(t *testing.T) {
+	observations := testObservations(testSetting("work_mem", "4096", "kB"), testSetting("max_connections", "100", ""))
+
+	// 2 hours of samples with 40 x 20MB temp files
+	for i := int64(0); i <= 4; i++ {
+		observations.samples = append(observations.samples,
+			sample{name: "disk.temp.files", value: 10, measuredAt: i * 1800},
+			sample{name: "disk.temp.bytes", value: 10 * 20 * 1024 * 1024, measuredAt: i * 1800},
+		)
+	}
+
+	recommendation := workMemRule(observations)
+	assert.NotNil(t, recommendation)
+	assert.Equal(t, "4MB", recommendation.CurrentValue)
+	assert.Equal(t, "32MB", recommendation.RecommendedValue)
+	assert.Equal(t, data.Evidence{Name: "temp_files_per_hour", Value: "25"}, recommendation.Evidence[0])
+
+	// capped by memory across connections
+	observations.memoryBytes = 8 * 1024 * 1024 * 1024
+	recommendation = workMemRule(observations)
+	assert.Equal(t, "20971kB", recommendation.RecommendedValue)
+
+	// not enough observation time
+	observations.samples = observations.samples[:2]
+	assert.Nil(t, workMemRule(observations))
+}
+
+func TestSharedBuffersRule(t *testing.T) {
+	// 128MB
+	observations := testObservations(testSetting("shared_buffers", "16384", "8kB"))
+	assert.Nil(t, sharedBuffersRule(observations))
+
+	observations.memoryBytes = 4 * 1024 * 1024 * 1024
+	observations.samples = []sample{
+		{name: "cache.table.hit.rate", value: 0.95, measuredAt: 0},
+		{name: "cache.table.hit.rate", value: 0.97, measuredAt: 3600},
+	}
+
+	recommendation := sharedBuffersRule(observations)
+	assert.NotNil(t, recommendation)
+	assert.Equal(t, "128MB", recommendation.CurrentValue)
+	assert.Equal(t, "1GB", recommendation.RecommendedValue)
+	assert.Equal(t, data.Evidence{Name: "table_cache_hit_rate", Value: "0.96"}, recommendation.Evidence[2])
+
+	// high cache hit rate
+	observations.samples = []sample{
+		{name: "cache.table.hit.rate", value: 0.999, measuredAt: 0},
+		{name: "cache.table.hit.rate", value: 0.999, measuredAt: 3600},
+	}
+	assert.Nil(t, sharedBuffersRule(observations))
+
+	// 2GB is too large a fraction of memory
+	observations = testObservations(testSetting("shared_buffers", "262144", "8kB"))
+	observations.memoryBytes = 4 * 1024 * 1024 * 1024
+	recommendation = sharedBuffersRule(observations)
+	assert.Equal(t, "2GB", recommendation.CurrentValue)
+	assert.Equal(t, "1GB", recommendation.RecommendedValue)
+}
+
+func TestMaxConnectionsRule(t *testing.T) {
+	observations := testObservations(testSetting("max_connections", "100", ""))
+	observations.samples = []sample{
+		{name: "connections.used", value: 50, measuredAt: 0},
+		{name: "connections.used", value: 95, measuredAt: 3600},
+	}
+
+	recommendation := maxConnectionsRule(observations)
+	assert.NotNil(t, recommendation)
+	assert.Equal(t, "100", recommendation.CurrentValue)
+	assert.Equal(t, "119", recommendation.RecommendedValue)
+
+	observations = testObservations(testSetting("max_connections", "500", ""))
+	observations.samples = []sample{
+		{name: "connections.used", value: 30, measuredAt: 0},
+		{name: "connections.used", value: 40, measuredAt: 3600},
+	}
+
+	// not enough observation time to recommend lowering
+	assert.Nil(t, maxConnectionsRule(observations))
+
+	observations.samples = append(observations.samples, sample{name: "connections.used", value: 60, measuredAt: minConnectionObservationSeconds})
+	recommendation = maxConnectionsRule(observations)
+	assert.Equal(t, "500", recommendation.CurrentValue)
+	assert.Equal(t, "120", recommendation.RecommendedValue)
+}
+
+func TestAutovacuumScaleFactorRule(t *testing.T) {
+	observations := testObservations(testSetting("autovacuum_vacuum_scale_factor", "0.2", ""), testSetting("autovacuum_vacuum_threshold", "50", ""))
+	observations.tables = []*db.Table{
+		{Schema: "public", Name: "events", LiveRowEstimate: 10000000, DeadRowEstimate: 5000000},
+		{Schema: "public", Name: "users", LiveRowEstimate: 2000000, DeadRowEstimate: 100000},
+		{Schema: "public", Name: "small", LiveRowEstimate: 1000, DeadRowEstimate: 5000},
+	}
+
+	recommendation := autovacuumScaleFactorRule(observations)
+	assert.NotNil(t, recommendation)
+	assert.Equal(t, "0.2", recommendation.CurrentValue)
+	assert.Equal(t, "0.01", recommendation.RecommendedValue)
+	assert.Equal(t, []data.Evidence{{Name: "dead_row_fraction", Entity: "table/public.events", Value: "0.5"}}, recommendation.Evidence)
+
+	observations.tables = observations.tables[1:]
+	assert.Nil(t, autovacuumScaleFactorRule(observations))
+}
+
+func TestAutovacuumScaleFactorRuleTableOverrides(t *testing.T) {
+	observations := testObservations(testSetting("autovacuum_vacuum_scale_factor", "0.2", ""), testSetting("autovacuum_vacuum_threshold", "50", ""))
+	observations.tables = []*db.Table{
+		// already lowered on the table so dead rows come from something else - ex. long running transactions
+		{Schema: "public", Name: "events", LiveRowEstimate: 10000000, DeadRowEstimate: 5000000, StorageOptions: map[string]string{"autovacuum_vacuum_scale_factor": "0.005"}},
+		// autovacuum never runs for the table
+		{Schema: "public", Name: "archive", LiveRowEstimate: 10000000, DeadRowEstimate: 5000000, StorageOptions: map[string]string{"autovacuum_enabled": "false"}},
+	}
+	assert.Nil(t, autovacuumScaleFactorRule(observations))
+
+	// a table override that vacuums more often than the global setting would
+	observations.tables = []*db.Table{
+		{Schema: "public", Name: "users", LiveRowEstimate: 2000000, DeadRowEstimate: 500000, StorageOptions: map[string]string{"autovacuum_vacuum_scale_factor": "0.1", "autovacuum_vacuum_threshold": "1000"}},
+	}
+	recommendation := autovacuumScaleFactorRule(observations)
+	assert.NotNil(t, recommendation)
+	assert.Equal(t, []data.Evidence{{Name: "dead_row_fraction", Entity: "table/public.users", Value: "0.25"}}, recommendation.Evidence)
+
+	// no table is vacuumed when autovacuum is off
+	observations.settings["autovacuum"] = testSetting("autovacuum", "off", "")
+	assert.Nil(t, autovacuumScaleFactorRule(observations))
+}
+
+func TestRandomPageCostRule(t *testing.T) {
+	observations := testObservations(testSetting("random_page_cost", "4", ""))
+	assert.Nil(t, randomPageCostRule(observations))
+
+	observations.platform = db.HerokuPlatform
+	recommendation := randomPageCostRule(observations)
+	assert.Equal(t, "4", recommendation.CurrentValue)
+	assert.Equal(t, "1.1", recommendation.RecommendedValue)
+
+	observations.settings["random_page_cost"] = testSetting("random_page_cost", "1.1", "")
+	assert.Nil(t, randomPageCostRule(observations))
+}
+
+func TestUnitBytes(t *testing.T) {
+	bytes, ok := unitBytes("8kB")
+	assert.True(t, ok)
+	assert.Equal(t, int64(8192), bytes)
+
+	bytes, ok = unitBytes("MB")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1024*1024), bytes)
+
+	_, ok = unitBytes("ms")
+	assert.False(t, ok)
+}
+
+func TestParseHerokuBytes(t *testing.T) {
+	bytes, ok := parseHerokuBytes("242328kB")
+	assert.True(t, ok)
+	assert.Equal(t, int64(242328*1024), bytes)
+
+	_, ok = parseHerokuBytes("")
+	assert.False(t, ok)
+	_, ok = parseHerokuBytes("0.5")
+	assert.False(t, ok)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "4MB", formatBytes(4*1024*1024))
+	assert.Equal(t, "1GB", formatBytes(1024*1024*1024))
+	assert.Equal(t, "1536kB", formatBytes(1536*1024))
+	assert.Equal(t, "1kB", formatBytes(1500))
+	assert.Equal(t, "512B", formatBytes(512))
+}
+
+func TestIsHerokuSource(t *testing.T) {
+	serverID := db.ServerID{ConfigName: "GREEN", ConfigVarName: "HEROKU_POSTGRESQL_GREEN_URL", ConfigVarAliases: "DATABASE_URL"}
+
+	assert.True(t, isHerokuSource("HEROKU_POSTGRESQL_GREEN", serverID))
+	assert.True(t, isHerokuSource("DATABASE", serverID))
+	assert.False(t, isHerokuSource("HEROKU_POSTGRESQL_RED", serverID))
+	assert.False(t, isHerokuSource("", serverID))
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"agent/advisor"
 	"agent/api"
 	"agent/config"
 	"agent/data"
@@ -20,36 +21,64 @@ import (
 const maxBufferedRequests = 10
 
 type Agent struct {
-	config              config.Config
-	data                *data.Data
-	requests            *deque.Deque[*api.ReportRequest]
-	logMetricChannel    chan data.LogMetrics
-	logTestChannel      chan string
-	serverChannel       chan *db.PostgresServer
-	databaseChannel     chan *db.Database
-	replicationChannel  chan *db.Replication
-	metricsChannel      chan []*db.Metric
-	queryStatsChannel   chan []*db.QueryStats
-	settingsChannel     chan []*db.Setting
-	rawSlowQueryChannel chan *db.SlowQuery
-	stats               *util.Stats
+	config               config.Config
+	data                 *data.Data
+	advisor              *advisor.Advisor
+	requests             *deque.Deque[*api.ReportRequest]
+	logMetricChannel     chan data.LogMetrics
+	logTestChannel       chan string
+	serverChannel        chan *db.PostgresServer
+	databaseChannel      chan *db.Database
+	replicationChannel   chan *db.Replication
+	metricsChannel       chan []*db.Metric
+	queryStatsChannel    chan []*db.QueryStats
+	settingsChannel      chan *db.ServerSettings
+	securityChannel      chan *db.Security
+	serverEventChannel   chan *db.ServerEvent
+	rawSlowQueryChannel  chan *db.SlowQuery
+	rawTempFileChannel   chan *db.TempFile
+	tempFileChannel      chan *db.TempFile
+	rawVacuumChannel     chan *db.VacuumEvent
+	vacuumChannel        chan *db.VacuumEvent
+	rawCheckpointChannel chan *db.CheckpointEvent
+	rawLockChannel       chan *db.LockEvent
+	lockChannel          chan *db.LockEvent
+	rawLogErrorChannel   chan *db.LogError
+	logErrorChannel      chan *db.LogError
+	rawLoggedPlanChannel chan *db.LoggedPlan
+	logLinePrefixes      *db.LogLinePrefixRegistry
+	stats                *util.Stats
 }
 
 func New(config config.Config) *Agent {
 	return &Agent{
-		config:              config,
-		data:                &data.Data{},
-		requests:            deque.New[*api.ReportRequest](maxBufferedRequests, maxBufferedRequests),
-		logMetricChannel:    make(chan data.LogMetrics, 50),
-		logTestChannel:      make(chan string, 10),
-		serverChannel:       make(chan *db.PostgresServer, 25),
-		databaseChannel:     make(chan *db.Database, 25),
-		replicationChannel:  make(chan *db.Replication, 25),
-		metricsChannel:      make(chan []*db.Metric, 25),
-		queryStatsChannel:   make(chan []*db.QueryStats, 25),
-		settingsChannel:     make(chan []*db.Setting, 25),
-		rawSlowQueryChannel: make(chan *db.SlowQuery, 100),
-		stats:               &util.Stats{},
+		config:               config,
+		data:                 &data.Data{},
+		advisor:              advisor.New(),
+		requests:             deque.New[*api.ReportRequest](maxBufferedRequests, maxBufferedRequests),
+		logMetricChannel:     make(chan data.LogMetrics, 50),
+		logTestChannel:       make(chan string, 10),
+		serverChannel:        make(chan *db.PostgresServer, 25),
+		databaseChannel:      make(chan *db.Database, 25),
+		replicationChannel:   make(chan *db.Replication, 25),
+		metricsChannel:       make(chan []*db.Metric, 25),
+		queryStatsChannel:    make(chan []*db.QueryStats, 25),
+		settingsChannel:      make(chan *db.ServerSettings, 25),
+		securityChannel:      make(chan *db.Security, 25),
+		serverEventChannel:   make(chan *db.ServerEvent, 25),
+		rawSlowQueryChannel:  make(chan *db.SlowQuery, 100),
+		rawTempFileChannel:   make(chan *db.TempFile, 100),
+		tempFileChannel:      make(chan *db.TempFile, 100),
+		rawVacuumChannel:     make(chan *db.VacuumEvent, 100),
+		vacuumChannel:        make(chan *db.VacuumEvent, 100),
+		rawCheckpointChannel: make(chan *db.CheckpointEvent, 100),
+		rawLockChannel:       make(chan *db.LockEvent, 100),
+		lockChannel:          make(chan *db.LockEvent, 100),
+		rawLogErrorChannel:   make(chan *db.LogError, 100),
+		logErrorChannel:      make(chan *db.LogError, 100),
+		rawLoggedPlanChannel: make(chan *db.LoggedPlan, 100),
+		logLinePrefixes:      &db.LogLinePrefixRegistry{},
+		stats:                &util.Stats{},
 	}
 }
 
@@ -98,7 +127,19 @@ func (a *Agent) TestLogs() {
 }
 
 func (a *Agent) startServer() {
-	logsServer := logs.NewServer(a.config, a.logMetricChannel, a.logTestChannel, a.rawSlowQueryChannel, a.stats)
+	channels := logs.ServerChannels{
+		LogMetrics:     a.logMetricChannel,
+		LogTest:        a.logTestChannel,
+		RawSlowQueries: a.rawSlowQueryChannel,
+		RawTempFiles:   a.rawTempFileChannel,
+		RawVacuums:     a.rawVacuumChannel,
+		RawCheckpoints: a.rawCheckpointChannel,
+		RawLocks:       a.rawLockChannel,
+		RawLogErrors:   a.rawLogErrorChannel,
+		RawPlans:       a.rawLoggedPlanChannel,
+	}
+
+	logsServer := logs.NewServer(a.config, channels, a.logLinePrefixes, a.stats)
 	logsServer.Start() // doesn't return
 }
 
@@ -107,7 +148,29 @@ func (a *Agent) startPostgresObserver() {
 }
 
 func (a *Agent) newObserver() *db.Observer {
-	return db.NewObserver(a.config, a.serverChannel, a.databaseChannel, a.replicationChannel, a.metricsChannel, a.queryStatsChannel, a.settingsChannel, a.rawSlowQueryChannel)
+	channels := db.ObserverChannels{
+		Servers:         a.serverChannel,
+		Schemas:         a.databaseChannel,
+		Replications:    a.replicationChannel,
+		Metrics:         a.metricsChannel,
+		QueryStats:      a.queryStatsChannel,
+		Settings:        a.settingsChannel,
+		Security:        a.securityChannel,
+		ServerEvents:    a.serverEventChannel,
+		RawSlowQueries:  a.rawSlowQueryChannel,
+		RawTempFiles:    a.rawTempFileChannel,
+		TempFiles:       a.tempFileChannel,
+		RawVacuumEvents: a.rawVacuumChannel,
+		VacuumEvents:    a.vacuumChannel,
+		RawCheckpoints:  a.rawCheckpointChannel,
+		RawLockEvents:   a.rawLockChannel,
+		LockEvents:      a.lockChannel,
+		RawLogErrors:    a.rawLogErrorChannel,
+		LogErrors:       a.logErrorChannel,
+		RawLoggedPlans:  a.rawLoggedPlanChannel,
+	}
+
+	return db.NewObserver(a.config, channels, a.logLinePrefixes)
 }
 
 // runs forever
@@ -134,9 +197,24 @@ func (a *Agent) updateDataChannels() {
 		case metrics := <-a.metricsChannel:
 			a.data.AddMetrics(metrics)
 		case settings := <-a.settingsChannel:
-			a.data.AddSettings(settings)
+			a.data.AddSettings(settings.Settings)
+			a.data.AddSettingOverrides(settings.ServerID, settings.SettingOverrides)
+			a.data.AddHbaRules(settings.ServerID, settings.HbaRules)
+			a.data.AddSettingChanges(settings.SettingChanges)
+		case security := <-a.securityChannel:
+			a.data.AddSecurity(security)
+		case serverEvent := <-a.serverEventChannel:
+			a.data.AddServerEvent(serverEvent)
 		case stats := <-a.queryStatsChannel:
 			a.data.AddQueryStats(stats)
+		case tempFile := <-a.tempFileChannel:
+			a.data.AddTempFile(tempFile)
+		case vacuumEvent := <-a.vacuumChannel:
+			a.data.AddVacuumEvent(vacuumEvent)
+		case lockEvent := <-a.lockChannel:
+			a.data.AddLockEvent(lockEvent)
+		case logError := <-a.logErrorChannel:
+			a.data.AddLogError(logError)
 		case err := <-errors.ErrorsChannel:
 			a.data.AddErrorReport(err)
 		}
@@ -147,7 +225,13 @@ func (a *Agent) updateDataChannels() {
 func (a *Agent) sendRequest() {
 	d := a.data.CopyAndReset()
 	stats := a.stats.CopyAndReset()
-	request := api.NewReportRequest(a.config, d, time.Now().UTC().Unix(), stats)
+	now := time.Now().UTC().Unix()
+
+	// compare settings with observed workload
+	a.advisor.Observe(d, now)
+	d.AddRecommendations(a.advisor.Advise(now))
+
+	request := api.NewReportRequest(a.config, d, now, stats)
 
 	if request.Valid() {
 		// add latest request to buffered requests, sending the latest two requests each call to this method
@@ -33,6 +33,9 @@ type PostgresServer struct {
 	// ex. GREEN_URL
 	ConfigVarName string `json:"config_var_name"`
 
+	// ex. DATABASE_URL
+	ConfigVarAliases []string `json:"config_var_aliases,omitempty"`
+
 	Platform string `json:"platform,omitempty"`
 
 	Databases []*Database `json:"databases,omitempty"`
@@ -40,14 +43,25 @@ type PostgresServer struct {
 	Replica  *Replica         `json:"replica,omitempty"`
 	Replicas []*ReplicaClient `json:"replicas,omitempty"`
 
-	Metrics []*Metric `json:"metrics,omitempty"`
-	Queries *Queries  `json:"queries,omitempty"`
-
-	MaxConnections int64      `json:"max_connections,omitempty"`
-	PgBouncer      *PgBouncer `json:"pg_bouncer,omitempty"`
-	Settings       []*Setting `json:"settings,omitempty"`
-	Version        string     `json:"version"`
-	MonitoredAt    int64      `json:"monitored_at"`
+	Metrics    []*Metric      `json:"metrics,omitempty"`
+	Queries    *Queries       `json:"queries,omitempty"`
+	Vacuums    []*TableVacuum `json:"vacuums,omitempty"`
+	LockEvents []*LockEvent   `json:"lock_events,omitempty"`
+	LogErrors  []*LogError    `json:"log_errors,omitempty"`
+
+	MaxConnections int64        `json:"max_connections,omitempty"`
+	PgBouncer      *PgBouncer   `json:"pg_bouncer,omitempty"`
+	Extensions     []*Extension `json:"extensions,omitempty"`
+	Settings       []*Setting   `json:"settings,omitempty"`
+
+	SettingOverrides []*SettingOverride `json:"setting_overrides,omitempty"`
+	HbaRules         []*HbaRule         `json:"hba_rules,omitempty"`
+	SettingChanges   []*SettingChange   `json:"setting_changes,omitempty"`
+	Recommendations  []*Recommendation  `json:"recommendations,omitempty"`
+	Events           []*ServerEvent     `json:"events,omitempty"`
+	Security         *Security          `json:"security,omitempty"`
+	Version          string             `json:"version"`
+	MonitoredAt      int64              `json:"monitored_at"`
 }
 
 type PgBouncer struct {
@@ -55,6 +69,15 @@ type PgBouncer struct {
 	Version              string `json:"version,omitempty"`
 }
 
+type Extension struct {
+	Database         string `json:"database,omitempty"`
+	Name             string `json:"name"`
+	Schema           string `json:"schema,omitempty"`
+	Version          string `json:"version,omitempty"`
+	LatestVersion    string `json:"latest_version,omitempty"`
+	UpgradeAvailable bool   `json:"upgrade_available,omitempty"`
+}
+
 type Metric struct {
 	Name   string        `json:"name"`
 	Entity string        `json:"entity,omitempty"`
@@ -67,7 +90,83 @@ type MetricValue struct {
 }
 
 type Queries struct {
-	Stats []*Query `json:"stats,omitempty"`
+	Stats      []*Query     `json:"stats,omitempty"`
+	TempSpills []*TempSpill `json:"temp_spills,omitempty"`
+}
+
+// queries that spilled sorts or hashes to temp files
+type TempSpill struct {
+	Fingerprint             string `json:"fingerprint"`
+	Query                   string `json:"query,omitempty"`
+	Calls                   int64  `json:"calls,omitempty"`
+	TempBytesWritten        int64  `json:"temp_bytes_written,omitempty"`
+	TempFiles               int64  `json:"temp_files,omitempty"`
+	TempFileBytes           int64  `json:"temp_file_bytes,omitempty"`
+	MaxTempFileBytes        int64  `json:"max_temp_file_bytes,omitempty"`
+	RecommendedWorkMemBytes int64  `json:"recommended_work_mem_bytes,omitempty"`
+}
+
+// autovacuum and autoanalyze runs per table parsed from logs
+type TableVacuum struct {
+	Database                string  `json:"database,omitempty"`
+	Schema                  string  `json:"schema"`
+	Table                   string  `json:"table"`
+	Vacuums                 int64   `json:"vacuums,omitempty"`
+	AggressiveVacuums       int64   `json:"aggressive_vacuums,omitempty"`
+	WraparoundVacuums       int64   `json:"wraparound_vacuums,omitempty"`
+	VacuumElapsedSeconds    float64 `json:"vacuum_elapsed_seconds,omitempty"`
+	MaxVacuumElapsedSeconds float64 `json:"max_vacuum_elapsed_seconds,omitempty"`
+	Analyzes                int64   `json:"analyzes,omitempty"`
+	AnalyzeElapsedSeconds   float64 `json:"analyze_elapsed_seconds,omitempty"`
+	PagesRemoved            int64   `json:"pages_removed,omitempty"`
+	TuplesRemoved           int64   `json:"tuples_removed,omitempty"`
+	TuplesDeadNotRemovable  int64   `json:"tuples_dead_not_removable,omitempty"`
+	BufferMisses            int64   `json:"buffer_misses,omitempty"`
+	BufferDirtied           int64   `json:"buffer_dirtied,omitempty"`
+	WalBytes                int64   `json:"wal_bytes,omitempty"`
+	LastVacuumAt            int64   `json:"last_vacuum_at,omitempty"`
+	LastAnalyzeAt           int64   `json:"last_analyze_at,omitempty"`
+}
+
+// lock waits and deadlocks parsed from logs
+type LockEvent struct {
+	Kind          string             `json:"kind"`
+	LockMode      string             `json:"lock_mode,omitempty"`
+	LockObject    string             `json:"lock_object,omitempty"`
+	WaitMs        float64            `json:"wait_ms,omitempty"`
+	HolderPids    []int64            `json:"holder_pids,omitempty"`
+	WaitQueuePids []int64            `json:"wait_queue_pids,omitempty"`
+	Participants  []*LockParticipant `json:"participants,omitempty"`
+	Pid           int64              `json:"pid,omitempty"`
+	Database      string             `json:"database,omitempty"`
+	User          string             `json:"user,omitempty"`
+	Application   string             `json:"application,omitempty"`
+	MeasuredAt    int64              `json:"measured_at"`
+}
+
+type LockParticipant struct {
+	Pid          int64  `json:"pid"`
+	LockMode     string `json:"lock_mode,omitempty"`
+	LockObject   string `json:"lock_object,omitempty"`
+	BlockedByPid int64  `json:"blocked_by_pid,omitempty"`
+	Fingerprint  string `json:"fingerprint,omitempty"`
+	Query        string `json:"query,omitempty"`
+}
+
+// errors parsed from logs grouped by sqlstate and message template
+type LogError struct {
+	Severity     string            `json:"severity"`
+	SqlState     string            `json:"sql_state,omitempty"`
+	Message      string            `json:"message"`
+	Count        int64             `json:"count"`
+	Samples      []*LogErrorSample `json:"samples,omitempty"`
+	FirstErrorAt int64             `json:"first_error_at,omitempty"`
+	LastErrorAt  int64             `json:"last_error_at,omitempty"`
+}
+
+type LogErrorSample struct {
+	Fingerprint string `json:"fingerprint"`
+	Query       string `json:"query,omitempty"`
 }
 
 type Query struct {
@@ -106,8 +205,12 @@ type Database struct {
 }
 
 type Schema struct {
-	Name   string   `json:"name"`
-	Tables []*Table `json:"tables,omitempty"`
+	Name              string              `json:"name"`
+	Tables            []*Table            `json:"tables,omitempty"`
+	Views             []*View             `json:"views,omitempty"`
+	MaterializedViews []*MaterializedView `json:"materialized_views,omitempty"`
+	Triggers          []*Trigger          `json:"triggers,omitempty"`
+	Types             []*CustomType       `json:"types,omitempty"`
 }
 
 type Table struct {
@@ -152,9 +255,26 @@ type Table struct {
 	DiskToastBlocksHit       int64   `json:"toast_blocks_hit,omitempty"`
 	DiskToastIndexBlocksRead int64   `json:"toast_index_blocks_read,omitempty"`
 	DiskToastIndexBlocksHit  int64   `json:"toast_index_blocks_hit,omitempty"`
+	HotUpdatedRows           int64   `json:"hot_updated_rows,omitempty"`
+	HotUpdateRatio           float64 `json:"hot_update_ratio,omitempty"`
+	HotUpdateAdvice          string  `json:"hot_update_advice,omitempty"`
+	Fillfactor               int64   `json:"fillfactor,omitempty"`
 
-	Columns []*Column `json:"columns,omitempty"`
-	Indexes []*Index  `json:"indexes,omitempty"`
+	StorageOptions map[string]string `json:"storage_options,omitempty"`
+
+	AutoanalyzeThreshold int64 `json:"autoanalyze_threshold,omitempty"`
+	StatisticsStale      bool  `json:"statistics_stale,omitempty"`
+
+	Columns            []*Column            `json:"columns,omitempty"`
+	Indexes            []*Index             `json:"indexes,omitempty"`
+	ExtendedStatistics []*ExtendedStatistic `json:"extended_statistics,omitempty"`
+}
+
+type ExtendedStatistic struct {
+	Name    string   `json:"name"`
+	Schema  string   `json:"schema,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+	Kinds   []string `json:"kinds,omitempty"`
 }
 
 type Column struct {
@@ -167,6 +287,11 @@ type Column struct {
 	NumericScale     int    `json:"scale,omitempty"`
 	IntervalType     string `json:"interval_type,omitempty"`
 	IsIdentity       bool   `json:"is_identity,omitempty"`
+
+	// nil when the column hasn't been analyzed since 0 is a meaningful value
+	NullFraction   *float64 `json:"null_frac,omitempty"`
+	DistinctValues *float64 `json:"n_distinct,omitempty"`
+	Correlation    *float64 `json:"correlation,omitempty"`
 }
 
 type Index struct {
@@ -185,6 +310,37 @@ type Index struct {
 	Definition      string  `json:"definition,omitempty"`
 }
 
+type View struct {
+	Name         string   `json:"name"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+type MaterializedView struct {
+	Name            string   `json:"name"`
+	Bytes           int64    `json:"bytes,omitempty"`
+	Populated       bool     `json:"populated"`
+	Dependencies    []string `json:"dependencies,omitempty"`
+	LastRefreshedAt int64    `json:"last_refreshed_at,omitempty"`
+}
+
+type Trigger struct {
+	Name      string   `json:"name"`
+	TableName string   `json:"table_name"`
+	Timing    string   `json:"timing,omitempty"`
+	Events    []string `json:"events,omitempty"`
+	Level     string   `json:"level,omitempty"`
+	Enabled   string   `json:"enabled,omitempty"`
+	Function  string   `json:"function,omitempty"`
+}
+
+type CustomType struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Values     []string `json:"values,omitempty"`
+	BaseType   string   `json:"base_type,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+}
+
 type Agent struct {
 	UUID    string   `json:"uuid"`
 	Version string   `json:"version"`
@@ -204,6 +360,23 @@ type LogStats struct {
 	MetricsLinesDropped int `json:"metrics_dropped,omitempty"`
 	SlowQueries         int `json:"slow_queries,omitempty"`
 	SlowQueriesDropped  int `json:"slow_queries_dropped,omitempty"`
+	TempFiles           int `json:"temp_files,omitempty"`
+	TempFilesDropped    int `json:"temp_files_dropped,omitempty"`
+	SyslogInvalid       int `json:"syslog_invalid,omitempty"`
+	SyslogUnmapped      int `json:"syslog_unmapped,omitempty"`
+	LogplexInvalid      int `json:"logplex_invalid,omitempty"`
+	LogplexCountInvalid int `json:"logplex_count_invalid,omitempty"`
+	Rejected            int `json:"rejected,omitempty"`
+	Vacuums             int `json:"vacuums,omitempty"`
+	VacuumsDropped      int `json:"vacuums_dropped,omitempty"`
+	Checkpoints         int `json:"checkpoints,omitempty"`
+	CheckpointsDropped  int `json:"checkpoints_dropped,omitempty"`
+	Locks               int `json:"locks,omitempty"`
+	LocksDropped        int `json:"locks_dropped,omitempty"`
+	Errors              int `json:"errors,omitempty"`
+	ErrorsDropped       int `json:"errors_dropped,omitempty"`
+	Plans               int `json:"plans,omitempty"`
+	PlansDropped        int `json:"plans_dropped,omitempty"`
 }
 
 // NOTE: we do not want to expose replica server or client hostnames, IPs or ports
@@ -211,6 +384,10 @@ type Replica struct {
 	ApplicationName   string `json:"application_name,omitempty"`
 	PrimaryConfigName string `json:"primary_config_name,omitempty"` // ex. GREEN
 	Status            string `json:"status,omitempty"`
+	ReplayPaused      bool   `json:"replay_paused,omitempty"`
+	ReceiveLsn        string `json:"receive_lsn,omitempty"`
+	ReplayLsn         string `json:"replay_lsn,omitempty"`
+	LastReplayedAt    int64  `json:"last_replayed_at,omitempty"`
 }
 
 // we track agent errors and panics to proactively be aware of agent issues
@@ -246,10 +423,111 @@ type Setting struct {
 	PendingRestart bool   `json:"pending_restart"`
 }
 
+type SettingOverride struct {
+	Role     string `json:"role,omitempty"`
+	Database string `json:"database,omitempty"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+}
+
+type HbaRule struct {
+	LineNumber int64    `json:"line_number,omitempty"`
+	Type       string   `json:"type,omitempty"`
+	Databases  []string `json:"databases,omitempty"`
+	Users      []string `json:"users,omitempty"`
+	Address    string   `json:"address,omitempty"`
+	Netmask    string   `json:"netmask,omitempty"`
+	AuthMethod string   `json:"auth_method,omitempty"`
+	Options    []string `json:"options,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type SettingChange struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	OldValue   string `json:"old_value,omitempty"`
+	NewValue   string `json:"new_value,omitempty"`
+	Source     string `json:"source,omitempty"`
+	DetectedAt int64  `json:"detected_at"`
+}
+
+type Recommendation struct {
+	Setting          string      `json:"setting"`
+	CurrentValue     string      `json:"current_value"`
+	RecommendedValue string      `json:"recommended_value"`
+	Reason           string      `json:"reason"`
+	Evidence         []*Evidence `json:"evidence,omitempty"`
+	CreatedAt        int64       `json:"created_at"`
+}
+
+type Evidence struct {
+	Name   string `json:"name"`
+	Entity string `json:"entity,omitempty"`
+	Value  string `json:"value"`
+}
+
+type ServerEvent struct {
+	Type          string `json:"type"`
+	PreviousValue string `json:"previous_value,omitempty"`
+	Value         string `json:"value,omitempty"`
+	DetectedAt    int64  `json:"detected_at"`
+}
+
+type Security struct {
+	Roles                    []*Role                    `json:"roles,omitempty"`
+	RoleMemberships          []*RoleMembership          `json:"role_memberships,omitempty"`
+	PublicPrivileges         []*PublicPrivilege         `json:"public_privileges,omitempty"`
+	SecurityDefinerFunctions []*SecurityDefinerFunction `json:"security_definer_functions,omitempty"`
+	LoginRoleOwnedObjects    []*OwnedObject             `json:"login_role_owned_objects,omitempty"`
+
+	LoginRoleOwnedObjectsTruncated bool `json:"login_role_owned_objects_truncated,omitempty"`
+
+	MeasuredAt int64 `json:"measured_at"`
+}
+
+type Role struct {
+	Name            string `json:"name"`
+	Superuser       bool   `json:"superuser"`
+	CreateRole      bool   `json:"create_role"`
+	CreateDB        bool   `json:"create_db"`
+	BypassRLS       bool   `json:"bypass_rls"`
+	Replication     bool   `json:"replication"`
+	Login           bool   `json:"login"`
+	ValidUntil      int64  `json:"valid_until,omitempty"`
+	ConnectionLimit int64  `json:"connection_limit"`
+}
+
+type RoleMembership struct {
+	Role        string `json:"role"`
+	Member      string `json:"member"`
+	AdminOption bool   `json:"admin_option"`
+}
+
+type PublicPrivilege struct {
+	Schema     string   `json:"schema"`
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Privileges []string `json:"privileges"`
+}
+
+type SecurityDefinerFunction struct {
+	Schema    string `json:"schema"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	Owner     string `json:"owner"`
+}
+
+type OwnedObject struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Owner  string `json:"owner"`
+}
+
 func NewReportRequest(config config.Config, data *data.Data, reportedAt int64, stats *util.Stats) ReportRequest {
 	return ReportRequest{
 		LogMetrics:               ConvertLogMetrics(data.LogMetrics),
-		PostgresServers:          ConvertPostgresServers(data.PostgresServers, data.Databases, data.Replications, data.Metrics, data.Settings, data.QueryStats),
+		PostgresServers:          ConvertPostgresServers(data.PostgresServers, data.Databases, data.Replications, data.Metrics, data.Settings, data.SettingOverrides, data.HbaRules, data.SettingChanges, data.Recommendations, data.ServerEvents, data.Securities, data.QueryStats, data.TempFiles, data.VacuumEvents, data.LockEvents, data.LogErrors),
 		LogTestMessageReceivedAt: data.LogTestMessageReceivedAt,
 		ReportedAt:               reportedAt,
 		Agent: Agent{
@@ -299,19 +577,26 @@ func ConvertLogMetrics(from []data.LogMetrics) []LogMetrics {
 	return to
 }
 
-func ConvertPostgresServers(fromServers []db.PostgresServer, fromDbs []db.Database, fromReplications []db.Replication, fromMetrics []db.Metric, fromSettings []db.Setting, fromQueryStats []db.QueryStats) []PostgresServer {
+func ConvertPostgresServers(fromServers []db.PostgresServer, fromDbs []db.Database, fromReplications []db.Replication, fromMetrics []db.Metric, fromSettings []db.Setting, fromSettingOverrides []db.SettingOverride, fromHbaRules []db.HbaRule, fromSettingChanges []db.SettingChange, fromRecommendations []data.Recommendation, fromServerEvents []db.ServerEvent, fromSecurities []db.Security, fromQueryStats []db.QueryStats, fromTempFiles []db.TempFile, fromVacuumEvents []db.VacuumEvent, fromLockEvents []db.LockEvent, fromLogErrors []db.LogError) []PostgresServer {
 	to := []PostgresServer{}
 
 	for _, fromServer := range fromServers {
 		toServer := PostgresServer{
-			ConfigVarName:  fromServer.ServerID.ConfigVarName,
-			ConfigName:     fromServer.ServerID.ConfigName,
-			Platform:       fromServer.Platform,
-			MaxConnections: fromServer.MaxConnections,
-			Settings:       ConvertSettings(fromSettings, fromServer),
-			Version:        fromServer.Version,
-			MonitoredAt:    fromServer.MonitoredAt,
+			ConfigVarName:    fromServer.ServerID.ConfigVarName,
+			ConfigName:       fromServer.ServerID.ConfigName,
+			ConfigVarAliases: fromServer.ServerID.Aliases(),
+			Platform:         fromServer.Platform,
+			MaxConnections:   fromServer.MaxConnections,
+			Settings:         ConvertSettings(fromSettings, fromServer),
+			SettingOverrides: ConvertSettingOverrides(fromSettingOverrides, fromServer),
+			HbaRules:         ConvertHbaRules(fromHbaRules, fromServer),
+			SettingChanges:   ConvertSettingChanges(fromSettingChanges, fromServer),
+			Recommendations:  ConvertRecommendations(fromRecommendations, fromServer),
+			Events:           ConvertServerEvents(fromServerEvents, fromServer),
+			Version:          fromServer.Version,
+			MonitoredAt:      fromServer.MonitoredAt,
 		}
+		toServer.Extensions = ConvertExtensions(fromServer.ServerID.Database, fromServer.Extensions)
 		if fromServer.PgBouncer != nil {
 			toServer.PgBouncer = &PgBouncer{
 				MaxServerConnections: fromServer.PgBouncer.MaxServerConnections,
@@ -336,8 +621,18 @@ func ConvertPostgresServers(fromServers []db.PostgresServer, fromDbs []db.Databa
 			}
 		}
 
+		for _, fromSecurity := range fromSecurities {
+			// only one tracked security audit per server so no aggregation needed
+			if fromSecurity.ServerID.ConfigName == fromServer.ServerID.ConfigName {
+				toServer.Security = ConvertSecurity(&fromSecurity)
+			}
+		}
+
 		toServer.Metrics = ConvertMetrics(fromServer.ServerID.ConfigName, fromMetrics)
-		toServer.Queries = ConvertQueries(fromServer.ServerID.ConfigName, fromQueryStats)
+		toServer.Queries = ConvertQueries(fromServer.ServerID.ConfigName, fromQueryStats, fromTempFiles)
+		toServer.Vacuums = ConvertVacuums(fromServer.ServerID.ConfigName, fromVacuumEvents)
+		toServer.LockEvents = ConvertLockEvents(fromServer.ServerID.ConfigName, fromLockEvents)
+		toServer.LogErrors = ConvertLogErrors(fromServer.ServerID.ConfigName, fromLogErrors)
 
 		to = append(to, toServer)
 	}
@@ -345,6 +640,21 @@ func ConvertPostgresServers(fromServers []db.PostgresServer, fromDbs []db.Databa
 	return to
 }
 
+func ConvertExtensions(database string, from []*db.Extension) []*Extension {
+	var to []*Extension
+	for _, fromExtension := range from {
+		to = append(to, &Extension{
+			Database:         database,
+			Name:             fromExtension.Name,
+			Schema:           fromExtension.Schema,
+			Version:          fromExtension.Version,
+			LatestVersion:    fromExtension.LatestVersion,
+			UpgradeAvailable: fromExtension.UpgradeAvailable,
+		})
+	}
+	return to
+}
+
 func ConvertMetrics(configName string, fromMetrics []db.Metric) []*Metric {
 	var metrics []*Metric
 
@@ -393,24 +703,158 @@ func ConvertMetrics(configName string, fromMetrics []db.Metric) []*Metric {
 	return metrics
 }
 
-func ConvertQueries(configName string, fromQueryStats []db.QueryStats) *Queries {
+func ConvertQueries(configName string, fromQueryStats []db.QueryStats, fromTempFiles []db.TempFile) *Queries {
 	queries := &Queries{}
 	var queryStats []*Query
+	var serverQueryStats []db.QueryStats
+	var serverTempFiles []db.TempFile
 
 	for _, fromStats := range fromQueryStats {
 		if fromStats.ServerID.ConfigName == configName {
 			queryStats = append(queryStats, ConvertQueryStats(fromStats))
+			serverQueryStats = append(serverQueryStats, fromStats)
 		}
 	}
 
-	if len(queryStats) > 0 {
-		queries.Stats = queryStats
+	for _, fromTempFile := range fromTempFiles {
+		if fromTempFile.ServerID != nil && fromTempFile.ServerID.ConfigName == configName {
+			serverTempFiles = append(serverTempFiles, fromTempFile)
+		}
+	}
+
+	queries.Stats = queryStats
+	queries.TempSpills = ConvertTempSpills(db.TopTempSpills(serverQueryStats, serverTempFiles, db.MaxTempSpills))
+
+	if len(queries.Stats) > 0 || len(queries.TempSpills) > 0 {
 		return queries
 	} else {
 		return nil
 	}
 }
 
+func ConvertTempSpills(fromSpills []*db.TempSpill) []*TempSpill {
+	var to []*TempSpill
+	for _, fromSpill := range fromSpills {
+		to = append(to, &TempSpill{
+			Fingerprint:             fromSpill.Fingerprint,
+			Query:                   fromSpill.Query,
+			Calls:                   fromSpill.Calls,
+			TempBytesWritten:        fromSpill.TempBytesWritten,
+			TempFiles:               fromSpill.TempFiles,
+			TempFileBytes:           fromSpill.TempFileBytes,
+			MaxTempFileBytes:        fromSpill.MaxTempFileBytes,
+			RecommendedWorkMemBytes: fromSpill.RecommendedWorkMemBytes,
+		})
+	}
+	return to
+}
+
+func ConvertVacuums(configName string, fromVacuumEvents []db.VacuumEvent) []*TableVacuum {
+	var serverVacuumEvents []db.VacuumEvent
+	for _, fromVacuumEvent := range fromVacuumEvents {
+		if fromVacuumEvent.ServerID != nil && fromVacuumEvent.ServerID.ConfigName == configName {
+			serverVacuumEvents = append(serverVacuumEvents, fromVacuumEvent)
+		}
+	}
+
+	var to []*TableVacuum
+	for _, fromVacuum := range db.AggregateVacuumEvents(serverVacuumEvents, db.MaxTableVacuums) {
+		to = append(to, &TableVacuum{
+			Database:                fromVacuum.Database,
+			Schema:                  fromVacuum.Schema,
+			Table:                   fromVacuum.Table,
+			Vacuums:                 fromVacuum.Vacuums,
+			AggressiveVacuums:       fromVacuum.AggressiveVacuums,
+			WraparoundVacuums:       fromVacuum.WraparoundVacuums,
+			VacuumElapsedSeconds:    util.Round4(fromVacuum.VacuumElapsedSeconds),
+			MaxVacuumElapsedSeconds: util.Round4(fromVacuum.MaxVacuumElapsedSeconds),
+			Analyzes:                fromVacuum.Analyzes,
+			AnalyzeElapsedSeconds:   util.Round4(fromVacuum.AnalyzeElapsedSeconds),
+			PagesRemoved:            fromVacuum.PagesRemoved,
+			TuplesRemoved:           fromVacuum.TuplesRemoved,
+			TuplesDeadNotRemovable:  fromVacuum.TuplesDeadNotRemovable,
+			BufferMisses:            fromVacuum.BufferMisses,
+			BufferDirtied:           fromVacuum.BufferDirtied,
+			WalBytes:                fromVacuum.WalBytes,
+			LastVacuumAt:            fromVacuum.LastVacuumAt,
+			LastAnalyzeAt:           fromVacuum.LastAnalyzeAt,
+		})
+	}
+	return to
+}
+
+func ConvertLockEvents(configName string, fromLockEvents []db.LockEvent) []*LockEvent {
+	var serverLockEvents []db.LockEvent
+	for _, fromLockEvent := range fromLockEvents {
+		if fromLockEvent.ServerID != nil && fromLockEvent.ServerID.ConfigName == configName {
+			serverLockEvents = append(serverLockEvents, fromLockEvent)
+		}
+	}
+
+	var to []*LockEvent
+	for _, fromLockEvent := range db.LatestLockEvents(serverLockEvents, db.MaxLockEvents) {
+		// the raw statements and client are not reported
+		var participants []*LockParticipant
+		for _, fromParticipant := range fromLockEvent.Participants {
+			participants = append(participants, &LockParticipant{
+				Pid:          fromParticipant.Pid,
+				LockMode:     fromParticipant.LockMode,
+				LockObject:   fromParticipant.LockObject,
+				BlockedByPid: fromParticipant.BlockedByPid,
+				Fingerprint:  fromParticipant.Fingerprint,
+				Query:        fromParticipant.Obfuscated,
+			})
+		}
+
+		to = append(to, &LockEvent{
+			Kind:          fromLockEvent.Kind,
+			LockMode:      fromLockEvent.LockMode,
+			LockObject:    fromLockEvent.LockObject,
+			WaitMs:        util.Round(fromLockEvent.WaitMs),
+			HolderPids:    fromLockEvent.HolderPids,
+			WaitQueuePids: fromLockEvent.WaitQueuePids,
+			Participants:  participants,
+			Pid:           fromLockEvent.Fields.Pid,
+			Database:      fromLockEvent.Fields.Database,
+			User:          fromLockEvent.Fields.User,
+			Application:   fromLockEvent.Fields.Application,
+			MeasuredAt:    fromLockEvent.MeasuredAt,
+		})
+	}
+	return to
+}
+
+func ConvertLogErrors(configName string, fromLogErrors []db.LogError) []*LogError {
+	var serverLogErrors []db.LogError
+	for _, fromLogError := range fromLogErrors {
+		if fromLogError.ServerID != nil && fromLogError.ServerID.ConfigName == configName {
+			serverLogErrors = append(serverLogErrors, fromLogError)
+		}
+	}
+
+	var to []*LogError
+	for _, fromGroup := range db.AggregateLogErrors(serverLogErrors, db.MaxLogErrorGroups) {
+		var samples []*LogErrorSample
+		for _, fromSample := range fromGroup.Samples {
+			samples = append(samples, &LogErrorSample{
+				Fingerprint: fromSample.Fingerprint,
+				Query:       fromSample.Query,
+			})
+		}
+
+		to = append(to, &LogError{
+			Severity:     fromGroup.Severity,
+			SqlState:     fromGroup.SqlState,
+			Message:      fromGroup.Message,
+			Count:        fromGroup.Count,
+			Samples:      samples,
+			FirstErrorAt: fromGroup.FirstErrorAt,
+			LastErrorAt:  fromGroup.LastErrorAt,
+		})
+	}
+	return to
+}
+
 func ConvertQueryStats(fromStats db.QueryStats) *Query {
 	return &Query{
 		Database:            fromStats.ServerID.Database,
@@ -468,8 +912,12 @@ func ConvertSchemas(from []*db.Schema) []*Schema {
 
 	for _, fromSchema := range from {
 		toSchema := &Schema{
-			Name:   fromSchema.Name,
-			Tables: ConvertTables(fromSchema.Tables),
+			Name:              fromSchema.Name,
+			Tables:            ConvertTables(fromSchema.Tables),
+			Views:             ConvertViews(fromSchema.Views),
+			MaterializedViews: ConvertMaterializedViews(fromSchema.MaterializedViews),
+			Triggers:          ConvertTriggers(fromSchema.Triggers),
+			Types:             ConvertCustomTypes(fromSchema.Types),
 		}
 		to = append(to, toSchema)
 	}
@@ -522,8 +970,16 @@ func ConvertTables(from []*db.Table) []*Table {
 			DiskToastBlocksHit:       fromTable.DiskToastBlocksHit,
 			DiskToastIndexBlocksRead: fromTable.DiskToastIndexBlocksRead,
 			DiskToastIndexBlocksHit:  fromTable.DiskToastIndexBlocksHit,
+			HotUpdatedRows:           fromTable.HotUpdatedRows,
+			HotUpdateRatio:           fromTable.HotUpdateRatio,
+			HotUpdateAdvice:          fromTable.HotUpdateAdvice,
+			Fillfactor:               fromTable.Fillfactor,
+			StorageOptions:           fromTable.StorageOptions,
+			AutoanalyzeThreshold:     fromTable.AutoanalyzeThreshold,
+			StatisticsStale:          fromTable.StatisticsStale,
 			Columns:                  ConvertColumns(fromTable.Columns),
 			Indexes:                  ConvertIndexes(fromTable.Indexes),
+			ExtendedStatistics:       ConvertExtendedStatistics(fromTable.ExtendedStatistics),
 		}
 		to = append(to, toTable)
 	}
@@ -543,12 +999,28 @@ func ConvertColumns(from []*db.Column) []*Column {
 			NumericScale:     int(convertSqlNullInt64(fromColumn.NumericScale)),
 			IntervalType:     fromColumn.IntervalType.String,
 			IsIdentity:       convertSqlYesNoToBool(fromColumn.IsIdentity),
+			NullFraction:     convertSqlNullFloat64Pointer(fromColumn.NullFraction),
+			DistinctValues:   convertSqlNullFloat64Pointer(fromColumn.DistinctValues),
+			Correlation:      convertSqlNullFloat64Pointer(fromColumn.Correlation),
 		}
 		to = append(to, toColumn)
 	}
 	return to
 }
 
+func ConvertExtendedStatistics(from []*db.ExtendedStatistic) []*ExtendedStatistic {
+	var to []*ExtendedStatistic
+	for _, fromStatistic := range from {
+		to = append(to, &ExtendedStatistic{
+			Name:    fromStatistic.Name,
+			Schema:  fromStatistic.Schema,
+			Columns: fromStatistic.Columns,
+			Kinds:   fromStatistic.Kinds,
+		})
+	}
+	return to
+}
+
 func ConvertIndexes(from []*db.Index) []*Index {
 	to := []*Index{}
 	for _, fromIndex := range from {
@@ -572,6 +1044,61 @@ func ConvertIndexes(from []*db.Index) []*Index {
 	return to
 }
 
+func ConvertViews(from []*db.View) []*View {
+	to := []*View{}
+	for _, fromView := range from {
+		to = append(to, &View{
+			Name:         fromView.Name,
+			Dependencies: fromView.Dependencies,
+		})
+	}
+	return to
+}
+
+func ConvertMaterializedViews(from []*db.MaterializedView) []*MaterializedView {
+	to := []*MaterializedView{}
+	for _, fromView := range from {
+		to = append(to, &MaterializedView{
+			Name:            fromView.Name,
+			Bytes:           fromView.Bytes,
+			Populated:       fromView.Populated,
+			Dependencies:    fromView.Dependencies,
+			LastRefreshedAt: convertSqlNullInt64(fromView.LastRefreshedAt),
+		})
+	}
+	return to
+}
+
+func ConvertTriggers(from []*db.Trigger) []*Trigger {
+	to := []*Trigger{}
+	for _, fromTrigger := range from {
+		to = append(to, &Trigger{
+			Name:      fromTrigger.Name,
+			TableName: fromTrigger.TableName,
+			Timing:    fromTrigger.Timing,
+			Events:    fromTrigger.Events,
+			Level:     fromTrigger.Level,
+			Enabled:   fromTrigger.Enabled,
+			Function:  fromTrigger.Function,
+		})
+	}
+	return to
+}
+
+func ConvertCustomTypes(from []*db.CustomType) []*CustomType {
+	to := []*CustomType{}
+	for _, fromType := range from {
+		to = append(to, &CustomType{
+			Name:       fromType.Name,
+			Kind:       fromType.Kind,
+			Values:     fromType.Values,
+			BaseType:   fromType.BaseType,
+			Attributes: fromType.Attributes,
+		})
+	}
+	return to
+}
+
 func ConvertReplica(from *db.Replica) *Replica {
 	if from == nil {
 		return nil // return nil to not send replica
@@ -581,6 +1108,10 @@ func ConvertReplica(from *db.Replica) *Replica {
 		ApplicationName:   from.ApplicationName,
 		PrimaryConfigName: from.PrimaryConfigName,
 		Status:            from.Status,
+		ReplayPaused:      from.ReplayPaused,
+		ReceiveLsn:        from.ReceiveLsn.String,
+		ReplayLsn:         from.ReplayLsn.String,
+		LastReplayedAt:    convertSqlNullInt64(from.LastReplayedAt),
 	}
 }
 
@@ -650,6 +1181,170 @@ func ConvertSettings(from []db.Setting, fromServer db.PostgresServer) []*Setting
 	return to
 }
 
+func ConvertSecurity(from *db.Security) *Security {
+	to := &Security{
+		LoginRoleOwnedObjectsTruncated: from.LoginRoleOwnedObjectsTruncated,
+		MeasuredAt:                     from.MeasuredAt,
+	}
+
+	for _, fromRole := range from.Roles {
+		to.Roles = append(to.Roles, &Role{
+			Name:            fromRole.Name,
+			Superuser:       fromRole.Superuser,
+			CreateRole:      fromRole.CreateRole,
+			CreateDB:        fromRole.CreateDB,
+			BypassRLS:       fromRole.BypassRLS,
+			Replication:     fromRole.Replication,
+			Login:           fromRole.Login,
+			ValidUntil:      convertSqlNullInt64(fromRole.ValidUntil),
+			ConnectionLimit: fromRole.ConnectionLimit,
+		})
+	}
+
+	for _, fromMembership := range from.RoleMemberships {
+		to.RoleMemberships = append(to.RoleMemberships, &RoleMembership{
+			Role:        fromMembership.Role,
+			Member:      fromMembership.Member,
+			AdminOption: fromMembership.AdminOption,
+		})
+	}
+
+	for _, fromPrivilege := range from.PublicPrivileges {
+		to.PublicPrivileges = append(to.PublicPrivileges, &PublicPrivilege{
+			Schema:     fromPrivilege.Schema,
+			Name:       fromPrivilege.Name,
+			Kind:       fromPrivilege.Kind,
+			Privileges: fromPrivilege.Privileges,
+		})
+	}
+
+	for _, fromFunction := range from.SecurityDefinerFunctions {
+		to.SecurityDefinerFunctions = append(to.SecurityDefinerFunctions, &SecurityDefinerFunction{
+			Schema:    fromFunction.Schema,
+			Name:      fromFunction.Name,
+			Arguments: fromFunction.Arguments,
+			Owner:     fromFunction.Owner,
+		})
+	}
+
+	for _, fromObject := range from.LoginRoleOwnedObjects {
+		to.LoginRoleOwnedObjects = append(to.LoginRoleOwnedObjects, &OwnedObject{
+			Schema: fromObject.Schema,
+			Name:   fromObject.Name,
+			Kind:   fromObject.Kind,
+			Owner:  fromObject.Owner,
+		})
+	}
+
+	return to
+}
+
+func ConvertSettingOverrides(from []db.SettingOverride, fromServer db.PostgresServer) []*SettingOverride {
+	var to []*SettingOverride
+
+	for _, fromOverride := range from {
+		// filter to overrides on the same server
+		if fromOverride.ServerID.ConfigName == fromServer.ServerID.ConfigName {
+			to = append(to, &SettingOverride{
+				Role:     fromOverride.Role,
+				Database: fromOverride.Database,
+				Name:     fromOverride.Name,
+				Value:    fromOverride.Value,
+			})
+		}
+	}
+
+	return to
+}
+
+func ConvertHbaRules(from []db.HbaRule, fromServer db.PostgresServer) []*HbaRule {
+	var to []*HbaRule
+
+	for _, fromRule := range from {
+		// filter to rules on the same server
+		if fromRule.ServerID.ConfigName == fromServer.ServerID.ConfigName {
+			to = append(to, &HbaRule{
+				LineNumber: convertSqlNullInt64(fromRule.LineNumber),
+				Type:       fromRule.Type.String,
+				Databases:  fromRule.Databases,
+				Users:      fromRule.Users,
+				Address:    fromRule.Address.String,
+				Netmask:    fromRule.Netmask.String,
+				AuthMethod: fromRule.AuthMethod.String,
+				Options:    fromRule.Options,
+				Error:      fromRule.Error.String,
+			})
+		}
+	}
+
+	return to
+}
+
+func ConvertSettingChanges(from []db.SettingChange, fromServer db.PostgresServer) []*SettingChange {
+	var to []*SettingChange
+
+	for _, fromChange := range from {
+		// filter to changes on the same server
+		if fromChange.ServerID.ConfigName == fromServer.ServerID.ConfigName {
+			to = append(to, &SettingChange{
+				Name:       fromChange.Name,
+				Type:       fromChange.Type,
+				OldValue:   fromChange.OldValue.String,
+				NewValue:   fromChange.NewValue.String,
+				Source:     fromChange.Source,
+				DetectedAt: fromChange.DetectedAt,
+			})
+		}
+	}
+
+	return to
+}
+
+func ConvertRecommendations(from []data.Recommendation, fromServer db.PostgresServer) []*Recommendation {
+	var to []*Recommendation
+
+	for _, fromRecommendation := range from {
+		// filter to recommendations on the same server
+		if fromRecommendation.ServerID.ConfigName == fromServer.ServerID.ConfigName {
+			toRecommendation := &Recommendation{
+				Setting:          fromRecommendation.Setting,
+				CurrentValue:     fromRecommendation.CurrentValue,
+				RecommendedValue: fromRecommendation.RecommendedValue,
+				Reason:           fromRecommendation.Reason,
+				CreatedAt:        fromRecommendation.CreatedAt,
+			}
+			for _, evidence := range fromRecommendation.Evidence {
+				toRecommendation.Evidence = append(toRecommendation.Evidence, &Evidence{
+					Name:   evidence.Name,
+					Entity: evidence.Entity,
+					Value:  evidence.Value,
+				})
+			}
+			to = append(to, toRecommendation)
+		}
+	}
+
+	return to
+}
+
+func ConvertServerEvents(from []db.ServerEvent, fromServer db.PostgresServer) []*ServerEvent {
+	var to []*ServerEvent
+
+	for _, fromEvent := range from {
+		// filter to events on the same server
+		if fromEvent.ServerID.ConfigName == fromServer.ServerID.ConfigName {
+			to = append(to, &ServerEvent{
+				Type:          fromEvent.Type,
+				PreviousValue: fromEvent.PreviousValue,
+				Value:         fromEvent.Value,
+				DetectedAt:    fromEvent.DetectedAt,
+			})
+		}
+	}
+
+	return to
+}
+
 func ConvertStats(stats *util.Stats) *Stats {
 	data := stats.ToMap()
 
@@ -675,6 +1370,23 @@ func ConvertLogStats(stats map[string]int) *LogStats {
 		MetricsLinesDropped: stats["logs.metric_lines.dropped"],
 		SlowQueries:         stats["logs.slow_queries"],
 		SlowQueriesDropped:  stats["logs.slow_queries.dropped"],
+		TempFiles:           stats["logs.temp_files"],
+		TempFilesDropped:    stats["logs.temp_files.dropped"],
+		SyslogInvalid:       stats["logs.syslog.invalid"],
+		SyslogUnmapped:      stats["logs.syslog.unmapped"],
+		LogplexInvalid:      stats["logs.logplex.invalid_frames"],
+		LogplexCountInvalid: stats["logs.logplex.count_mismatch"],
+		Rejected:            stats["logs.rejected"],
+		Vacuums:             stats["logs.vacuums"],
+		VacuumsDropped:      stats["logs.vacuums.dropped"],
+		Checkpoints:         stats["logs.checkpoints"],
+		CheckpointsDropped:  stats["logs.checkpoints.dropped"],
+		Locks:               stats["logs.locks"],
+		LocksDropped:        stats["logs.locks.dropped"],
+		Errors:              stats["logs.errors"],
+		ErrorsDropped:       stats["logs.errors.dropped"],
+		Plans:               stats["logs.plans"],
+		PlansDropped:        stats["logs.plans.dropped"],
 	}
 }
 
@@ -705,6 +1417,14 @@ func convertSqlNullInt64(nullInt64 sql.NullInt64) int64 {
 	}
 }
 
+func convertSqlNullFloat64Pointer(nullFloat64 sql.NullFloat64) *float64 {
+	if !nullFloat64.Valid {
+		return nil
+	}
+	value := util.Round4(nullFloat64.Float64)
+	return &value
+}
+
 func convertSqlYesNoToBool(yesNo sql.NullString) bool {
 	return yesNo.String == "YES"
 }
@@ -311,3 +311,179 @@ func TestToCompressedJSON(t *testing.T) {
 func buildPgTypeInterval(microseconds int64) pgtype.Interval {
 	return pgtype.Interval{Days: 0, Months: 0, Microseconds: microseconds, Status: pgtype.Present}
 }
+
+func TestConvertReplicaRecoveryState(t *testing.T) {
+	replica := ConvertReplica(&db.Replica{
+		ApplicationName:   "follower:651387237",
+		PrimaryConfigName: "GREEN",
+		Status:            "streaming",
+		ReplayPaused:      true,
+		ReceiveLsn:        sql.NullString{Valid: true, String: "0/3000148"},
+		ReplayLsn:         sql.NullString{Valid: true, String: "0/3000060"},
+		LastReplayedAt:    sql.NullInt64{Valid: true, Int64: 1649299369},
+	})
+
+	assert.Equal(t, &Replica{
+		ApplicationName:   "follower:651387237",
+		PrimaryConfigName: "GREEN",
+		Status:            "streaming",
+		ReplayPaused:      true,
+		ReceiveLsn:        "0/3000148",
+		ReplayLsn:         "0/3000060",
+		LastReplayedAt:    1649299369,
+	}, replica)
+}
+
+func TestConvertColumnStatistics(t *testing.T) {
+	columns := ConvertColumns([]*db.Column{
+		{Name: "id", Type: "bigint", NullFraction: sql.NullFloat64{Valid: true, Float64: 0}, DistinctValues: sql.NullFloat64{Valid: true, Float64: -1}, Correlation: sql.NullFloat64{Valid: true, Float64: 0.987654}},
+		{Name: "name", Type: "text"},
+	})
+
+	assert.Equal(t, 0.0, *columns[0].NullFraction)
+	assert.Equal(t, -1.0, *columns[0].DistinctValues)
+	assert.Equal(t, 0.9877, *columns[0].Correlation)
+
+	// not analyzed yet
+	assert.Nil(t, columns[1].NullFraction)
+	assert.Nil(t, columns[1].DistinctValues)
+	assert.Nil(t, columns[1].Correlation)
+}
+
+func TestConvertSecurity(t *testing.T) {
+	security := ConvertSecurity(&db.Security{
+		Roles: []*db.Role{
+			{Name: "app", Login: true, ValidUntil: sql.NullInt64{Valid: true, Int64: 1649299369}, ConnectionLimit: -1},
+			{Name: "admin", Superuser: true},
+		},
+		RoleMemberships:          []*db.RoleMembership{{Role: "readers", Member: "app", AdminOption: true}},
+		PublicPrivileges:         []*db.PublicPrivilege{{Schema: "public", Name: "public", Kind: "schema", Privileges: []string{"CREATE", "USAGE"}}},
+		SecurityDefinerFunctions: []*db.SecurityDefinerFunction{{Schema: "public", Name: "reset_password", Arguments: "user_id bigint", Owner: "admin"}},
+		LoginRoleOwnedObjects:    []*db.OwnedObject{{Schema: "public", Name: "users", Kind: "table", Owner: "app"}},
+
+		LoginRoleOwnedObjectsTruncated: true,
+		MeasuredAt:                     1649299369,
+	})
+
+	assert.Equal(t, &Security{
+		Roles: []*Role{
+			{Name: "app", Login: true, ValidUntil: 1649299369, ConnectionLimit: -1},
+			{Name: "admin", Superuser: true},
+		},
+		RoleMemberships:          []*RoleMembership{{Role: "readers", Member: "app", AdminOption: true}},
+		PublicPrivileges:         []*PublicPrivilege{{Schema: "public", Name: "public", Kind: "schema", Privileges: []string{"CREATE", "USAGE"}}},
+		SecurityDefinerFunctions: []*SecurityDefinerFunction{{Schema: "public", Name: "reset_password", Arguments: "user_id bigint", Owner: "admin"}},
+		LoginRoleOwnedObjects:    []*OwnedObject{{Schema: "public", Name: "users", Kind: "table", Owner: "app"}},
+
+		LoginRoleOwnedObjectsTruncated: true,
+		MeasuredAt:                     1649299369,
+	}, security)
+
+	// roles without an expiration don't report one
+	security = ConvertSecurity(&db.Security{Roles: []*db.Role{{Name: "app"}}})
+	assert.Equal(t, int64(0), security.Roles[0].ValidUntil)
+	assert.Nil(t, security.LoginRoleOwnedObjects)
+}
+
+func TestConvertVacuums(t *testing.T) {
+	green := &db.ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "prod"}
+	blue := &db.ServerID{ConfigName: "BLUE", ConfigVarName: "BLUE_URL", Database: "prod"}
+
+	vacuums := ConvertVacuums("GREEN", []db.VacuumEvent{
+		{ServerID: green, Kind: db.VacuumEventKindVacuum, Database: "prod", Schema: "public", Table: "events", ElapsedSeconds: 1.23456, PagesRemoved: 10, MeasuredAt: 100},
+		{ServerID: green, Kind: db.VacuumEventKindAnalyze, Database: "prod", Schema: "public", Table: "events", ElapsedSeconds: 0.5, MeasuredAt: 150},
+		{ServerID: blue, Kind: db.VacuumEventKindVacuum, Database: "prod", Schema: "public", Table: "users", ElapsedSeconds: 5, MeasuredAt: 100},
+		// logged before the server was known
+		{ServerConfigName: "GREEN", Kind: db.VacuumEventKindVacuum, Database: "prod", Schema: "public", Table: "users", ElapsedSeconds: 5, MeasuredAt: 100},
+	})
+
+	assert.Equal(t, []*TableVacuum{
+		{
+			Database:                "prod",
+			Schema:                  "public",
+			Table:                   "events",
+			Vacuums:                 1,
+			VacuumElapsedSeconds:    1.2346,
+			MaxVacuumElapsedSeconds: 1.2346,
+			Analyzes:                1,
+			AnalyzeElapsedSeconds:   0.5,
+			PagesRemoved:            10,
+			LastVacuumAt:            100,
+			LastAnalyzeAt:           150,
+		},
+	}, vacuums)
+
+	assert.Nil(t, ConvertVacuums("RED", []db.VacuumEvent{{ServerID: green, Kind: db.VacuumEventKindVacuum}}))
+}
+
+func TestConvertLockEvents(t *testing.T) {
+	green := &db.ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "prod"}
+	blue := &db.ServerID{ConfigName: "BLUE", ConfigVarName: "BLUE_URL", Database: "prod"}
+
+	lockEvents := ConvertLockEvents("GREEN", []db.LockEvent{
+		{
+			ServerID:      green,
+			Kind:          db.LockEventKindWaiting,
+			LockMode:      "ShareLock",
+			LockObject:    "transaction 5678",
+			WaitMs:        1000.25,
+			HolderPids:    []int64{456},
+			WaitQueuePids: []int64{123},
+			Participants: []*db.LockParticipant{
+				{Pid: 123, LockMode: "ShareLock", LockObject: "transaction 5678", BlockedByPid: 456, Raw: "UPDATE accounts SET balance = 10 WHERE id = 1", Obfuscated: "UPDATE accounts SET balance = ? WHERE id = ?", Fingerprint: "abc"},
+			},
+			Fields:     db.LogFields{Pid: 123, Database: "prod", User: "app", Application: "web", Client: "10.0.0.1"},
+			MeasuredAt: 100,
+		},
+		{ServerID: green, Kind: db.LockEventKindDeadlock, MeasuredAt: 50},
+		{ServerID: blue, Kind: db.LockEventKindDeadlock, MeasuredAt: 200},
+		{ServerConfigName: "GREEN", Kind: db.LockEventKindDeadlock, MeasuredAt: 300},
+	})
+
+	// deadlocks are reported first and the raw statements and client are never reported
+	assert.Equal(t, []*LockEvent{
+		{Kind: db.LockEventKindDeadlock, MeasuredAt: 50},
+		{
+			Kind:          db.LockEventKindWaiting,
+			LockMode:      "ShareLock",
+			LockObject:    "transaction 5678",
+			WaitMs:        1000.25,
+			HolderPids:    []int64{456},
+			WaitQueuePids: []int64{123},
+			Participants: []*LockParticipant{
+				{Pid: 123, LockMode: "ShareLock", LockObject: "transaction 5678", BlockedByPid: 456, Fingerprint: "abc", Query: "UPDATE accounts SET balance = ? WHERE id = ?"},
+			},
+			Pid:         123,
+			Database:    "prod",
+			User:        "app",
+			Application: "web",
+			MeasuredAt:  100,
+		},
+	}, lockEvents)
+}
+
+func TestConvertLogErrors(t *testing.T) {
+	green := &db.ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "prod"}
+	blue := &db.ServerID{ConfigName: "BLUE", ConfigVarName: "BLUE_URL", Database: "prod"}
+
+	logErrors := ConvertLogErrors("GREEN", []db.LogError{
+		{ServerID: green, Severity: "ERROR", SqlState: "57014", Message: "canceling statement due to statement timeout", Raw: "SELECT pg_sleep(10)", Obfuscated: "SELECT pg_sleep(?)", Fingerprint: "abc", MeasuredAt: 100},
+		{ServerID: green, Severity: "ERROR", SqlState: "57014", Message: "canceling statement due to statement timeout", MeasuredAt: 200},
+		{ServerID: blue, Severity: "FATAL", SqlState: "53300", Message: "sorry, too many clients already", MeasuredAt: 150},
+		{ServerConfigName: "GREEN", Severity: "FATAL", SqlState: "53300", Message: "sorry, too many clients already", MeasuredAt: 150},
+	})
+
+	assert.Equal(t, []*LogError{
+		{
+			Severity:     "ERROR",
+			SqlState:     "57014",
+			Message:      "canceling statement due to statement timeout",
+			Count:        2,
+			Samples:      []*LogErrorSample{{Fingerprint: "abc", Query: "SELECT pg_sleep(?)"}},
+			FirstErrorAt: 100,
+			LastErrorAt:  200,
+		},
+	}, logErrors)
+
+	assert.Nil(t, ConvertLogErrors("BLUE", []db.LogError{{Severity: "ERROR", Message: "no server"}}))
+}
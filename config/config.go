@@ -4,6 +4,7 @@ import (
 	"agent/logger"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,6 +26,8 @@ type Config struct {
 	MonitorInterval           time.Duration
 	MonitorSchemaInterval     time.Duration
 	MonitorSettingsInterval   time.Duration
+	MonitorSecurityInterval   time.Duration
+	MonitorStorageInterval    time.Duration
 	MonitorQueryStatsInterval time.Duration
 
 	MonitorPgBouncer    bool
@@ -32,7 +35,42 @@ type Config struct {
 	MonitorReplication  bool
 	MonitorSchema       bool
 	MonitorSettings     bool
+	MonitorSecurity     bool
+	MonitorStorage      bool
 	MonitorAgentQueries bool
+	MonitorHost         bool
+
+	// procfs and sysfs paths for host metrics - ex. /host/proc when running as a sidecar
+	HostProcPath string
+	HostSysPath  string
+
+	// syslog listener addresses for self-managed servers - ex. :5514 - empty disables the listener
+	SyslogUDPAddr     string
+	SyslogTCPAddr     string
+	SyslogTLSAddr     string
+	SyslogTLSCertFile string
+	SyslogTLSKeyFile  string
+
+	// maps syslog hostnames or app names to server config names - ex. db-1.internal=GREEN
+	// a * key matches any hostname or app name
+	SyslogServerNames map[string]string
+
+	// postgres log_directory to tail when running next to postgres - empty disables tailing
+	LogDirectory  string
+	LogFormat     string // stderr, csvlog or jsonlog - detected from the file extension when empty
+	LogServerName string // server config name the log files belong to - ex. GREEN
+	// read offsets are saved here so restarts resume where they left off - required with LogDirectory
+	// and must be on persistent storage since a lost state file skips lines logged while the agent was down
+	LogStateFile string
+
+	// allowed logplex drain tokens optionally mapped to the server config name their logs are routed to
+	// ex. d.123=GREEN,d.456 - logplex drains are rejected when empty
+	LogDrainTokens map[string]string
+
+	// optional credentials for non-logplex senders posting to /logs
+	LogBasicAuthUser     string
+	LogBasicAuthPassword string
+	LogBearerToken       string
 
 	TestMode bool
 }
@@ -59,7 +97,26 @@ func New() Config {
 	monitorReplication := getEnvVarBool("MONITOR_REPLICATION", true)
 	monitorSchema := getEnvVarBool("MONITOR_SCHEMA", true)
 	monitorSettings := getEnvVarBool("MONITOR_SETTINGS", true)
+	monitorSecurity := getEnvVarBool("MONITOR_SECURITY", true)
+	monitorStorage := getEnvVarBool("MONITOR_STORAGE", true)
 	monitorAgentQueries := getEnvVarBool("MONITOR_AGENT_QUERIES", false)
+	monitorHost := getEnvVarBool("MONITOR_HOST", false)
+	hostProcPath := getEnvVar("HOST_PROC_PATH", "/proc")
+	hostSysPath := getEnvVar("HOST_SYS_PATH", "/sys")
+	syslogUDPAddr := getEnvVar("SYSLOG_UDP_ADDR", "")
+	syslogTCPAddr := getEnvVar("SYSLOG_TCP_ADDR", "")
+	syslogTLSAddr := getEnvVar("SYSLOG_TLS_ADDR", "")
+	syslogTLSCertFile := getEnvVar("SYSLOG_TLS_CERT_FILE", "")
+	syslogTLSKeyFile := getEnvVar("SYSLOG_TLS_KEY_FILE", "")
+	syslogServerNames := getEnvVarMap("SYSLOG_SERVER_NAMES")
+	logDirectory := getEnvVar("LOG_DIRECTORY", "")
+	logFormat := getEnvVar("LOG_FORMAT", "")
+	logServerName := getEnvVar("LOG_SERVER_NAME", "")
+	logStateFile := getEnvVar("LOG_STATE_FILE", "")
+	logDrainTokens := getEnvVarAllowlist("LOG_DRAIN_TOKENS")
+	logBasicAuthUser := getEnvVar("LOG_BASIC_AUTH_USER", "")
+	logBasicAuthPassword := getEnvVar("LOG_BASIC_AUTH_PASSWORD", "")
+	logBearerToken := getEnvVar("LOG_BEARER_TOKEN", "")
 
 	return Config{
 		APIEndpoint:               endpoint,
@@ -74,12 +131,33 @@ func New() Config {
 		MonitorQueryStatsInterval: 1 * time.Minute,  // ^
 		MonitorSchemaInterval:     15 * time.Minute, // ^
 		MonitorSettingsInterval:   3 * time.Hour,    // ^
+		MonitorSecurityInterval:   3 * time.Hour,    // ^
+		MonitorStorageInterval:    5 * time.Minute,  // ^
 		MonitorPgBouncer:          monitorPgBouncer,
 		MonitorQueryStats:         monitorQueryStats,
 		MonitorReplication:        monitorReplication,
 		MonitorSchema:             monitorSchema,
 		MonitorSettings:           monitorSettings,
+		MonitorSecurity:           monitorSecurity,
+		MonitorStorage:            monitorStorage,
 		MonitorAgentQueries:       monitorAgentQueries,
+		MonitorHost:               monitorHost,
+		HostProcPath:              hostProcPath,
+		HostSysPath:               hostSysPath,
+		SyslogUDPAddr:             syslogUDPAddr,
+		SyslogTCPAddr:             syslogTCPAddr,
+		SyslogTLSAddr:             syslogTLSAddr,
+		SyslogTLSCertFile:         syslogTLSCertFile,
+		SyslogTLSKeyFile:          syslogTLSKeyFile,
+		SyslogServerNames:         syslogServerNames,
+		LogDirectory:              logDirectory,
+		LogFormat:                 logFormat,
+		LogServerName:             logServerName,
+		LogStateFile:              logStateFile,
+		LogDrainTokens:            logDrainTokens,
+		LogBasicAuthUser:          logBasicAuthUser,
+		LogBasicAuthPassword:      logBasicAuthPassword,
+		LogBearerToken:            logBearerToken,
 	}
 }
 
@@ -116,3 +194,44 @@ func getEnvVarBool(name string, defaultValue bool) bool {
 	}
 	return valueBool
 }
+
+// parses comma separated key=value pairs - ex. db-1.internal=GREEN,db-2.internal=BLUE
+func getEnvVarMap(name string) map[string]string {
+	values := make(map[string]string)
+
+	for _, pair := range strings.Split(getEnvVar(name, ""), ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if key != "" && value != "" {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+// parses a comma separated allowlist where each entry can be mapped to a value - ex. d.123=GREEN,d.456
+func getEnvVarAllowlist(name string) map[string]string {
+	values := make(map[string]string)
+
+	for _, entry := range strings.Split(getEnvVar(name, ""), ",") {
+		parts := strings.SplitN(entry, "=", 2)
+
+		key := strings.TrimSpace(parts[0])
+		if key == "" {
+			continue
+		}
+
+		values[key] = ""
+		if len(parts) == 2 {
+			values[key] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return values
+}
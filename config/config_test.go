@@ -32,3 +32,21 @@ func TestGetEnvVarBool(t *testing.T) {
 
 	os.Unsetenv("FOO")
 }
+
+func TestGetEnvVarMap(t *testing.T) {
+	assert.Equal(t, map[string]string{}, getEnvVarMap("FOO"))
+
+	os.Setenv("FOO", "db-1.internal=GREEN, postgres-replica = BLUE,invalid,=RED")
+	assert.Equal(t, map[string]string{"db-1.internal": "GREEN", "postgres-replica": "BLUE"}, getEnvVarMap("FOO"))
+
+	os.Unsetenv("FOO")
+}
+
+func TestGetEnvVarAllowlist(t *testing.T) {
+	assert.Equal(t, map[string]string{}, getEnvVarAllowlist("FOO"))
+
+	os.Setenv("FOO", "d.123=GREEN, d.456 ,,=RED")
+	assert.Equal(t, map[string]string{"d.123": "GREEN", "d.456": ""}, getEnvVarAllowlist("FOO"))
+
+	os.Unsetenv("FOO")
+}
@@ -10,6 +10,23 @@ import (
 // metric values are always strings as a lowest-common-denominator
 type LogMetrics map[string]string
 
+// a configuration tuning recommendation with the observed evidence behind it
+type Recommendation struct {
+	ServerID         *db.ServerID
+	Setting          string
+	CurrentValue     string
+	RecommendedValue string
+	Reason           string
+	Evidence         []Evidence
+	CreatedAt        int64
+}
+
+type Evidence struct {
+	Name   string
+	Entity string // ex. table/public.users
+	Value  string
+}
+
 type Data struct {
 	LogMetrics               []LogMetrics
 	Metrics                  []db.Metric
@@ -17,7 +34,17 @@ type Data struct {
 	Databases                []db.Database
 	Replications             []db.Replication
 	Settings                 []db.Setting
+	SettingOverrides         []db.SettingOverride
+	HbaRules                 []db.HbaRule
+	SettingChanges           []db.SettingChange
+	Securities               []db.Security
+	Recommendations          []Recommendation
+	ServerEvents             []db.ServerEvent
 	QueryStats               []db.QueryStats
+	TempFiles                []db.TempFile
+	VacuumEvents             []db.VacuumEvent
+	LockEvents               []db.LockEvent
+	LogErrors                []db.LogError
 	Errors                   []errors.ErrorReport
 	LogTestMessageReceivedAt int64
 	mu                       sync.Mutex
@@ -79,6 +106,10 @@ func (d *Data) AddPostgresServer(newServer *db.PostgresServer) {
 	if newServer.MaxConnections != 0 {
 		d.PostgresServers[existingIndex].MaxConnections = newServer.MaxConnections
 	}
+
+	if len(newServer.Extensions) > 0 {
+		d.PostgresServers[existingIndex].Extensions = newServer.Extensions
+	}
 }
 
 func (d *Data) AddDatabase(database *db.Database) {
@@ -156,6 +187,90 @@ func (d *Data) AddSetting(setting *db.Setting) {
 	}
 }
 
+// replaces all setting overrides for the server since overrides can be removed
+func (d *Data) AddSettingOverrides(serverID *db.ServerID, overrides []*db.SettingOverride) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var settingOverrides []db.SettingOverride
+	for _, existingOverride := range d.SettingOverrides {
+		if !reflect.DeepEqual(existingOverride.ServerID, serverID) {
+			settingOverrides = append(settingOverrides, existingOverride)
+		}
+	}
+	for _, override := range overrides {
+		settingOverrides = append(settingOverrides, *override)
+	}
+
+	d.SettingOverrides = settingOverrides
+}
+
+// replaces all hba rules for the server since rules can be removed
+func (d *Data) AddHbaRules(serverID *db.ServerID, rules []*db.HbaRule) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var hbaRules []db.HbaRule
+	for _, existingRule := range d.HbaRules {
+		if !reflect.DeepEqual(existingRule.ServerID, serverID) {
+			hbaRules = append(hbaRules, existingRule)
+		}
+	}
+	for _, rule := range rules {
+		hbaRules = append(hbaRules, *rule)
+	}
+
+	d.HbaRules = hbaRules
+}
+
+func (d *Data) AddSettingChanges(changes []*db.SettingChange) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, change := range changes {
+		d.SettingChanges = append(d.SettingChanges, *change)
+	}
+}
+
+func (d *Data) AddRecommendations(recommendations []*Recommendation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, recommendation := range recommendations {
+		d.Recommendations = append(d.Recommendations, *recommendation)
+	}
+}
+
+func (d *Data) AddServerEvent(event *db.ServerEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ServerEvents = append(d.ServerEvents, *event)
+}
+
+func (d *Data) AddSecurity(security *db.Security) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// find security and replace it since we only need the latest audit per server
+	existingIndex := -1
+
+	for index, existingSecurity := range d.Securities {
+		// uniqueness by server id (config name & database name)
+		if reflect.DeepEqual(existingSecurity.ServerID, security.ServerID) {
+			existingIndex = index
+			break
+		}
+	}
+
+	if existingIndex == -1 {
+		d.Securities = append(d.Securities, *security)
+	} else {
+		// replace existing
+		d.Securities[existingIndex] = *security
+	}
+}
+
 func (d *Data) AddQueryStats(stats []*db.QueryStats) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -166,6 +281,34 @@ func (d *Data) AddQueryStats(stats []*db.QueryStats) {
 	}
 }
 
+func (d *Data) AddTempFile(tempFile *db.TempFile) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.TempFiles = append(d.TempFiles, *tempFile)
+}
+
+func (d *Data) AddVacuumEvent(vacuumEvent *db.VacuumEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.VacuumEvents = append(d.VacuumEvents, *vacuumEvent)
+}
+
+func (d *Data) AddLockEvent(lockEvent *db.LockEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.LockEvents = append(d.LockEvents, *lockEvent)
+}
+
+func (d *Data) AddLogError(logError *db.LogError) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.LogErrors = append(d.LogErrors, *logError)
+}
+
 func (d *Data) AddErrorReport(err *errors.ErrorReport) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -212,9 +355,39 @@ func (d *Data) CopyAndReset() *Data {
 	settingsCopy := make([]db.Setting, len(d.Settings))
 	copy(settingsCopy, d.Settings)
 
+	settingOverridesCopy := make([]db.SettingOverride, len(d.SettingOverrides))
+	copy(settingOverridesCopy, d.SettingOverrides)
+
+	hbaRulesCopy := make([]db.HbaRule, len(d.HbaRules))
+	copy(hbaRulesCopy, d.HbaRules)
+
+	settingChangesCopy := make([]db.SettingChange, len(d.SettingChanges))
+	copy(settingChangesCopy, d.SettingChanges)
+
+	securitiesCopy := make([]db.Security, len(d.Securities))
+	copy(securitiesCopy, d.Securities)
+
+	recommendationsCopy := make([]Recommendation, len(d.Recommendations))
+	copy(recommendationsCopy, d.Recommendations)
+
+	serverEventsCopy := make([]db.ServerEvent, len(d.ServerEvents))
+	copy(serverEventsCopy, d.ServerEvents)
+
 	queryStatsCopy := make([]db.QueryStats, len(d.QueryStats))
 	copy(queryStatsCopy, d.QueryStats)
 
+	tempFilesCopy := make([]db.TempFile, len(d.TempFiles))
+	copy(tempFilesCopy, d.TempFiles)
+
+	vacuumEventsCopy := make([]db.VacuumEvent, len(d.VacuumEvents))
+	copy(vacuumEventsCopy, d.VacuumEvents)
+
+	lockEventsCopy := make([]db.LockEvent, len(d.LockEvents))
+	copy(lockEventsCopy, d.LockEvents)
+
+	logErrorsCopy := make([]db.LogError, len(d.LogErrors))
+	copy(logErrorsCopy, d.LogErrors)
+
 	errorsCopy := make([]errors.ErrorReport, len(d.Errors))
 	copy(errorsCopy, d.Errors)
 
@@ -225,7 +398,17 @@ func (d *Data) CopyAndReset() *Data {
 		Databases:                databasesCopy,
 		Replications:             replicationsCopy,
 		Settings:                 settingsCopy,
+		SettingOverrides:         settingOverridesCopy,
+		HbaRules:                 hbaRulesCopy,
+		SettingChanges:           settingChangesCopy,
+		Securities:               securitiesCopy,
+		Recommendations:          recommendationsCopy,
+		ServerEvents:             serverEventsCopy,
 		QueryStats:               queryStatsCopy,
+		TempFiles:                tempFilesCopy,
+		VacuumEvents:             vacuumEventsCopy,
+		LockEvents:               lockEventsCopy,
+		LogErrors:                logErrorsCopy,
 		Errors:                   errorsCopy,
 		LogTestMessageReceivedAt: d.LogTestMessageReceivedAt,
 	}
@@ -236,7 +419,17 @@ func (d *Data) CopyAndReset() *Data {
 	d.Databases = []db.Database{}
 	d.Replications = []db.Replication{}
 	d.Settings = []db.Setting{}
+	d.SettingOverrides = []db.SettingOverride{}
+	d.HbaRules = []db.HbaRule{}
+	d.SettingChanges = []db.SettingChange{}
+	d.Securities = []db.Security{}
+	d.Recommendations = []Recommendation{}
+	d.ServerEvents = []db.ServerEvent{}
 	d.QueryStats = []db.QueryStats{}
+	d.TempFiles = []db.TempFile{}
+	d.VacuumEvents = []db.VacuumEvent{}
+	d.LockEvents = []db.LockEvent{}
+	d.LogErrors = []db.LogError{}
 	d.Errors = []errors.ErrorReport{}
 	d.LogTestMessageReceivedAt = 0
 
@@ -247,6 +247,87 @@ func TestAddReplication(t *testing.T) {
 	assert.Equal(t, "1.2.3.5", data.Replications[0].Replicas[0].ClientAddr.String)
 }
 
+func TestAddSettingOverrides(t *testing.T) {
+	data := &Data{}
+	green := &db.ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "testDb"}
+	red := &db.ServerID{ConfigName: "RED", ConfigVarName: "RED_URL", Database: "testDb"}
+
+	data.AddSettingOverrides(green, []*db.SettingOverride{
+		{ServerID: green, Role: "app", Name: "statement_timeout", Value: "5s"},
+		{ServerID: green, Database: "testDb", Name: "work_mem", Value: "16MB"},
+	})
+	data.AddSettingOverrides(red, []*db.SettingOverride{
+		{ServerID: red, Role: "app", Name: "statement_timeout", Value: "10s"},
+	})
+	data.AddSettingOverrides(green, []*db.SettingOverride{
+		{ServerID: green, Role: "app", Name: "statement_timeout", Value: "30s"},
+	})
+
+	// latest overrides replace the previous ones for the same server
+	assert.Equal(t, 2, len(data.SettingOverrides))
+	assert.Equal(t, "RED", data.SettingOverrides[0].ServerID.ConfigName)
+	assert.Equal(t, "10s", data.SettingOverrides[0].Value)
+	assert.Equal(t, "GREEN", data.SettingOverrides[1].ServerID.ConfigName)
+	assert.Equal(t, "30s", data.SettingOverrides[1].Value)
+}
+
+func TestAddHbaRules(t *testing.T) {
+	data := &Data{}
+	serverId := &db.ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "testDb"}
+
+	data.AddHbaRules(serverId, []*db.HbaRule{{ServerID: serverId}, {ServerID: serverId}})
+	data.AddHbaRules(serverId, []*db.HbaRule{{ServerID: serverId}})
+
+	assert.Equal(t, 1, len(data.HbaRules))
+}
+
+func TestAddSettingChanges(t *testing.T) {
+	data := &Data{}
+	serverId := &db.ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "testDb"}
+
+	data.AddSettingChanges([]*db.SettingChange{{ServerID: serverId, Name: "work_mem", Type: db.SettingChangeTypeChanged}})
+	data.AddSettingChanges([]*db.SettingChange{{ServerID: serverId, Name: "work_mem", Type: db.SettingChangeTypeChanged}})
+
+	// changes are events so all are kept until reported
+	assert.Equal(t, 2, len(data.SettingChanges))
+}
+
+func TestAddServerEvent(t *testing.T) {
+	data := &Data{}
+	serverId := &db.ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "testDb"}
+
+	data.AddServerEvent(&db.ServerEvent{ServerID: serverId, Type: db.ServerEventTypeRestart})
+	data.AddServerEvent(&db.ServerEvent{ServerID: serverId, Type: db.ServerEventTypePromotion})
+
+	assert.Equal(t, 2, len(data.ServerEvents))
+	assert.Equal(t, db.ServerEventTypePromotion, data.ServerEvents[1].Type)
+}
+
+func TestAddSecurity(t *testing.T) {
+	data := &Data{}
+	serverId := &db.ServerID{
+		ConfigName:    "GREEN",
+		ConfigVarName: "GREEN_URL",
+		Database:      "testDb",
+	}
+
+	data.AddSecurity(&db.Security{
+		ServerID:   serverId,
+		Roles:      []*db.Role{{Name: "app", Login: true}},
+		MeasuredAt: 123456789,
+	})
+	data.AddSecurity(&db.Security{
+		ServerID:   serverId,
+		Roles:      []*db.Role{{Name: "app", Login: true}, {Name: "admin", Superuser: true}},
+		MeasuredAt: 123456799,
+	})
+
+	// latest security audit replaces the previous one
+	assert.Equal(t, 1, len(data.Securities))
+	assert.Equal(t, 2, len(data.Securities[0].Roles))
+	assert.Equal(t, int64(123456799), data.Securities[0].MeasuredAt)
+}
+
 func TestAddErrorReport_AddOne(t *testing.T) {
 	data := &Data{}
 
@@ -352,14 +433,24 @@ func TestCopyAndReset(t *testing.T) {
 	copiedData := data.CopyAndReset()
 
 	expectedEmptyData := &Data{
-		LogMetrics:      []LogMetrics{},
-		PostgresServers: []db.PostgresServer{},
-		Databases:       []db.Database{},
-		Replications:    []db.Replication{},
-		Metrics:         []db.Metric{},
-		Settings:        []db.Setting{},
-		QueryStats:      []db.QueryStats{},
-		Errors:          []errors.ErrorReport{},
+		LogMetrics:       []LogMetrics{},
+		PostgresServers:  []db.PostgresServer{},
+		Databases:        []db.Database{},
+		Replications:     []db.Replication{},
+		Metrics:          []db.Metric{},
+		Settings:         []db.Setting{},
+		SettingOverrides: []db.SettingOverride{},
+		HbaRules:         []db.HbaRule{},
+		SettingChanges:   []db.SettingChange{},
+		Securities:       []db.Security{},
+		Recommendations:  []Recommendation{},
+		ServerEvents:     []db.ServerEvent{},
+		QueryStats:       []db.QueryStats{},
+		TempFiles:        []db.TempFile{},
+		VacuumEvents:     []db.VacuumEvent{},
+		LockEvents:       []db.LockEvent{},
+		LogErrors:        []db.LogError{},
+		Errors:           []errors.ErrorReport{},
 	}
 	assert.Equal(t, expectedEmptyData, data)
 
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"agent/logger"
+	"strconv"
+	"strings"
+)
+
+const (
+	CheckpointEventKindStarting    = "starting"
+	CheckpointEventKindComplete    = "complete"
+	CheckpointEventKindTooFrequent = "too_frequent"
+
+	ServerEventTypeCheckpointsTooFrequent = "checkpoints_too_frequent"
+)
+
+// a checkpoint logged by postgres when log_checkpoints is enabled
+// ex. LOG:  checkpoint complete: wrote 3306 buffers (20.2%); 0 WAL file(s) added, 0 removed, 2 recycled; ...
+type CheckpointEvent struct {
+	ServerConfigName string
+	ServerID         *ServerID
+	Kind             string
+	Reason           string // why a checkpoint started - ex. time, wal or immediate force wait
+
+	BuffersWritten        int64
+	BuffersWrittenPercent float64
+	WalFilesAdded         int64
+	WalFilesRemoved       int64
+	WalFilesRecycled      int64
+	WriteSeconds          float64
+	SyncSeconds           float64
+	TotalSeconds          float64
+	SyncFiles             int64
+	DistanceBytes         int64
+	EstimateBytes         int64
+
+	// seconds between checkpoints when they are occurring too frequently
+	SecondsApart int64
+
+	Fields     LogFields
+	MeasuredAt int64
+}
+
+// runs forever
+func (o *Observer) MonitorCheckpoints() {
+	for {
+		select {
+		case checkpoint := <-o.rawCheckpointChannel:
+			checkpoint.ServerID = o.serverIDForConfigName(checkpoint.ServerConfigName)
+			if checkpoint.ServerID == nil {
+				continue
+			}
+
+			metrics := checkpoint.Metrics()
+			if len(metrics) > 0 {
+				select {
+				case o.metricsChannel <- metrics:
+					// sent
+				default:
+					logger.Warn("Dropping checkpoint metrics: channel buffer full")
+				}
+			}
+
+			serverEvent := checkpoint.ServerEvent()
+			if serverEvent != nil {
+				logger.Info("Server event detected", "configName", checkpoint.ServerID.ConfigName, "type", serverEvent.Type)
+
+				select {
+				case o.serverEventChannel <- serverEvent:
+					// sent
+				default:
+					logger.Warn("Dropping server event: channel buffer full")
+				}
+			}
+		}
+	}
+}
+
+// checkpoint metrics from logs are available even when pg_stat_bgwriter can't be queried
+func (e *CheckpointEvent) Metrics() []*Metric {
+	serverID := *e.ServerID
+
+	switch e.Kind {
+	case CheckpointEventKindStarting:
+		// timed checkpoints are expected while requested ones mean max_wal_size was reached or a manual checkpoint ran
+		if strings.Contains(e.Reason, "time") {
+			return []*Metric{NewMetric("checkpoint.timed", 1, "", serverID, e.MeasuredAt)}
+		}
+		return []*Metric{NewMetric("checkpoint.requested", 1, "", serverID, e.MeasuredAt)}
+	case CheckpointEventKindComplete:
+		return []*Metric{
+			NewMetric("checkpoint.buffers.written", float64(e.BuffersWritten), "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.buffers.written.percent", e.BuffersWrittenPercent, "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.wal.files.added", float64(e.WalFilesAdded), "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.wal.files.removed", float64(e.WalFilesRemoved), "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.wal.files.recycled", float64(e.WalFilesRecycled), "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.write.time", e.WriteSeconds*1000, "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.sync.time", e.SyncSeconds*1000, "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.total.time", e.TotalSeconds*1000, "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.sync.files", float64(e.SyncFiles), "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.distance.bytes", float64(e.DistanceBytes), "", serverID, e.MeasuredAt),
+			NewMetric("checkpoint.estimate.bytes", float64(e.EstimateBytes), "", serverID, e.MeasuredAt),
+		}
+	}
+
+	return nil
+}
+
+// checkpoints occurring too frequently mean max_wal_size is too small for the write load
+func (e *CheckpointEvent) ServerEvent() *ServerEvent {
+	if e.Kind != CheckpointEventKindTooFrequent {
+		return nil
+	}
+
+	return &ServerEvent{
+		ServerID:   e.ServerID,
+		Type:       ServerEventTypeCheckpointsTooFrequent,
+		Value:      strconv.FormatInt(e.SecondsApart, 10),
+		DetectedAt: e.MeasuredAt,
+	}
+}
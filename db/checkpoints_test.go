@@ -0,0 +1,37 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointEventMetrics(t *testing.T) {
+	serverID := &ServerID{ConfigName: "GREEN"}
+
+	event := &CheckpointEvent{ServerID: serverID, Kind: CheckpointEventKindStarting, Reason: "time", MeasuredAt: 100}
+	assert.Equal(t, []*Metric{NewMetric("checkpoint.timed", 1, "", *serverID, 100)}, event.Metrics())
+
+	event = &CheckpointEvent{ServerID: serverID, Kind: CheckpointEventKindStarting, Reason: "wal", MeasuredAt: 100}
+	assert.Equal(t, []*Metric{NewMetric("checkpoint.requested", 1, "", *serverID, 100)}, event.Metrics())
+
+	event = &CheckpointEvent{ServerID: serverID, Kind: CheckpointEventKindComplete, BuffersWritten: 3306, TotalSeconds: 1.5, MeasuredAt: 100}
+	metrics := event.Metrics()
+	assert.Equal(t, 11, len(metrics))
+	assert.Equal(t, NewMetric("checkpoint.buffers.written", 3306, "", *serverID, 100), metrics[0])
+	assert.Equal(t, NewMetric("checkpoint.total.time", 1500, "", *serverID, 100), metrics[7])
+	assert.Nil(t, event.ServerEvent())
+}
+
+func TestCheckpointEventServerEvent(t *testing.T) {
+	serverID := &ServerID{ConfigName: "GREEN"}
+	event := &CheckpointEvent{ServerID: serverID, Kind: CheckpointEventKindTooFrequent, SecondsApart: 9, MeasuredAt: 100}
+
+	assert.Equal(t, &ServerEvent{
+		ServerID:   serverID,
+		Type:       ServerEventTypeCheckpointsTooFrequent,
+		Value:      "9",
+		DetectedAt: 100,
+	}, event.ServerEvent())
+	assert.Empty(t, event.Metrics())
+}
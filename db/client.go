@@ -5,8 +5,10 @@ import (
 	"agent/logger"
 	"database/sql"
 	"log"
+	"net"
 	nurl "net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
@@ -27,11 +29,19 @@ type PostgresClient struct {
 	// ex host from url
 	host string
 
+	// hosts from alias config var urls
+	aliasHosts []string
+
 	platform string
 
 	maxConnections int64
 	version        string
 
+	// nil until extensions have been loaded
+	// guarded since the extension monitor runs alongside the collectors that check them
+	extensions   []*Extension
+	extensionsMu sync.RWMutex
+
 	pgBouncerEnabled              *bool
 	pgBouncerMaxServerConnections int64
 	pgBouncerVersion              string
@@ -61,9 +71,7 @@ func BuildPostgresClients(config config.Config) []*PostgresClient {
 func NewPostgresClient(config config.Config, configVar []string) *PostgresClient {
 	varName := configVar[0]
 	url := configVar[1]
-	// support both HEROKU_POSTGRESQL_BLUE_URL and BLUE_URL config vars
-	configName := strings.ReplaceAll(varName, "HEROKU_POSTGRESQL_", "")
-	configName = strings.ReplaceAll(configName, "_URL", "")
+	configName := configNameFromVarName(varName)
 	urlParts := strings.Split(url, "/")
 	database := urlParts[len(urlParts)-1]
 
@@ -93,6 +101,160 @@ func NewPostgresClient(config config.Config, configVar []string) *PostgresClient
 	}
 }
 
+// support both HEROKU_POSTGRESQL_BLUE_URL and BLUE_URL config vars
+func configNameFromVarName(varName string) string {
+	configName := strings.ReplaceAll(varName, "HEROKU_POSTGRESQL_", "")
+	return strings.ReplaceAll(configName, "_URL", "")
+}
+
+// merges clients whose config vars point at the same database into a single client
+// ex. DATABASE_URL and HEROKU_POSTGRESQL_GREEN_URL on heroku
+func DedupePostgresClients(postgresClients []*PostgresClient) []*PostgresClient {
+	var identities []string
+	groups := make(map[string][]*PostgresClient)
+
+	for _, postgresClient := range postgresClients {
+		identity := findServerIdentity(postgresClient)
+		if _, ok := groups[identity]; !ok {
+			identities = append(identities, identity)
+		}
+		groups[identity] = append(groups[identity], postgresClient)
+	}
+
+	var deduped []*PostgresClient
+	for _, identity := range identities {
+		deduped = append(deduped, mergePostgresClients(groups[identity]))
+	}
+
+	return deduped
+}
+
+// identifies the physical database by system identifier and database oid
+// falls back to the url when pg_control_system() isn't readable
+func findServerIdentity(postgresClient *PostgresClient) string {
+	// clients that couldn't connect are never merged
+	if postgresClient.client == nil || postgresClient.client.conn == nil {
+		return "config:" + postgresClient.serverID.ConfigVarName
+	}
+
+	var systemIdentifier string
+	var databaseOid string
+
+	query := `select (select system_identifier::text from pg_control_system()),
+						(select oid::text from pg_database where datname = current_database())` + postgresMonitorQueryComment()
+	err := postgresClient.client.QueryRow(query).Scan(&systemIdentifier, &databaseOid)
+	if err != nil {
+		logger.Debug("Unable to find system identifier", "configName", postgresClient.serverID.ConfigName, "err", err)
+		return "url:" + postgresClient.url
+	}
+
+	return "system:" + systemIdentifier + "/" + databaseOid
+}
+
+// keeps the preferred client and records the other clients as aliases
+func mergePostgresClients(postgresClients []*PostgresClient) *PostgresClient {
+	sort.SliceStable(postgresClients, func(i, j int) bool {
+		return configVarPreference(postgresClients[i].serverID.ConfigVarName) < configVarPreference(postgresClients[j].serverID.ConfigVarName)
+	})
+
+	merged := postgresClients[0]
+
+	var aliases []string
+	for _, alias := range postgresClients[1:] {
+		aliases = append(aliases, alias.serverID.ConfigVarName)
+		if alias.host != merged.host {
+			merged.aliasHosts = append(merged.aliasHosts, alias.host)
+		}
+
+		// only one connection is needed per database
+		if alias.client != nil && alias.client.conn != nil {
+			alias.client.conn.Close()
+		}
+
+		logger.Info("Merging Postgres config var alias", "configVarName", merged.serverID.ConfigVarName, "alias", alias.serverID.ConfigVarName)
+	}
+
+	sort.Strings(aliases)
+	merged.serverID.ConfigVarAliases = strings.Join(aliases, ",")
+
+	return merged
+}
+
+// prefer named heroku config vars since their config name matches log colors
+func configVarPreference(varName string) int {
+	if strings.HasPrefix(varName, "HEROKU_POSTGRESQL_") {
+		return 0
+	} else if varName != "DATABASE_URL" {
+		return 1
+	}
+	return 2
+}
+
+// matches the config name or any alias config name - ex. GREEN or DATABASE
+func (c *PostgresClient) MatchesConfigName(configName string) bool {
+	if c.serverID.ConfigName == configName {
+		return true
+	}
+
+	for _, alias := range c.serverID.Aliases() {
+		if configNameFromVarName(alias) == configName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches the url host or any alias url host
+func (c *PostgresClient) MatchesHost(host string) bool {
+	if c.host == host {
+		return true
+	}
+
+	for _, aliasHost := range c.aliasHosts {
+		if aliasHost == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unix sockets, loopback addresses and the machine's own addresses are on the agent's host
+func (c *PostgresClient) IsLocal() bool {
+	return isLocalHost(c.host)
+}
+
+func isLocalHost(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+
+	if hostname, err := os.Hostname(); err == nil && host == hostname {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func NewClient(config config.Config, dbURL string) *Client {
 	return &Client{
 		config: config,
@@ -129,6 +291,39 @@ func (c *PostgresClient) SetPgBouncerEnabled(enabled bool) {
 	c.pgBouncerEnabled = &enabled
 }
 
+// optional collectors check that the extension they depend on is installed before running
+// if extensions couldn't be loaded, assume the extension is installed and let the collector try
+func (c *PostgresClient) HasExtension(name string) bool {
+	c.extensionsMu.RLock()
+	defer c.extensionsMu.RUnlock()
+
+	if c.extensions == nil {
+		return true
+	}
+
+	for _, extension := range c.extensions {
+		if extension.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *PostgresClient) Extensions() []*Extension {
+	c.extensionsMu.RLock()
+	defer c.extensionsMu.RUnlock()
+
+	return c.extensions
+}
+
+func (c *PostgresClient) SetExtensions(extensions []*Extension) {
+	c.extensionsMu.Lock()
+	defer c.extensionsMu.Unlock()
+
+	c.extensions = extensions
+}
+
 // wrap pgx Query with mutex to ensure only one active connection is used at one time
 func (c *Client) Query(query string) (*sql.Rows, error) {
 	c.mu.Lock()
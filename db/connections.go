@@ -0,0 +1,223 @@
+package db
+
+import (
+	"agent/errors"
+	"agent/logger"
+	"database/sql"
+	"math"
+	"net"
+	"sort"
+	"time"
+)
+
+// max number of entities reported per connection breakdown before grouping into other
+const maxConnectionBreakdownEntities = 10
+
+// a pg_stat_activity row group used to break down connections
+type ConnectionGroup struct {
+	State           string
+	ApplicationName string
+	User            string
+	Database        string
+	BackendType     string
+	ClientAddr      string
+	Count           float64
+	MaxAge          float64 // seconds since the oldest backend in the group started
+}
+
+// breaks down connections by state, application, user, database, backend type and client
+// each breakdown is limited to the top connection counts with the rest grouped into other
+// other and unnamed connections are reported as separate metrics so they can't collide with real names
+func (m *MetricMonitor) FindConnectionBreakdownMetrics(postgresClient *PostgresClient) []*Metric {
+	// pg_stat_activity is scanned once and max_parallel_workers was added in postgres 10
+	query := `select coalesce(state, ''), coalesce(application_name, ''), coalesce(usename, ''), coalesce(datname, ''),
+						coalesce(backend_type, ''), coalesce(host(client_addr), ''), count(*),
+						coalesce(extract(epoch from max(now() - backend_start)), 0),
+						(select setting::int from pg_settings where name = 'max_parallel_workers')
+						from pg_stat_activity
+						group by 1, 2, 3, 4, 5, 6` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Connection breakdown metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+	defer rows.Close()
+
+	var groups []*ConnectionGroup
+	var maxParallelWorkers sql.NullFloat64
+
+	for rows.Next() {
+		var group ConnectionGroup
+
+		err := rows.Scan(
+			&group.State,
+			&group.ApplicationName,
+			&group.User,
+			&group.Database,
+			&group.BackendType,
+			&group.ClientAddr,
+			&group.Count,
+			&group.MaxAge,
+			&maxParallelWorkers,
+		)
+		if err != nil {
+			continue
+		}
+
+		groups = append(groups, &group)
+	}
+
+	now := time.Now().UTC().Unix()
+	metrics := connectionBreakdownMetrics(groups, *postgresClient.serverID, now)
+	metrics = append(metrics, connectionActivityMetrics(groups, maxParallelWorkers, *postgresClient.serverID, now)...)
+
+	return metrics
+}
+
+// reports the oldest client connection and parallel worker usage
+func connectionActivityMetrics(groups []*ConnectionGroup, maxParallelWorkers sql.NullFloat64, serverID ServerID, now int64) []*Metric {
+	var maxAge float64
+	var parallelWorkers float64
+
+	for _, group := range groups {
+		switch group.BackendType {
+		case "client backend":
+			maxAge = math.Max(maxAge, group.MaxAge)
+		case "parallel worker":
+			parallelWorkers += group.Count
+		}
+	}
+
+	metrics := []*Metric{
+		NewMetric("connections.age.max", maxAge, "", serverID, now),
+		NewMetric("connections.parallel_workers.used", parallelWorkers, "", serverID, now),
+	}
+	if maxParallelWorkers.Valid {
+		metrics = append(metrics, NewMetric("connections.parallel_workers.max", maxParallelWorkers.Float64, "", serverID, now))
+	}
+
+	return metrics
+}
+
+func connectionBreakdownMetrics(groups []*ConnectionGroup, serverID ServerID, now int64) []*Metric {
+	states := make(map[string]float64)
+	applications := make(map[string]float64)
+	users := make(map[string]float64)
+	databases := make(map[string]float64)
+	backendTypes := make(map[string]float64)
+	clients := make(map[string]float64)
+
+	for _, group := range groups {
+		if group.BackendType != "" {
+			backendTypes[group.BackendType] += group.Count
+		}
+
+		// background processes don't have a state, user or client
+		if group.BackendType != "" && group.BackendType != "client backend" {
+			continue
+		}
+
+		if group.State != "" {
+			states[group.State] += group.Count
+		}
+		applications[group.ApplicationName] += group.Count
+		if group.User != "" {
+			users[group.User] += group.Count
+		}
+		if group.Database != "" {
+			databases[group.Database] += group.Count
+		}
+		clients[clientNetwork(group.ClientAddr)] += group.Count
+	}
+
+	var metrics []*Metric
+
+	breakdowns := []struct {
+		name   string
+		prefix string
+		counts map[string]float64
+	}{
+		{"connections.state", "state/", states},
+		{"connections.application", "application/", applications},
+		{"connections.user", "user/", users},
+		{"connections.database", "database/", databases},
+		{"connections.backend_type", "backend_type/", backendTypes},
+		{"connections.client", "client/", clients},
+	}
+
+	for _, breakdown := range breakdowns {
+		counts := topConnectionCounts(breakdown.counts, maxConnectionBreakdownEntities)
+		for _, count := range counts.top {
+			metrics = append(metrics, NewMetric(breakdown.name, count.count, breakdown.prefix+count.name, serverID, now))
+		}
+		if counts.unknown > 0 {
+			metrics = append(metrics, NewMetric(breakdown.name+".unknown", counts.unknown, "", serverID, now))
+		}
+		if counts.other > 0 {
+			metrics = append(metrics, NewMetric(breakdown.name+".other", counts.other, "", serverID, now))
+		}
+	}
+
+	return metrics
+}
+
+type connectionCount struct {
+	name  string
+	count float64
+}
+
+type connectionCounts struct {
+	top     []connectionCount
+	unknown float64 // connections without a name - ex. no application_name or a unix socket client
+	other   float64 // connections outside of the top counts
+}
+
+// returns the top n counts by count with the remaining counts summed into other
+func topConnectionCounts(counts map[string]float64, n int) *connectionCounts {
+	result := &connectionCounts{}
+
+	var sorted []connectionCount
+	for name, count := range counts {
+		if name == "" {
+			result.unknown += count
+			continue
+		}
+		sorted = append(sorted, connectionCount{name: name, count: count})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count == sorted[j].count {
+			return sorted[i].name < sorted[j].name
+		}
+		return sorted[i].count > sorted[j].count
+	})
+
+	if len(sorted) <= n {
+		result.top = sorted
+		return result
+	}
+
+	result.top = sorted[:n]
+	for _, count := range sorted[n:] {
+		result.other += count.count
+	}
+
+	return result
+}
+
+// we do not want to expose client IPs so clients are grouped by their /24 or /64 network
+// unix socket connections don't have a client address
+func clientNetwork(clientAddr string) string {
+	ip := net.ParseIP(clientAddr)
+	if ip == nil {
+		return ""
+	}
+
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return (&net.IPNet{IP: ipv4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionBreakdownMetrics(t *testing.T) {
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+	groups := []*ConnectionGroup{
+		{State: "active", ApplicationName: "web", User: "app", Database: "test", BackendType: "client backend", ClientAddr: "10.0.1.12", Count: 5},
+		{State: "idle", ApplicationName: "web", User: "app", Database: "test", BackendType: "client backend", ClientAddr: "54.12.1.3", Count: 3},
+		{State: "idle in transaction", ApplicationName: "", User: "admin", Database: "test", BackendType: "client backend", Count: 1},
+		{BackendType: "autovacuum launcher", Count: 1},
+	}
+
+	metrics := connectionBreakdownMetrics(groups, serverID, 1000)
+
+	values := make(map[string]float64)
+	for _, metric := range metrics {
+		values[metric.Name+" "+metric.Entity] = metric.Value
+	}
+
+	assert.Equal(t, map[string]float64{
+		"connections.state state/active":                            5,
+		"connections.state state/idle":                              3,
+		"connections.state state/idle in transaction":               1,
+		"connections.application application/web":                   8,
+		"connections.application.unknown ":                          1,
+		"connections.user user/app":                                 8,
+		"connections.user user/admin":                               1,
+		"connections.database database/test":                        9,
+		"connections.backend_type backend_type/client backend":      9,
+		"connections.backend_type backend_type/autovacuum launcher": 1,
+		"connections.client client/10.0.1.0/24":                     5,
+		"connections.client client/54.12.1.0/24":                    3,
+		"connections.client.unknown ":                               1,
+	}, values)
+}
+
+func TestTopConnectionCounts(t *testing.T) {
+	counts := topConnectionCounts(map[string]float64{"web": 10, "worker": 5, "console": 1, "cron": 1, "": 2}, 2)
+
+	assert.Equal(t, &connectionCounts{
+		top: []connectionCount{
+			{name: "web", count: 10},
+			{name: "worker", count: 5},
+		},
+		unknown: 2,
+		other:   2,
+	}, counts)
+
+	// real names that match the unknown and other metrics are still reported by name
+	counts = topConnectionCounts(map[string]float64{"other": 3, "unknown": 1}, 2)
+	assert.Equal(t, &connectionCounts{top: []connectionCount{{name: "other", count: 3}, {name: "unknown", count: 1}}}, counts)
+}
+
+func TestClientNetwork(t *testing.T) {
+	assert.Equal(t, "10.0.1.0/24", clientNetwork("10.0.1.12"))
+	assert.Equal(t, "127.0.0.0/24", clientNetwork("127.0.0.1"))
+	assert.Equal(t, "2001:db8:1:2::/64", clientNetwork("2001:db8:1:2:3:4:5:6"))
+	assert.Equal(t, "", clientNetwork(""))
+	assert.Equal(t, "", clientNetwork("invalid"))
+}
+
+func TestConnectionActivityMetrics(t *testing.T) {
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+	groups := []*ConnectionGroup{
+		{State: "active", BackendType: "client backend", Count: 5, MaxAge: 120},
+		{State: "idle", BackendType: "client backend", Count: 3, MaxAge: 3600},
+		{State: "active", BackendType: "parallel worker", Count: 2, MaxAge: 1},
+		{BackendType: "checkpointer", Count: 1, MaxAge: 86400},
+	}
+
+	values := make(map[string]float64)
+	for _, metric := range connectionActivityMetrics(groups, sql.NullFloat64{Valid: true, Float64: 8}, serverID, 1000) {
+		values[metric.Name] = metric.Value
+	}
+
+	// background processes don't count towards the oldest connection
+	assert.Equal(t, map[string]float64{
+		"connections.age.max":               3600,
+		"connections.parallel_workers.used": 2,
+		"connections.parallel_workers.max":  8,
+	}, values)
+
+	// max_parallel_workers isn't available before postgres 10
+	assert.Equal(t, 2, len(connectionActivityMetrics(groups, sql.NullFloat64{}, serverID, 1000)))
+}
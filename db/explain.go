@@ -3,26 +3,38 @@ package db
 import (
 	"agent/errors"
 	"agent/logger"
+	"sync"
 	"time"
 )
 
 var MaxQueryLength = 30000
 
+// auto_explain can log a plan after the slow query so slow queries wait this long for it before running EXPLAIN
+var loggedPlanWait = 5 * time.Second
+
 type Explainer struct {
 	// cache of query fingerprint to expiration time
 	explained map[string]time.Time
+
+	// obfuscated plans logged by auto_explain that haven't been reported yet
+	loggedPlans map[loggedPlanKey]*LoggedPlan
+
+	// when auto_explain last logged a plan per server config name
+	autoExplainedAt map[string]time.Time
+
+	mu sync.Mutex
+}
+
+type loggedPlanKey struct {
+	configName  string
+	fingerprint string
 }
 
 func (e *Explainer) Explain(postgresClient *PostgresClient, slowQuery *SlowQuery) string {
 	var explain string
 
-	if e.explained == nil {
-		e.explained = make(map[string]time.Time)
-	}
-
 	// only explain queries once per hour
-	expiration, ok := e.explained[slowQuery.Fingerprint]
-	if ok && time.Now().UTC().Before(expiration) {
+	if e.recentlyExplained(slowQuery.Fingerprint) {
 		return explain
 	}
 
@@ -53,7 +65,79 @@ func (e *Explainer) Explain(postgresClient *PostgresClient, slowQuery *SlowQuery
 	}
 
 	// add expiration for cached query fingerprint
+	e.mu.Lock()
 	e.explained[slowQuery.Fingerprint] = time.Now().UTC().Add(1 * time.Hour)
+	e.mu.Unlock()
 
 	return explain
 }
+
+func (e *Explainer) recentlyExplained(fingerprint string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.explained == nil {
+		e.explained = make(map[string]time.Time)
+	}
+
+	expiration, ok := e.explained[fingerprint]
+	return ok && time.Now().UTC().Before(expiration)
+}
+
+// caches a plan logged by auto_explain and skips running EXPLAIN for the query for the next hour
+func (e *Explainer) AddLoggedPlan(plan *LoggedPlan) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.explained == nil {
+		e.explained = make(map[string]time.Time)
+	}
+	if e.loggedPlans == nil {
+		e.loggedPlans = make(map[loggedPlanKey]*LoggedPlan)
+	}
+	if e.autoExplainedAt == nil {
+		e.autoExplainedAt = make(map[string]time.Time)
+	}
+
+	now := time.Now().UTC()
+
+	// drop plans that were never matched to a slow query or query stats
+	for key, loggedPlan := range e.loggedPlans {
+		if now.After(loggedPlan.expiresAt) {
+			delete(e.loggedPlans, key)
+		}
+	}
+
+	plan.expiresAt = now.Add(1 * time.Hour)
+	e.loggedPlans[loggedPlanKey{configName: plan.ServerConfigName, fingerprint: plan.Fingerprint}] = plan
+	e.explained[plan.Fingerprint] = plan.expiresAt
+	e.autoExplainedAt[plan.ServerConfigName] = now
+}
+
+// auto_explain is considered enabled for a server once it has logged a plan within the last hour
+func (e *Explainer) AutoExplainActive(configName string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	loggedAt, ok := e.autoExplainedAt[configName]
+	return ok && time.Now().UTC().Before(loggedAt.Add(1*time.Hour))
+}
+
+// returns and removes the logged plan for a query so each plan is reported once
+func (e *Explainer) TakeLoggedPlan(configName string, fingerprint string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := loggedPlanKey{configName: configName, fingerprint: fingerprint}
+	plan, ok := e.loggedPlans[key]
+	if !ok {
+		return ""
+	}
+
+	delete(e.loggedPlans, key)
+	if time.Now().UTC().After(plan.expiresAt) {
+		return ""
+	}
+
+	return plan.Obfuscated
+}
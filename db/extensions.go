@@ -0,0 +1,124 @@
+package db
+
+import (
+	"agent/errors"
+	"agent/logger"
+	"agent/util"
+
+	"github.com/jackc/pgtype"
+)
+
+// extensions that optional collectors depend on
+const (
+	PgStatStatementsExtension = "pg_stat_statements"
+)
+
+// https://www.postgresql.org/docs/current/catalog-pg-extension.html
+type Extension struct {
+	Name             string
+	Schema           string
+	Version          string
+	LatestVersion    string
+	UpgradeAvailable bool
+}
+
+type ExtensionMonitor struct{}
+
+func (o *Observer) MonitorExtensions() {
+	for _, postgresClient := range o.postgresClients {
+		go NewMonitorWorker(
+			o.config,
+			postgresClient,
+			&ExtensionMonitor{},
+		).Start()
+	}
+}
+
+// extensions are cached on the client and reported with the server metadata
+func (m *ExtensionMonitor) Run(postgresClient *PostgresClient) {
+	extensions := m.FindExtensions(postgresClient)
+	if extensions != nil {
+		postgresClient.SetExtensions(extensions)
+	}
+}
+
+func (m *ExtensionMonitor) FindExtensions(postgresClient *PostgresClient) []*Extension {
+	query := `select e.extname as name, n.nspname as schema, e.extversion as version,
+						coalesce(av.default_version, '') as default_version,
+						coalesce((select array_agg(v.version) from pg_available_extension_versions v
+							where v.name = e.extname), '{}') as available_versions
+						from pg_extension e
+						join pg_namespace n on n.oid = e.extnamespace
+						left join pg_available_extensions av on av.name = e.extname` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find extensions error", "err", err)
+		errors.Report(err)
+		return nil
+	}
+	defer rows.Close()
+
+	extensions := []*Extension{}
+
+	for rows.Next() {
+		var extension Extension
+		var defaultVersion string
+		var availableVersions pgtype.TextArray
+
+		err := rows.Scan(
+			&extension.Name,
+			&extension.Schema,
+			&extension.Version,
+			&defaultVersion,
+			&availableVersions,
+		)
+		if err != nil {
+			logger.Error("Find extensions error", "err", err)
+			errors.Report(err)
+			continue
+		}
+
+		extension.LatestVersion = latestExtensionVersion(textArrayToStrings(availableVersions), defaultVersion)
+		extension.UpgradeAvailable = extensionUpgradeAvailable(extension.Version, extension.LatestVersion)
+
+		extensions = append(extensions, &extension)
+	}
+
+	return extensions
+}
+
+// extension versions are free form strings so fall back to the default
+// version when none of the available versions can be compared
+func latestExtensionVersion(availableVersions []string, defaultVersion string) string {
+	latest := ""
+	for _, version := range availableVersions {
+		if !util.VersionValid(version) {
+			continue
+		}
+
+		if latest == "" || util.VersionGreaterThan(version, latest) {
+			latest = version
+		}
+	}
+
+	if latest == "" {
+		return defaultVersion
+	}
+
+	return latest
+}
+
+func extensionUpgradeAvailable(installedVersion string, latestVersion string) bool {
+	if installedVersion == "" || latestVersion == "" || installedVersion == latestVersion {
+		return false
+	}
+
+	c, err := util.VersionCompare(latestVersion, installedVersion)
+	if err != nil {
+		// versions can't be compared but they differ
+		return true
+	}
+
+	return c > 0
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestExtensionVersion(t *testing.T) {
+	assert.Equal(t, "1.10", latestExtensionVersion([]string{"1.4", "1.10", "1.9"}, "1.9"))
+	assert.Equal(t, "1.5", latestExtensionVersion([]string{"1.5"}, "1.5"))
+	assert.Equal(t, "unpackaged", latestExtensionVersion([]string{"unpackaged"}, "unpackaged"))
+	assert.Equal(t, "", latestExtensionVersion([]string{}, ""))
+}
+
+func TestExtensionUpgradeAvailable(t *testing.T) {
+	assert.True(t, extensionUpgradeAvailable("1.8", "1.10"))
+	assert.False(t, extensionUpgradeAvailable("1.10", "1.10"))
+	assert.False(t, extensionUpgradeAvailable("1.10", "1.9"))
+	assert.False(t, extensionUpgradeAvailable("1.10", ""))
+	assert.True(t, extensionUpgradeAvailable("unpackaged", "1.0"))
+}
+
+func TestHasExtension(t *testing.T) {
+	postgresClient := &PostgresClient{}
+
+	// extensions haven't been loaded yet
+	assert.True(t, postgresClient.HasExtension(PgStatStatementsExtension))
+
+	postgresClient.SetExtensions([]*Extension{{Name: PgStatStatementsExtension}})
+	assert.True(t, postgresClient.HasExtension(PgStatStatementsExtension))
+	assert.False(t, postgresClient.HasExtension("pg_buffercache"))
+}
@@ -13,3 +13,16 @@ func fingerprintQuery(query string) string {
 	checksum := md5.Sum([]byte(query))
 	return hex.EncodeToString(checksum[:])[0:10]
 }
+
+// obfuscates and fingerprints a query from the logs the same way as slow queries so fingerprints match query stats
+// the query comment should be removed first and the obfuscated query is truncated after fingerprinting
+func (o *Observer) obfuscateAndFingerprint(raw string) (string, string) {
+	obfuscated := CleanQuery(o.obfuscator.ObfuscateQuery(raw))
+	fingerprint := fingerprintQuery(obfuscated)
+
+	if len(obfuscated) > 5000 {
+		obfuscated = TruncateQuery(obfuscated)
+	}
+
+	return obfuscated, fingerprint
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateAndFingerprint(t *testing.T) {
+	observer := &Observer{obfuscator: NewObfuscator()}
+
+	obfuscated, fingerprint := observer.obfuscateAndFingerprint("SELECT *\n\tFROM users WHERE id = 1")
+	assert.Equal(t, "SELECT * FROM users WHERE id = ?", obfuscated)
+	assert.Equal(t, fingerprintQuery("SELECT * FROM users WHERE id = ?"), fingerprint)
+
+	// the same query with other values and whitespace has the same fingerprint
+	_, other := observer.obfuscateAndFingerprint("SELECT * FROM users   WHERE id = 2")
+	assert.Equal(t, fingerprint, other)
+
+	// long queries are fingerprinted before truncating
+	long := "SELECT " + strings.Repeat("a, ", 2000) + "b FROM users"
+	obfuscated, fingerprint = observer.obfuscateAndFingerprint(long)
+	assert.Equal(t, fingerprintQuery(long), fingerprint)
+	assert.Equal(t, TruncateQuery(long), obfuscated)
+}
@@ -0,0 +1,615 @@
+package db
+
+import (
+	"agent/errors"
+	"agent/logger"
+	"agent/util"
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cgroup v1 reports an unlimited memory limit as a very large page aligned number
+const cgroupUnlimitedMemory = int64(1) << 62
+
+// stateful host stats that stores cumulative /proc counters per server
+// deltas are calculated each polling interval and reported as metrics
+type HostStatsState struct {
+	CPU      map[ServerID]*CPUStats
+	Disks    map[ServerID]map[string]*DiskStats
+	Networks map[ServerID]map[string]*NetworkStats
+	mu       sync.Mutex
+}
+
+// cumulative cpu time in USER_HZ from the cpu line of /proc/stat
+// https://man7.org/linux/man-pages/man5/proc.5.html
+type CPUStats struct {
+	User    float64
+	Nice    float64
+	System  float64
+	Idle    float64
+	IOWait  float64
+	IRQ     float64
+	SoftIRQ float64
+	Steal   float64
+}
+
+// https://www.kernel.org/doc/Documentation/ABI/testing/procfs-diskstats
+type DiskStats struct {
+	Reads          float64
+	SectorsRead    float64
+	Writes         float64
+	SectorsWritten float64
+	IOTime         float64 // ms
+}
+
+// from /proc/net/dev
+type NetworkStats struct {
+	ReceivedBytes   float64
+	ReceivedPackets float64
+	SentBytes       float64
+	SentPackets     float64
+}
+
+type LoadAverage struct {
+	OneMinute     float64
+	FiveMinute    float64
+	FifteenMinute float64
+}
+
+type DiskUsage struct {
+	TotalBytes     float64
+	AvailableBytes float64
+	UsedBytes      float64
+}
+
+// container limits from cgroups - zero values mean no limit
+type CgroupLimits struct {
+	MemoryLimitBytes float64
+	MemoryUsedBytes  float64
+	CPULimitCores    float64
+}
+
+func (c *CPUStats) Total() float64 {
+	return c.User + c.Nice + c.System + c.Idle + c.IOWait + c.IRQ + c.SoftIRQ + c.Steal
+}
+
+// collects host metrics from procfs when the agent runs on the database host or as a sidecar
+type HostMonitor struct {
+	metricsChannel chan []*Metric
+	hostStatsState *HostStatsState
+	procPath       string
+	sysPath        string
+}
+
+func (m *HostMonitor) Run(postgresClient *PostgresClient) {
+	now := time.Now().UTC().Unix()
+	serverID := *postgresClient.serverID
+
+	var metrics []*Metric
+	metrics = append(metrics, m.FindCPUMetrics(serverID, now)...)
+	metrics = append(metrics, m.FindLoadMetrics(serverID, now)...)
+	metrics = append(metrics, m.FindMemoryMetrics(serverID, now)...)
+	metrics = append(metrics, m.FindDiskUsageMetrics(postgresClient, now)...)
+	metrics = append(metrics, m.FindDiskIOMetrics(serverID, now)...)
+	metrics = append(metrics, m.FindNetworkMetrics(serverID, now)...)
+
+	select {
+	case m.metricsChannel <- metrics:
+		// sent
+	default:
+		logger.Warn("Dropping host metrics: channel buffer full")
+	}
+}
+
+func (m *HostMonitor) FindCPUMetrics(serverID ServerID, now int64) []*Metric {
+	content, err := os.ReadFile(filepath.Join(m.procPath, "stat"))
+	if err != nil {
+		logger.Error("Host cpu metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	cpu, err := parseProcStat(string(content))
+	if err != nil {
+		logger.Error("Host cpu metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	var metrics []*Metric
+
+	limits := m.findCgroupLimits()
+	if limits.CPULimitCores > 0 {
+		metrics = append(metrics, NewMetric("host.cpu.limit", limits.CPULimitCores, "", serverID, now))
+	}
+
+	m.hostStatsState.mu.Lock()
+	defer m.hostStatsState.mu.Unlock()
+
+	if m.hostStatsState.CPU == nil {
+		m.hostStatsState.CPU = make(map[ServerID]*CPUStats)
+	}
+
+	previous, ok := m.hostStatsState.CPU[serverID]
+	m.hostStatsState.CPU[serverID] = cpu
+	if !ok {
+		// only report cpu percents once there is a delta from two consecutive polls
+		return metrics
+	}
+
+	total := cpu.Total() - previous.Total()
+	if total <= 0 {
+		return metrics
+	}
+
+	return append(metrics,
+		NewMetric("host.cpu.user.percent", util.Percent(cpu.User+cpu.Nice-previous.User-previous.Nice, total), "", serverID, now),
+		NewMetric("host.cpu.system.percent", util.Percent(cpu.System+cpu.IRQ+cpu.SoftIRQ-previous.System-previous.IRQ-previous.SoftIRQ, total), "", serverID, now),
+		NewMetric("host.cpu.iowait.percent", util.Percent(cpu.IOWait-previous.IOWait, total), "", serverID, now),
+		NewMetric("host.cpu.steal.percent", util.Percent(cpu.Steal-previous.Steal, total), "", serverID, now),
+		NewMetric("host.cpu.idle.percent", util.Percent(cpu.Idle-previous.Idle, total), "", serverID, now),
+	)
+}
+
+func (m *HostMonitor) FindLoadMetrics(serverID ServerID, now int64) []*Metric {
+	content, err := os.ReadFile(filepath.Join(m.procPath, "loadavg"))
+	if err != nil {
+		logger.Error("Host load metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	load, err := parseLoadAverage(string(content))
+	if err != nil {
+		logger.Error("Host load metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	return []*Metric{
+		NewMetric("host.load.1m", load.OneMinute, "", serverID, now),
+		NewMetric("host.load.5m", load.FiveMinute, "", serverID, now),
+		NewMetric("host.load.15m", load.FifteenMinute, "", serverID, now),
+	}
+}
+
+func (m *HostMonitor) FindMemoryMetrics(serverID ServerID, now int64) []*Metric {
+	content, err := os.ReadFile(filepath.Join(m.procPath, "meminfo"))
+	if err != nil {
+		logger.Error("Host memory metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	memInfo := parseMemInfo(string(content))
+
+	total := memInfo["MemTotal"]
+	available := memInfo["MemAvailable"]
+	used := total - available
+
+	// containers see the host's memory in /proc/meminfo so use cgroup limits when they're lower
+	limits := m.findCgroupLimits()
+	if limits.MemoryLimitBytes > 0 && limits.MemoryLimitBytes < total {
+		total = limits.MemoryLimitBytes
+		used = limits.MemoryUsedBytes
+		available = total - used
+	}
+
+	return []*Metric{
+		NewMetric("host.memory.total", total, "", serverID, now),
+		NewMetric("host.memory.available", available, "", serverID, now),
+		NewMetric("host.memory.used", used, "", serverID, now),
+		NewMetric("host.memory.cached", memInfo["Cached"], "", serverID, now),
+		NewMetric("host.swap.total", memInfo["SwapTotal"], "", serverID, now),
+		NewMetric("host.swap.used", memInfo["SwapTotal"]-memInfo["SwapFree"], "", serverID, now),
+	}
+}
+
+// reports disk usage for the mounts that contain the data and wal directories
+func (m *HostMonitor) FindDiskUsageMetrics(postgresClient *PostgresClient, now int64) []*Metric {
+	var dataDirectory sql.NullString
+	var versionNum int
+
+	// data_directory is only visible to superusers and pg_read_all_settings members
+	query := `select setting, current_setting('server_version_num')::int
+						from pg_settings where name = 'data_directory'` + postgresMonitorQueryComment()
+	err := postgresClient.client.QueryRow(query).Scan(&dataDirectory, &versionNum)
+	if err != nil || !dataDirectory.Valid {
+		return []*Metric{}
+	}
+
+	directories := map[string]string{
+		"data": dataDirectory.String,
+		// the wal directory is commonly a symlink to a separate mount
+		"wal": walDirectory(dataDirectory.String, versionNum),
+	}
+
+	var metrics []*Metric
+
+	for name, directory := range directories {
+		usage, err := findDiskUsage(directory)
+		if err != nil {
+			logger.Debug("Host disk usage error", "directory", directory, "err", err)
+			continue
+		}
+
+		entity := "directory/" + name
+		metrics = append(metrics,
+			NewMetric("host.disk.total", usage.TotalBytes, entity, *postgresClient.serverID, now),
+			NewMetric("host.disk.available", usage.AvailableBytes, entity, *postgresClient.serverID, now),
+			NewMetric("host.disk.used", usage.UsedBytes, entity, *postgresClient.serverID, now),
+			NewMetric("host.disk.used.percent", util.Percent(usage.UsedBytes, usage.UsedBytes+usage.AvailableBytes), entity, *postgresClient.serverID, now),
+		)
+	}
+
+	return metrics
+}
+
+// pg_xlog was renamed to pg_wal in postgres 10
+func walDirectory(dataDirectory string, versionNum int) string {
+	if versionNum > 0 && versionNum < 100000 {
+		return filepath.Join(dataDirectory, "pg_xlog")
+	}
+	return filepath.Join(dataDirectory, "pg_wal")
+}
+
+func (m *HostMonitor) FindDiskIOMetrics(serverID ServerID, now int64) []*Metric {
+	content, err := os.ReadFile(filepath.Join(m.procPath, "diskstats"))
+	if err != nil {
+		logger.Error("Host disk io metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	disks := m.filterWholeDisks(parseDiskStats(string(content)))
+
+	m.hostStatsState.mu.Lock()
+	defer m.hostStatsState.mu.Unlock()
+
+	if m.hostStatsState.Disks == nil {
+		m.hostStatsState.Disks = make(map[ServerID]map[string]*DiskStats)
+	}
+
+	previousDisks, ok := m.hostStatsState.Disks[serverID]
+	m.hostStatsState.Disks[serverID] = disks
+	if !ok {
+		return []*Metric{}
+	}
+
+	var metrics []*Metric
+
+	for device, disk := range disks {
+		previous, ok := previousDisks[device]
+		if !ok {
+			continue
+		}
+
+		// sectors are always 512 bytes in diskstats
+		entity := "device/" + device
+		metrics = append(metrics,
+			NewMetric("host.disk.io.reads", disk.Reads-previous.Reads, entity, serverID, now),
+			NewMetric("host.disk.io.writes", disk.Writes-previous.Writes, entity, serverID, now),
+			NewMetric("host.disk.io.read.bytes", (disk.SectorsRead-previous.SectorsRead)*512, entity, serverID, now),
+			NewMetric("host.disk.io.write.bytes", (disk.SectorsWritten-previous.SectorsWritten)*512, entity, serverID, now),
+			NewMetric("host.disk.io.time", disk.IOTime-previous.IOTime, entity, serverID, now),
+		)
+	}
+
+	return metrics
+}
+
+// partitions are also counted by their disk so only whole disks in /sys/block are kept
+// all devices are kept when sysfs isn't available
+func (m *HostMonitor) filterWholeDisks(disks map[string]*DiskStats) map[string]*DiskStats {
+	entries, err := os.ReadDir(filepath.Join(m.sysPath, "block"))
+	if err != nil || len(entries) == 0 {
+		return disks
+	}
+
+	blockDevices := make(map[string]bool)
+	for _, entry := range entries {
+		blockDevices[entry.Name()] = true
+	}
+
+	wholeDisks := make(map[string]*DiskStats)
+	for device, disk := range disks {
+		// sysfs replaces slashes in device names - ex. cciss/c0d0 is cciss!c0d0
+		if blockDevices[strings.ReplaceAll(device, "/", "!")] {
+			wholeDisks[device] = disk
+		}
+	}
+
+	return wholeDisks
+}
+
+func (m *HostMonitor) FindNetworkMetrics(serverID ServerID, now int64) []*Metric {
+	content, err := os.ReadFile(filepath.Join(m.procPath, "net", "dev"))
+	if err != nil {
+		logger.Error("Host network metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	networks := parseNetDev(string(content))
+
+	m.hostStatsState.mu.Lock()
+	defer m.hostStatsState.mu.Unlock()
+
+	if m.hostStatsState.Networks == nil {
+		m.hostStatsState.Networks = make(map[ServerID]map[string]*NetworkStats)
+	}
+
+	previousNetworks, ok := m.hostStatsState.Networks[serverID]
+	m.hostStatsState.Networks[serverID] = networks
+	if !ok {
+		return []*Metric{}
+	}
+
+	var metrics []*Metric
+
+	for name, network := range networks {
+		previous, ok := previousNetworks[name]
+		if !ok {
+			continue
+		}
+
+		entity := "interface/" + name
+		metrics = append(metrics,
+			NewMetric("host.network.received.bytes", network.ReceivedBytes-previous.ReceivedBytes, entity, serverID, now),
+			NewMetric("host.network.received.packets", network.ReceivedPackets-previous.ReceivedPackets, entity, serverID, now),
+			NewMetric("host.network.sent.bytes", network.SentBytes-previous.SentBytes, entity, serverID, now),
+			NewMetric("host.network.sent.packets", network.SentPackets-previous.SentPackets, entity, serverID, now),
+		)
+	}
+
+	return metrics
+}
+
+// supports both cgroup v2 and v1 hierarchies
+func (m *HostMonitor) findCgroupLimits() *CgroupLimits {
+	limits := &CgroupLimits{}
+	cgroupPath := filepath.Join(m.sysPath, "fs", "cgroup")
+
+	// cgroup v2
+	if content, err := os.ReadFile(filepath.Join(cgroupPath, "memory.max")); err == nil {
+		limits.MemoryLimitBytes = parseCgroupValue(string(content))
+		if content, err := os.ReadFile(filepath.Join(cgroupPath, "memory.current")); err == nil {
+			limits.MemoryUsedBytes = parseCgroupValue(string(content))
+		}
+		if content, err := os.ReadFile(filepath.Join(cgroupPath, "cpu.max")); err == nil {
+			limits.CPULimitCores = parseCgroupCPUMax(string(content))
+		}
+		return limits
+	}
+
+	// cgroup v1
+	if content, err := os.ReadFile(filepath.Join(cgroupPath, "memory", "memory.limit_in_bytes")); err == nil {
+		limits.MemoryLimitBytes = parseCgroupValue(string(content))
+		if content, err := os.ReadFile(filepath.Join(cgroupPath, "memory", "memory.usage_in_bytes")); err == nil {
+			limits.MemoryUsedBytes = parseCgroupValue(string(content))
+		}
+	}
+	quota, quotaErr := os.ReadFile(filepath.Join(cgroupPath, "cpu", "cpu.cfs_quota_us"))
+	period, periodErr := os.ReadFile(filepath.Join(cgroupPath, "cpu", "cpu.cfs_period_us"))
+	if quotaErr == nil && periodErr == nil {
+		limits.CPULimitCores = parseCgroupCPUMax(strings.TrimSpace(string(quota)) + " " + strings.TrimSpace(string(period)))
+	}
+
+	return limits
+}
+
+// parses the aggregate cpu line from /proc/stat
+// ex. cpu  10132153 290696 3084719 46828483 16683 0 25195 0 0 0
+func parseProcStat(content string) (*CPUStats, error) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 9 || fields[0] != "cpu" {
+			continue
+		}
+
+		values := make([]float64, 8)
+		for i := range values {
+			value, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+
+		return &CPUStats{
+			User:    values[0],
+			Nice:    values[1],
+			System:  values[2],
+			Idle:    values[3],
+			IOWait:  values[4],
+			IRQ:     values[5],
+			SoftIRQ: values[6],
+			Steal:   values[7],
+		}, nil
+	}
+
+	return nil, fmt.Errorf("missing cpu line in /proc/stat")
+}
+
+// ex. 0.20 0.18 0.12 1/80 11206
+func parseLoadAverage(content string) (*LoadAverage, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid /proc/loadavg: %s", content)
+	}
+
+	values := make([]float64, 3)
+	for i := range values {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return &LoadAverage{
+		OneMinute:     values[0],
+		FiveMinute:    values[1],
+		FifteenMinute: values[2],
+	}, nil
+}
+
+// returns /proc/meminfo values in bytes
+// ex. MemTotal:       16333644 kB
+func parseMemInfo(content string) map[string]float64 {
+	memInfo := make(map[string]float64)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+
+		memInfo[strings.TrimSuffix(fields[0], ":")] = value
+	}
+
+	return memInfo
+}
+
+// ex. 8       0 sda 20846 5683 1346316 11634 26366 25764 1364464 30468 0 34384 42102
+func parseDiskStats(content string) map[string]*DiskStats {
+	disks := make(map[string]*DiskStats)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 {
+			continue
+		}
+
+		device := fields[2]
+		// skip virtual devices
+		if strings.HasPrefix(device, "loop") || strings.HasPrefix(device, "ram") {
+			continue
+		}
+
+		values := make([]float64, 10)
+		valid := true
+		for i := range values {
+			value, err := strconv.ParseFloat(fields[i+3], 64)
+			if err != nil {
+				valid = false
+				break
+			}
+			values[i] = value
+		}
+		if !valid {
+			continue
+		}
+
+		disks[device] = &DiskStats{
+			Reads:          values[0],
+			SectorsRead:    values[2],
+			Writes:         values[4],
+			SectorsWritten: values[6],
+			IOTime:         values[9],
+		}
+	}
+
+	return disks
+}
+
+// ex.   eth0: 1432927   1852    0    0    0     0          0         0   184291    1489    0    0    0     0       0          0
+func parseNetDev(content string) map[string]*NetworkStats {
+	networks := make(map[string]*NetworkStats)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		nameStats := strings.SplitN(scanner.Text(), ":", 2)
+		if len(nameStats) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(nameStats[0])
+		if name == "lo" {
+			continue
+		}
+
+		fields := strings.Fields(nameStats[1])
+		if len(fields) < 10 {
+			continue
+		}
+
+		values := make([]float64, 10)
+		valid := true
+		for i := range values {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				valid = false
+				break
+			}
+			values[i] = value
+		}
+		if !valid {
+			continue
+		}
+
+		networks[name] = &NetworkStats{
+			ReceivedBytes:   values[0],
+			ReceivedPackets: values[1],
+			SentBytes:       values[8],
+			SentPackets:     values[9],
+		}
+	}
+
+	return networks
+}
+
+// returns 0 for unlimited values - ex. max in v2 or a near max int in v1
+func parseCgroupValue(content string) float64 {
+	content = strings.TrimSpace(content)
+	if content == "max" {
+		return 0
+	}
+
+	value, err := strconv.ParseInt(content, 10, 64)
+	if err != nil || value >= cgroupUnlimitedMemory {
+		return 0
+	}
+
+	return float64(value)
+}
+
+// parses cpu.max as "quota period" and returns the limit in cores
+// ex. 200000 100000 is 2 cores and max 100000 is unlimited
+func parseCgroupCPUMax(content string) float64 {
+	fields := strings.Fields(content)
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0
+	}
+
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || quota <= 0 {
+		return 0
+	}
+
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || period <= 0 {
+		return 0
+	}
+
+	return util.Round(quota / period)
+}
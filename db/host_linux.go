@@ -0,0 +1,25 @@
+//go:build linux
+
+package db
+
+import "syscall"
+
+func findDiskUsage(path string) (*DiskUsage, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSize := float64(stat.Bsize)
+	total := float64(stat.Blocks) * blockSize
+	// available is what non-root users can use which excludes reserved blocks
+	available := float64(stat.Bavail) * blockSize
+	used := total - float64(stat.Bfree)*blockSize
+
+	return &DiskUsage{
+		TotalBytes:     total,
+		AvailableBytes: available,
+		UsedBytes:      used,
+	}, nil
+}
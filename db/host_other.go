@@ -0,0 +1,9 @@
+//go:build !linux
+
+package db
+
+import "fmt"
+
+func findDiskUsage(path string) (*DiskUsage, error) {
+	return nil, fmt.Errorf("disk usage is only supported on linux")
+}
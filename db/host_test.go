@@ -0,0 +1,170 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcStat(t *testing.T) {
+	content := `cpu  10132153 290696 3084719 46828483 16683 0 25195 0 0 0
+cpu0 1393280 32966 572056 13343292 6130 0 17875 0 0 0
+intr 199292 0 0 0`
+
+	cpu, err := parseProcStat(content)
+	assert.Nil(t, err)
+	assert.Equal(t, &CPUStats{
+		User:    10132153,
+		Nice:    290696,
+		System:  3084719,
+		Idle:    46828483,
+		IOWait:  16683,
+		IRQ:     0,
+		SoftIRQ: 25195,
+		Steal:   0,
+	}, cpu)
+
+	_, err = parseProcStat("intr 199292 0 0 0")
+	assert.NotNil(t, err)
+}
+
+func TestParseLoadAverage(t *testing.T) {
+	load, err := parseLoadAverage("0.20 0.18 0.12 1/80 11206\n")
+	assert.Nil(t, err)
+	assert.Equal(t, &LoadAverage{OneMinute: 0.2, FiveMinute: 0.18, FifteenMinute: 0.12}, load)
+
+	_, err = parseLoadAverage("")
+	assert.NotNil(t, err)
+}
+
+func TestParseMemInfo(t *testing.T) {
+	memInfo := parseMemInfo(`MemTotal:       16333644 kB
+MemFree:          242328 kB
+MemAvailable:    8123456 kB
+Cached:          6000000 kB
+SwapTotal:       2097148 kB
+SwapFree:        2097148 kB
+HugePages_Total:       0`)
+
+	assert.Equal(t, float64(16333644*1024), memInfo["MemTotal"])
+	assert.Equal(t, float64(8123456*1024), memInfo["MemAvailable"])
+	assert.Equal(t, float64(2097148*1024), memInfo["SwapFree"])
+	assert.Equal(t, float64(0), memInfo["HugePages_Total"])
+}
+
+func TestParseDiskStats(t *testing.T) {
+	disks := parseDiskStats(`   7       0 loop0 49 0 2092 17 0 0 0 0 0 36 17 0 0 0 0
+   8       0 sda 20846 5683 1346316 11634 26366 25764 1364464 30468 0 34384 42102
+   8       1 sda1 20700 5683 1340000 11600 26366 25764 1364464 30468 0 34300 42000`)
+
+	assert.Equal(t, 2, len(disks))
+	assert.Equal(t, &DiskStats{Reads: 20846, SectorsRead: 1346316, Writes: 26366, SectorsWritten: 1364464, IOTime: 34384}, disks["sda"])
+	assert.Nil(t, disks["loop0"])
+}
+
+func TestWalDirectory(t *testing.T) {
+	assert.Equal(t, "/var/lib/postgresql/14/main/pg_wal", walDirectory("/var/lib/postgresql/14/main", 140005))
+	assert.Equal(t, "/var/lib/postgresql/10/main/pg_wal", walDirectory("/var/lib/postgresql/10/main", 100000))
+	assert.Equal(t, "/var/lib/postgresql/9.6/main/pg_xlog", walDirectory("/var/lib/postgresql/9.6/main", 90624))
+}
+
+func TestHostMonitorFilterWholeDisks(t *testing.T) {
+	sysPath := t.TempDir()
+	disks := map[string]*DiskStats{"sda": {Reads: 2}, "sda1": {Reads: 1}, "nvme0n1": {Reads: 4}, "nvme0n1p1": {Reads: 3}}
+
+	// sysfs isn't available
+	monitor := &HostMonitor{sysPath: sysPath}
+	assert.Equal(t, 4, len(monitor.filterWholeDisks(disks)))
+
+	os.MkdirAll(filepath.Join(sysPath, "block", "sda"), 0755)
+	os.MkdirAll(filepath.Join(sysPath, "block", "nvme0n1"), 0755)
+
+	filtered := monitor.filterWholeDisks(disks)
+	assert.Equal(t, 2, len(filtered))
+	assert.NotNil(t, filtered["sda"])
+	assert.NotNil(t, filtered["nvme0n1"])
+}
+
+func TestParseNetDev(t *testing.T) {
+	networks := parseNetDev(`Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:   12345      10    0    0    0     0          0         0    12345      10    0    0    0     0       0          0
+  eth0: 1432927    1852    0    0    0     0          0         0   184291    1489    0    0    0     0       0          0`)
+
+	assert.Equal(t, 1, len(networks))
+	assert.Equal(t, &NetworkStats{ReceivedBytes: 1432927, ReceivedPackets: 1852, SentBytes: 184291, SentPackets: 1489}, networks["eth0"])
+}
+
+func TestParseCgroupValue(t *testing.T) {
+	assert.Equal(t, float64(0), parseCgroupValue("max\n"))
+	assert.Equal(t, float64(0), parseCgroupValue("9223372036854771712\n"))
+	assert.Equal(t, float64(536870912), parseCgroupValue("536870912\n"))
+}
+
+func TestParseCgroupCPUMax(t *testing.T) {
+	assert.Equal(t, float64(2), parseCgroupCPUMax("200000 100000\n"))
+	assert.Equal(t, 0.5, parseCgroupCPUMax("50000 100000"))
+	assert.Equal(t, float64(0), parseCgroupCPUMax("max 100000"))
+	// cgroup v1 unlimited quota
+	assert.Equal(t, float64(0), parseCgroupCPUMax("-1 100000"))
+}
+
+func TestHostMonitorCPUMetrics(t *testing.T) {
+	procPath := t.TempDir()
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+	monitor := &HostMonitor{
+		hostStatsState: &HostStatsState{},
+		procPath:       procPath,
+		sysPath:        t.TempDir(),
+	}
+
+	os.WriteFile(filepath.Join(procPath, "stat"), []byte("cpu  100 0 100 700 100 0 0 0 0 0\n"), 0644)
+	// first poll has no delta
+	assert.Empty(t, monitor.FindCPUMetrics(serverID, 1000))
+
+	os.WriteFile(filepath.Join(procPath, "stat"), []byte("cpu  200 0 150 900 150 0 0 0 0 0\n"), 0644)
+	metrics := monitor.FindCPUMetrics(serverID, 1030)
+	assert.Equal(t, 5, len(metrics))
+	assert.Equal(t, "host.cpu.user.percent", metrics[0].Name)
+	assert.Equal(t, 0.25, metrics[0].Value)
+	assert.Equal(t, "host.cpu.system.percent", metrics[1].Name)
+	assert.Equal(t, 0.125, metrics[1].Value)
+	assert.Equal(t, "host.cpu.idle.percent", metrics[4].Name)
+	assert.Equal(t, 0.5, metrics[4].Value)
+}
+
+func TestHostMonitorCgroupLimits(t *testing.T) {
+	sysPath := t.TempDir()
+	cgroupPath := filepath.Join(sysPath, "fs", "cgroup")
+	os.MkdirAll(cgroupPath, 0755)
+	os.WriteFile(filepath.Join(cgroupPath, "memory.max"), []byte("536870912\n"), 0644)
+	os.WriteFile(filepath.Join(cgroupPath, "memory.current"), []byte("268435456\n"), 0644)
+	os.WriteFile(filepath.Join(cgroupPath, "cpu.max"), []byte("200000 100000\n"), 0644)
+
+	monitor := &HostMonitor{sysPath: sysPath}
+	assert.Equal(t, &CgroupLimits{MemoryLimitBytes: 536870912, MemoryUsedBytes: 268435456, CPULimitCores: 2}, monitor.findCgroupLimits())
+}
+
+func TestIsLocalHost(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	assert.True(t, isLocalHost(""))
+	assert.True(t, isLocalHost("localhost"))
+	assert.True(t, isLocalHost("127.0.0.1"))
+	assert.True(t, isLocalHost("::1"))
+	assert.True(t, isLocalHost(hostname))
+	assert.False(t, isLocalHost("ec2-123-456-789.compute-1.amazonaws.com"))
+	assert.False(t, isLocalHost("192.0.2.10"))
+}
+
+func TestFindLocalPostgresClient(t *testing.T) {
+	remote := &PostgresClient{host: "ec2-123-456-789.compute-1.amazonaws.com", serverID: &ServerID{ConfigName: "GREEN"}}
+	local := &PostgresClient{host: "localhost", serverID: &ServerID{ConfigName: "RED"}}
+	socket := &PostgresClient{host: "", serverID: &ServerID{ConfigName: "BLUE"}}
+
+	// only the first local server reports host metrics
+	assert.Equal(t, local, findLocalPostgresClient([]*PostgresClient{remote, local, socket}))
+	assert.Nil(t, findLocalPostgresClient([]*PostgresClient{remote}))
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"agent/util"
+	"strconv"
+	"strings"
+)
+
+const (
+	HotUpdateAdviceLowerFillfactor = "lower_fillfactor"
+	HotUpdateAdviceDropIndex       = "drop_updated_column_index"
+
+	// tables default to packing pages full which leaves no room for heap only tuple updates
+	defaultFillfactor = 100
+
+	// minimum updates in a polling interval before a table is considered update heavy
+	minHotUpdateRows = 1000
+
+	// minimum fraction of writes that are updates before a table is considered update heavy
+	minUpdateWriteRatio = 0.5
+
+	// hot update ratio below which an update heavy table is flagged
+	lowHotUpdateRatio = 0.5
+)
+
+// parses reloptions into a map - ex. {fillfactor=90,autovacuum_enabled=false}
+func parseStorageOptions(reloptions []string) map[string]string {
+	if len(reloptions) == 0 {
+		return nil
+	}
+
+	options := make(map[string]string)
+	for _, option := range reloptions {
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		options[parts[0]] = parts[1]
+	}
+	return options
+}
+
+func tableFillfactor(options map[string]string) int64 {
+	fillfactor, err := strconv.ParseInt(options["fillfactor"], 10, 64)
+	if err != nil {
+		return defaultFillfactor
+	}
+	return fillfactor
+}
+
+func hotUpdateRatio(hotUpdatedRows int64, updatedRows int64) float64 {
+	if updatedRows <= 0 {
+		return 0.0
+	}
+	return util.Round4(float64(hotUpdatedRows) / float64(updatedRows))
+}
+
+// flags update heavy tables with a low hot update ratio
+// with free space on each page already reserved, updates are most likely touching indexed columns
+func hotUpdateAdvice(table *Table) string {
+	if table.UpdatedRows < minHotUpdateRows {
+		return ""
+	}
+
+	writes := table.InsertedRows + table.UpdatedRows + table.DeletedRows
+	if float64(table.UpdatedRows)/float64(writes) < minUpdateWriteRatio {
+		return ""
+	}
+
+	if table.HotUpdateRatio >= lowHotUpdateRatio {
+		return ""
+	}
+
+	if table.Fillfactor >= defaultFillfactor {
+		return HotUpdateAdviceLowerFillfactor
+	}
+	return HotUpdateAdviceDropIndex
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStorageOptions(t *testing.T) {
+	options := parseStorageOptions([]string{"fillfactor=90", "autovacuum_vacuum_scale_factor=0.01", "invalid"})
+
+	assert.Equal(t, map[string]string{"fillfactor": "90", "autovacuum_vacuum_scale_factor": "0.01"}, options)
+	assert.Equal(t, int64(90), tableFillfactor(options))
+
+	assert.Nil(t, parseStorageOptions([]string{}))
+	assert.Equal(t, int64(100), tableFillfactor(nil))
+}
+
+func TestHotUpdateRatio(t *testing.T) {
+	assert.Equal(t, 0.25, hotUpdateRatio(250, 1000))
+	assert.Equal(t, 0.0, hotUpdateRatio(0, 0))
+}
+
+func TestHotUpdateAdvice(t *testing.T) {
+	table := &Table{
+		InsertedRows:   100,
+		UpdatedRows:    5000,
+		HotUpdatedRows: 500,
+		HotUpdateRatio: 0.1,
+		Fillfactor:     100,
+	}
+	assert.Equal(t, HotUpdateAdviceLowerFillfactor, hotUpdateAdvice(table))
+
+	// free space is already reserved so updates are likely changing indexed columns
+	table.Fillfactor = 80
+	assert.Equal(t, HotUpdateAdviceDropIndex, hotUpdateAdvice(table))
+
+	table.HotUpdateRatio = 0.9
+	assert.Equal(t, "", hotUpdateAdvice(table))
+
+	// insert heavy
+	table.HotUpdateRatio = 0.1
+	table.InsertedRows = 50000
+	assert.Equal(t, "", hotUpdateAdvice(table))
+
+	// too few updates
+	assert.Equal(t, "", hotUpdateAdvice(&Table{UpdatedRows: 10, Fillfactor: 100}))
+}
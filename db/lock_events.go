@@ -0,0 +1,96 @@
+package db
+
+import (
+	"agent/logger"
+	"sort"
+)
+
+const (
+	LockEventKindWaiting  = "waiting"
+	LockEventKindAcquired = "acquired"
+	LockEventKindDeadlock = "deadlock"
+
+	// max number of lock events reported per server
+	MaxLockEvents = 50
+)
+
+// a lock wait logged by postgres when log_lock_waits is enabled or a deadlock error
+// ex. LOG:  process 123 still waiting for ShareLock on transaction 5678 after 1000.072 ms
+// ex. ERROR:  deadlock detected
+type LockEvent struct {
+	ServerConfigName string
+	ServerID         *ServerID
+	Kind             string
+	LockMode         string // ex. ShareLock
+	LockObject       string // ex. transaction 5678 or relation 16384 of database 16385
+	WaitMs           float64
+
+	// processes holding and waiting for the lock from a lock wait's detail
+	HolderPids    []int64
+	WaitQueuePids []int64
+
+	// the waiting process for a lock wait or each process in the cycle for a deadlock
+	Participants []*LockParticipant
+
+	Fields     LogFields
+	MeasuredAt int64
+}
+
+type LockParticipant struct {
+	Pid          int64
+	LockMode     string
+	LockObject   string
+	BlockedByPid int64
+	Raw          string // raw statement logged for the process
+	Obfuscated   string
+	Fingerprint  string
+}
+
+// runs forever
+func (o *Observer) MonitorLockEvents() {
+	for {
+		select {
+		case lockEvent := <-o.rawLockEventChannel:
+			for _, participant := range lockEvent.Participants {
+				if participant.Raw == "" {
+					continue
+				}
+
+				parsedComment := parseComment(participant.Raw)
+				participant.Raw = parsedComment.Query
+
+				participant.Obfuscated, participant.Fingerprint = o.obfuscateAndFingerprint(participant.Raw)
+			}
+
+			lockEvent.ServerID = o.serverIDForConfigName(lockEvent.ServerConfigName)
+
+			select {
+			case o.lockEventChannel <- lockEvent:
+				// sent
+			default:
+				logger.Warn("Dropping lock event: channel buffer full")
+			}
+		}
+	}
+}
+
+// returns the latest lock events with deadlocks first since they are rarer and abort a transaction
+func LatestLockEvents(events []LockEvent, limit int) []LockEvent {
+	sorted := make([]LockEvent, len(events))
+	copy(sorted, events)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		deadlockI := sorted[i].Kind == LockEventKindDeadlock
+		deadlockJ := sorted[j].Kind == LockEventKindDeadlock
+		if deadlockI != deadlockJ {
+			return deadlockI
+		}
+		return sorted[i].MeasuredAt > sorted[j].MeasuredAt
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
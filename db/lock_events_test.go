@@ -0,0 +1,24 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestLockEvents(t *testing.T) {
+	events := []LockEvent{
+		{Kind: LockEventKindWaiting, MeasuredAt: 100},
+		{Kind: LockEventKindDeadlock, MeasuredAt: 50},
+		{Kind: LockEventKindAcquired, MeasuredAt: 200},
+	}
+
+	latest := LatestLockEvents(events, 2)
+	assert.Equal(t, []LockEvent{
+		{Kind: LockEventKindDeadlock, MeasuredAt: 50},
+		{Kind: LockEventKindAcquired, MeasuredAt: 200},
+	}, latest)
+
+	// the reported events are not reordered
+	assert.Equal(t, LockEventKindWaiting, events[0].Kind)
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"agent/logger"
+	"sort"
+)
+
+const (
+	// max number of error groups reported per server
+	MaxLogErrorGroups = 50
+
+	// max number of distinct statements kept per error group
+	MaxLogErrorSamples = 3
+)
+
+// an ERROR, FATAL or PANIC entry logged by postgres
+// ex. ERROR:  canceling statement due to statement timeout
+type LogError struct {
+	ServerConfigName string
+	ServerID         *ServerID
+	Severity         string
+	SqlState         string // ex. 57014
+	Message          string // message template with identifiers and literals stripped
+	Raw              string // raw STATEMENT logged with the error
+	Obfuscated       string
+	Fingerprint      string
+	Fields           LogFields
+	MeasuredAt       int64
+}
+
+// errors with the same SQLSTATE and message template aggregated over a report interval
+type LogErrorGroup struct {
+	Severity     string
+	SqlState     string
+	Message      string
+	Count        int64
+	Samples      []*LogErrorSample
+	FirstErrorAt int64
+	LastErrorAt  int64
+}
+
+type LogErrorSample struct {
+	Fingerprint string
+	Query       string
+}
+
+// runs forever
+func (o *Observer) MonitorLogErrors() {
+	for {
+		select {
+		case logError := <-o.rawLogErrorChannel:
+			if logError.Raw != "" {
+				parsedComment := parseComment(logError.Raw)
+				logError.Raw = parsedComment.Query
+
+				logError.Obfuscated, logError.Fingerprint = o.obfuscateAndFingerprint(logError.Raw)
+			}
+
+			logError.ServerID = o.serverIDForConfigName(logError.ServerConfigName)
+
+			select {
+			case o.logErrorChannel <- logError:
+				// sent
+			default:
+				logger.Warn("Dropping log error: channel buffer full")
+			}
+		}
+	}
+}
+
+// aggregates errors by SQLSTATE and message template and returns the most frequent groups
+func AggregateLogErrors(logErrors []LogError, limit int) []*LogErrorGroup {
+	type groupKey struct {
+		severity string
+		sqlState string
+		message  string
+	}
+
+	groups := make(map[groupKey]*LogErrorGroup)
+
+	for _, logError := range logErrors {
+		key := groupKey{severity: logError.Severity, sqlState: logError.SqlState, message: logError.Message}
+		group, ok := groups[key]
+		if !ok {
+			group = &LogErrorGroup{
+				Severity:     logError.Severity,
+				SqlState:     logError.SqlState,
+				Message:      logError.Message,
+				FirstErrorAt: logError.MeasuredAt,
+			}
+			groups[key] = group
+		}
+
+		group.Count++
+		if logError.MeasuredAt < group.FirstErrorAt {
+			group.FirstErrorAt = logError.MeasuredAt
+		}
+		if logError.MeasuredAt > group.LastErrorAt {
+			group.LastErrorAt = logError.MeasuredAt
+		}
+
+		addLogErrorSample(group, logError)
+	}
+
+	var sorted []*LogErrorGroup
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count == sorted[j].Count {
+			return sorted[i].SqlState+sorted[i].Message < sorted[j].SqlState+sorted[j].Message
+		}
+		return sorted[i].Count > sorted[j].Count
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
+
+// keeps the first few distinct statements for a group
+func addLogErrorSample(group *LogErrorGroup, logError LogError) {
+	if logError.Fingerprint == "" || len(group.Samples) >= MaxLogErrorSamples {
+		return
+	}
+
+	for _, sample := range group.Samples {
+		if sample.Fingerprint == logError.Fingerprint {
+			return
+		}
+	}
+
+	group.Samples = append(group.Samples, &LogErrorSample{
+		Fingerprint: logError.Fingerprint,
+		Query:       logError.Obfuscated,
+	})
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateLogErrors(t *testing.T) {
+	logErrors := []LogError{
+		{Severity: "ERROR", SqlState: "57014", Message: "canceling statement due to statement timeout", Fingerprint: "abc", Obfuscated: "select pg_sleep(?)", MeasuredAt: 200},
+		{Severity: "ERROR", SqlState: "57014", Message: "canceling statement due to statement timeout", Fingerprint: "abc", Obfuscated: "select pg_sleep(?)", MeasuredAt: 100},
+		{Severity: "ERROR", SqlState: "57014", Message: "canceling statement due to statement timeout", Fingerprint: "def", Obfuscated: "select * from events", MeasuredAt: 300},
+		{Severity: "FATAL", SqlState: "53300", Message: "sorry, too many clients already", MeasuredAt: 150},
+	}
+
+	groups := AggregateLogErrors(logErrors, 10)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, &LogErrorGroup{
+		Severity: "ERROR",
+		SqlState: "57014",
+		Message:  "canceling statement due to statement timeout",
+		Count:    3,
+		Samples: []*LogErrorSample{
+			{Fingerprint: "abc", Query: "select pg_sleep(?)"},
+			{Fingerprint: "def", Query: "select * from events"},
+		},
+		FirstErrorAt: 100,
+		LastErrorAt:  300,
+	}, groups[0])
+	assert.Equal(t, "53300", groups[1].SqlState)
+	assert.Empty(t, groups[1].Samples)
+
+	assert.Equal(t, 1, len(AggregateLogErrors(logErrors, 1)))
+}
+
+func TestAggregateLogErrorsMaxSamples(t *testing.T) {
+	var logErrors []LogError
+	for _, fingerprint := range []string{"a", "b", "c", "d"} {
+		logErrors = append(logErrors, LogError{Severity: "ERROR", SqlState: "23505", Message: "duplicate key", Fingerprint: fingerprint})
+	}
+
+	groups := AggregateLogErrors(logErrors, 10)
+	assert.Equal(t, int64(4), groups[0].Count)
+	assert.Equal(t, MaxLogErrorSamples, len(groups[0].Samples))
+}
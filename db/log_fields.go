@@ -0,0 +1,45 @@
+package db
+
+import "sync"
+
+// structured fields parsed from a log line's log_line_prefix or from csvlog / jsonlog columns
+type LogFields struct {
+	Pid         int64
+	User        string
+	Database    string
+	Application string
+	Client      string // remote host without the port - not reported since it can be an IP
+	SqlState    string
+	QueryId     int64
+	SessionID   string
+	BackendType string
+}
+
+// log_line_prefix settings by server config name that are shared with the log parsers
+type LogLinePrefixRegistry struct {
+	prefixes map[string]string
+	mu       sync.RWMutex
+}
+
+// registers the prefix for the server config name and any alias config names
+func (r *LogLinePrefixRegistry) Register(serverID *ServerID, prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.prefixes == nil {
+		r.prefixes = make(map[string]string)
+	}
+
+	r.prefixes[serverID.ConfigName] = prefix
+	for _, alias := range serverID.Aliases() {
+		r.prefixes[configNameFromVarName(alias)] = prefix
+	}
+}
+
+func (r *LogLinePrefixRegistry) Get(configName string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefix, ok := r.prefixes[configName]
+	return prefix, ok
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogLinePrefixRegistry(t *testing.T) {
+	registry := &LogLinePrefixRegistry{}
+
+	_, ok := registry.Get("GREEN")
+	assert.False(t, ok)
+
+	registry.Register(&ServerID{ConfigName: "GREEN", ConfigVarName: "HEROKU_POSTGRESQL_GREEN_URL", ConfigVarAliases: "DATABASE_URL"}, "%m [%p] ")
+
+	prefix, ok := registry.Get("GREEN")
+	assert.True(t, ok)
+	assert.Equal(t, "%m [%p] ", prefix)
+
+	prefix, ok = registry.Get("DATABASE")
+	assert.True(t, ok)
+	assert.Equal(t, "%m [%p] ", prefix)
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	LoggedPlanFormatText = "text"
+	LoggedPlanFormatJSON = "json"
+)
+
+// plans of the agent's own EXPLAIN queries are not useful
+var explainStatementRegex = regexp.MustCompile(`(?i)^\s*EXPLAIN\b`)
+
+// a query plan logged by auto_explain - ex. LOG:  duration: 1000.123 ms  plan:
+// the plan includes ANALYZE and BUFFERS output when auto_explain.log_analyze and log_buffers are enabled
+type LoggedPlan struct {
+	ServerConfigName string
+	Format           string
+	DurationMs       float64
+	Query            string // raw Query Text logged with the plan
+	Plan             string // raw plan without the query text
+	Obfuscated       string
+	Fingerprint      string
+	Fields           LogFields
+	MeasuredAt       int64
+
+	expiresAt time.Time
+}
+
+// runs forever
+func (o *Observer) MonitorLoggedPlans() {
+	for {
+		select {
+		case plan := <-o.rawLoggedPlanChannel:
+			o.handleLoggedPlan(plan)
+		}
+	}
+}
+
+func (o *Observer) handleLoggedPlan(plan *LoggedPlan) {
+	parsedComment := parseComment(plan.Query)
+	if explainStatementRegex.MatchString(parsedComment.Query) {
+		return
+	}
+	if !o.config.MonitorAgentQueries && isAgentQueryComment(parsedComment.Comment) {
+		return
+	}
+
+	// fingerprint the same way as slow queries so plans match slow queries and query stats
+	plan.Query = parsedComment.Query
+	_, plan.Fingerprint = o.obfuscateAndFingerprint(plan.Query)
+
+	if plan.Format == LoggedPlanFormatJSON {
+		plan.Obfuscated = o.obfuscator.ObfuscateJSONExplain(plan.Plan)
+	} else {
+		plan.Obfuscated = o.obfuscator.ObfuscateExplain(plan.Plan)
+	}
+	if plan.Obfuscated == "" {
+		return
+	}
+
+	// plans are looked up by the server's config name so plans logged for an alias still match
+	if serverID := o.serverIDForConfigName(plan.ServerConfigName); serverID != nil {
+		plan.ServerConfigName = serverID.ConfigName
+	}
+
+	o.explainer.AddLoggedPlan(plan)
+}
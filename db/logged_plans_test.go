@@ -0,0 +1,110 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainerLoggedPlans(t *testing.T) {
+	explainer := &Explainer{}
+	explainer.AddLoggedPlan(&LoggedPlan{ServerConfigName: "GREEN", Fingerprint: "abc", Obfuscated: "Seq Scan on users"})
+
+	// the agent doesn't run its own explain for the query
+	assert.True(t, explainer.recentlyExplained("abc"))
+	assert.Equal(t, "", explainer.Explain(nil, &SlowQuery{Fingerprint: "abc"}))
+
+	assert.Equal(t, "", explainer.TakeLoggedPlan("BLUE", "abc"))
+	assert.Equal(t, "Seq Scan on users", explainer.TakeLoggedPlan("GREEN", "abc"))
+
+	// each plan is reported once
+	assert.Equal(t, "", explainer.TakeLoggedPlan("GREEN", "abc"))
+}
+
+func TestExplainerLoggedPlansExpire(t *testing.T) {
+	explainer := &Explainer{}
+	explainer.AddLoggedPlan(&LoggedPlan{ServerConfigName: "GREEN", Fingerprint: "abc", Obfuscated: "Seq Scan on users"})
+	explainer.loggedPlans[loggedPlanKey{configName: "GREEN", fingerprint: "abc"}].expiresAt = time.Now().UTC().Add(-1 * time.Minute)
+
+	// expired plans are dropped when the next plan is logged
+	explainer.AddLoggedPlan(&LoggedPlan{ServerConfigName: "GREEN", Fingerprint: "def", Obfuscated: "Index Scan on users"})
+	assert.Equal(t, 1, len(explainer.loggedPlans))
+	assert.Equal(t, "", explainer.TakeLoggedPlan("GREEN", "abc"))
+}
+
+func TestHandleLoggedPlanAlias(t *testing.T) {
+	observer := &Observer{
+		obfuscator: NewObfuscator(),
+		explainer:  &Explainer{},
+		postgresClients: []*PostgresClient{
+			{serverID: &ServerID{ConfigName: "GREEN", ConfigVarName: "HEROKU_POSTGRESQL_GREEN_URL", ConfigVarAliases: "DATABASE_URL"}},
+		},
+	}
+
+	plan := &LoggedPlan{
+		ServerConfigName: "DATABASE",
+		Format:           LoggedPlanFormatText,
+		Query:            "SELECT * FROM users WHERE id = 1",
+		Plan:             "Seq Scan on users  (cost=0.00..1.01 rows=1 width=4)",
+	}
+	observer.handleLoggedPlan(plan)
+
+	// query stats look up plans by the client's config name
+	_, fingerprint := observer.obfuscateAndFingerprint("SELECT * FROM users WHERE id = 2")
+	assert.Equal(t, "Seq Scan on users  (cost=0.00..1.01 rows=1 width=4)", observer.explainer.TakeLoggedPlan("GREEN", fingerprint))
+}
+
+func TestSlowQueryBeforeLoggedPlan(t *testing.T) {
+	loggedPlanWait = 50 * time.Millisecond
+	defer func() { loggedPlanWait = 5 * time.Second }()
+
+	observer := &Observer{
+		obfuscator:        NewObfuscator(),
+		explainer:         &Explainer{},
+		queryStatsChannel: make(chan []*QueryStats, 1),
+		postgresClients: []*PostgresClient{
+			{serverID: &ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL"}},
+		},
+	}
+
+	// an earlier plan shows auto_explain is enabled for the server
+	observer.handleLoggedPlan(&LoggedPlan{ServerConfigName: "GREEN", Query: "SELECT 1", Plan: "Result  (cost=0.00..0.01 rows=1 width=4)"})
+	assert.True(t, observer.explainer.AutoExplainActive("GREEN"))
+	assert.False(t, observer.explainer.AutoExplainActive("BLUE"))
+
+	// the slow query is logged first and waits for its plan instead of running EXPLAIN
+	observer.handleSlowQuery(&SlowQuery{ServerConfigName: "GREEN", Raw: "SELECT * FROM users WHERE id = 1", DurationMs: 1000})
+	assert.Equal(t, 0, len(observer.queryStatsChannel))
+
+	observer.handleLoggedPlan(&LoggedPlan{ServerConfigName: "GREEN", Query: "SELECT * FROM users WHERE id = 1", Plan: "Seq Scan on users  (cost=0.00..1.01 rows=1 width=4)"})
+
+	select {
+	case stats := <-observer.queryStatsChannel:
+		assert.Equal(t, "SELECT * FROM users WHERE id = ?", stats[0].Query)
+		assert.Equal(t, "Seq Scan on users  (cost=0.00..1.01 rows=1 width=4)", stats[0].Explain)
+	case <-time.After(5 * time.Second):
+		t.Error("Timed out waiting for slow query stats")
+	}
+}
+
+func TestHandleLoggedPlanVerbose(t *testing.T) {
+	observer := &Observer{
+		obfuscator: NewObfuscator(),
+		explainer:  &Explainer{},
+		postgresClients: []*PostgresClient{
+			{serverID: &ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL"}},
+		},
+	}
+
+	// auto_explain.log_verbose logs the output of each node with the query's values
+	observer.handleLoggedPlan(&LoggedPlan{
+		ServerConfigName: "GREEN",
+		Format:           LoggedPlanFormatText,
+		Query:            "SELECT id, 'secret' FROM users WHERE id = 1",
+		Plan:             "Seq Scan on public.users  (cost=0.00..1.01 rows=1 width=36)\n  Output: id, 'secret'::text\n  Filter: (users.id = 1)",
+	})
+
+	_, fingerprint := observer.obfuscateAndFingerprint("SELECT id, 'secret' FROM users WHERE id = 1")
+	assert.Equal(t, "Seq Scan on public.users  (cost=0.00..1.01 rows=1 width=36)\n  Output: id, ?::text\n  Filter: (users.id = ?)", observer.explainer.TakeLoggedPlan("GREEN", fingerprint))
+}
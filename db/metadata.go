@@ -27,11 +27,14 @@ func (m *MetadataMonitor) Run(postgresClient *PostgresClient) {
 
 	server := &PostgresServer{
 		ServerID: &ServerID{
-			ConfigName:    postgresClient.serverID.ConfigName,
-			ConfigVarName: postgresClient.serverID.ConfigVarName,
+			ConfigName:       postgresClient.serverID.ConfigName,
+			ConfigVarName:    postgresClient.serverID.ConfigVarName,
+			Database:         postgresClient.serverID.Database,
+			ConfigVarAliases: postgresClient.serverID.ConfigVarAliases,
 		},
 		Platform:       postgresClient.platform,
 		MaxConnections: postgresClient.maxConnections,
+		Extensions:     postgresClient.Extensions(),
 		Version:        postgresClient.version,
 		MonitoredAt:    time.Now().UTC().Unix(),
 	}
@@ -33,6 +33,7 @@ func (m *MetricMonitor) Run(postgresClient *PostgresClient) {
 	metrics := m.FindUsedConnectionsMetric(postgresClient)
 	metrics = append(metrics, m.FindDatabaseStatMetrics(postgresClient)...)
 	metrics = append(metrics, m.FindDatabaseCacheHitMetrics(postgresClient)...)
+	metrics = append(metrics, m.FindConnectionBreakdownMetrics(postgresClient)...)
 
 	select {
 	case m.metricsChannel <- metrics:
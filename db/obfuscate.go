@@ -1,6 +1,8 @@
 package db
 
 import (
+	"bytes"
+	"encoding/json"
 	"regexp"
 	"strings"
 )
@@ -26,10 +28,23 @@ var betweenParam2Regex = `(?P<param2>` + quotedParamRegex + `|` + numberParamReg
 var queryBetweenParamRegex = regexp.MustCompile(caseInsensitiveRegex + betweenOperatorRegex + betweenParam1Regex + betweenAndRegex + betweenParam2Regex)
 
 // explain
-var explainParamLineStartRegex = regexp.MustCompile(caseInsensitiveRegex + `(Cond:|Filter:)`)
 var explainOperatorRegex = `(?P<operator>(=|>|<|~~|ANY \()\s*)`
 var explainOperatorParamRegex = regexp.MustCompile(caseInsensitiveRegex + explainOperatorRegex + paramRegex)
 
+// plan lines and json keys with expressions that can contain query params
+// ex. Index Cond, Filter, Output (VERBOSE), Sort Key, Group Key, Cache Key or Function Call
+var explainLineKeyRegex = regexp.MustCompile(`^\s*(?P<key>[A-Za-z][A-Za-z -]*):\s`)
+var explainExpressionKeyRegex = regexp.MustCompile(`(Cond|Filter|Key|Output|Call|Order By)$`)
+
+// counts of removed rows end with Filter but don't contain expressions
+var explainRowsRemovedKeyRegex = regexp.MustCompile(`^Rows Removed by`)
+
+var explainQuotedLiteralRegex = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// numbers that aren't part of an identifier, a bind param or a type modifier
+// ex. 5 in (id + 5) but not users2, $1 or ::character varying(255)
+var explainNumberLiteralRegex = regexp.MustCompile(`(?P<typmod>::[\w ]+\(\d+(?:,\s*\d+)?\))|(?P<prefix>^|[^\w$.])-?\d+(?:\.\d+)?\b`)
+
 type Obfuscator struct {
 }
 
@@ -49,6 +64,7 @@ func (o *Obfuscator) ObfuscateQuery(query string) string {
 }
 
 // obfuscate explain plans by replacing query params with ?
+// plans logged by auto_explain can be VERBOSE so every line is obfuscated and not only conditions and filters
 func (o *Obfuscator) ObfuscateExplain(explain string) string {
 	if len(explain) == 0 {
 		return explain
@@ -58,12 +74,11 @@ func (o *Obfuscator) ObfuscateExplain(explain string) string {
 	lines := strings.Split(explain, "\n")
 	numLines := len(lines)
 	for index, line := range lines {
-		// if line starts with Cond: or Filter:
-		if explainParamLineStartRegex.MatchString(line) {
-			obfuscatedLine := explainOperatorParamRegex.ReplaceAllString(line, "$operator?")
-			obfuscated += obfuscatedLine
+		if isExplainExpressionLine(line) {
+			obfuscated += obfuscateExplainExpression(line)
 		} else {
-			obfuscated += line
+			// node and stats lines only have numbers from the plan but could still have a quoted param
+			obfuscated += explainQuotedLiteralRegex.ReplaceAllString(line, "?")
 		}
 		if index != numLines-1 {
 			obfuscated += "\n"
@@ -71,3 +86,71 @@ func (o *Obfuscator) ObfuscateExplain(explain string) string {
 	}
 	return obfuscated
 }
+
+// ex. Index Cond: (id = 1) or Output: id, 'abc'::text
+func isExplainExpressionLine(line string) bool {
+	match := explainLineKeyRegex.FindStringSubmatch(line)
+	if match == nil {
+		return false
+	}
+
+	key := match[1]
+	return explainExpressionKeyRegex.MatchString(key) && !explainRowsRemovedKeyRegex.MatchString(key)
+}
+
+// replaces the params after operators, quoted literals and number literals with ?
+func obfuscateExplainExpression(expression string) string {
+	expression = explainOperatorParamRegex.ReplaceAllString(expression, "$operator?")
+	expression = explainQuotedLiteralRegex.ReplaceAllString(expression, "?")
+
+	return explainNumberLiteralRegex.ReplaceAllStringFunc(expression, func(match string) string {
+		submatches := explainNumberLiteralRegex.FindStringSubmatch(match)
+		if submatches[1] != "" {
+			return match
+		}
+		return submatches[2] + "?"
+	})
+}
+
+// obfuscate json explain plans by replacing query params with ? in every string value
+// plans that can't be parsed are dropped since they could contain query params
+func (o *Obfuscator) ObfuscateJSONExplain(explain string) string {
+	var plan interface{}
+	err := json.Unmarshal([]byte(explain), &plan)
+	if err != nil {
+		return ""
+	}
+
+	plan = obfuscateJSONExplainNode(plan, false)
+
+	var obfuscated bytes.Buffer
+	encoder := json.NewEncoder(&obfuscated)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(plan)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(obfuscated.String())
+}
+
+// expression values can be strings or lists of strings - ex. "Filter" or "Output" and "Sort Key"
+func obfuscateJSONExplainNode(node interface{}, expression bool) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = obfuscateJSONExplainNode(child, explainExpressionKeyRegex.MatchString(key))
+		}
+	case []interface{}:
+		for index, child := range value {
+			value[index] = obfuscateJSONExplainNode(child, expression)
+		}
+	case string:
+		if expression {
+			return obfuscateExplainExpression(value)
+		}
+		return explainQuotedLiteralRegex.ReplaceAllString(value, "?")
+	}
+	return node
+}
@@ -530,3 +530,88 @@ func TestObfuscateExplain(t *testing.T) {
 		})
 	}
 }
+
+func TestObfuscateJSONExplain(t *testing.T) {
+	obfuscator := NewObfuscator()
+
+	explain := `{"Plan":{"Node Type":"Index Scan","Index Cond":"(id = 1)","Plans":[{"Node Type":"Seq Scan","Filter":"((name)::text = 'bob'::text)","Relation Name":"users"}]}}`
+	assert.Equal(t, `{
+  "Plan": {
+    "Index Cond": "(id = ?)",
+    "Node Type": "Index Scan",
+    "Plans": [
+      {
+        "Filter": "((name)::text = ?::text)",
+        "Node Type": "Seq Scan",
+        "Relation Name": "users"
+      }
+    ]
+  }
+}`, obfuscator.ObfuscateJSONExplain(explain))
+
+	// plans that can't be parsed are dropped
+	assert.Equal(t, "", obfuscator.ObfuscateJSONExplain(`{"Plan": {"Filter": "(id = 1)"`))
+}
+
+func TestObfuscateVerboseExplain(t *testing.T) {
+	obfuscator := NewObfuscator()
+
+	// auto_explain.log_verbose adds the output of each node
+	explain := `Sort  (cost=1.02..1.03 rows=1 width=68) (actual time=0.020..0.021 rows=1 loops=1)
+  Output: id, (('secret'::text || name)), ((id * 42)), $1
+  Sort Key: ((users.id + 7)), users.name
+  Sort Method: quicksort  Memory: 25kB
+  ->  Memoize  (cost=0.00..1.01 rows=1 width=68)
+        Output: users.id, ('secret'::text || (users.name)::character varying(255))
+        Cache Key: (users.id + 1000)
+        ->  Function Scan on public.generate_series s  (cost=0.00..1.01 rows=1 width=4)
+              Output: s.s
+              Function Call: generate_series(1, 500)
+              Filter: (s.s <> 13)
+              Rows Removed by Filter: 5
+Settings: work_mem = '64MB'`
+
+	assert.Equal(t, `Sort  (cost=1.02..1.03 rows=1 width=68) (actual time=0.020..0.021 rows=1 loops=1)
+  Output: id, ((?::text || name)), ((id * ?)), $1
+  Sort Key: ((users.id + ?)), users.name
+  Sort Method: quicksort  Memory: 25kB
+  ->  Memoize  (cost=0.00..1.01 rows=1 width=68)
+        Output: users.id, (?::text || (users.name)::character varying(255))
+        Cache Key: (users.id + ?)
+        ->  Function Scan on public.generate_series s  (cost=0.00..1.01 rows=1 width=4)
+              Output: s.s
+              Function Call: generate_series(?, ?)
+              Filter: (s.s <> ?)
+              Rows Removed by Filter: 5
+Settings: work_mem = ?`, obfuscator.ObfuscateExplain(explain))
+}
+
+func TestObfuscateVerboseJSONExplain(t *testing.T) {
+	obfuscator := NewObfuscator()
+
+	explain := `{"Plan":{"Node Type":"Sort","Output":["id","('secret'::text || name)","(id * 42)"],"Sort Key":["((users.id + 7))"],"Rows Removed by Filter":5,"Plans":[{"Node Type":"Function Scan","Function Call":"generate_series(1, 500)","Alias":"s2"}]},"Settings":{"work_mem":"'64MB'"}}`
+	assert.Equal(t, `{
+  "Plan": {
+    "Node Type": "Sort",
+    "Output": [
+      "id",
+      "(?::text || name)",
+      "(id * ?)"
+    ],
+    "Plans": [
+      {
+        "Alias": "s2",
+        "Function Call": "generate_series(?, ?)",
+        "Node Type": "Function Scan"
+      }
+    ],
+    "Rows Removed by Filter": 5,
+    "Sort Key": [
+      "((users.id + ?))"
+    ]
+  },
+  "Settings": {
+    "work_mem": "?"
+  }
+}`, obfuscator.ObfuscateJSONExplain(explain))
+}
@@ -5,27 +5,48 @@ import (
 	"agent/errors"
 	"agent/logger"
 	"agent/schedule"
+	"strings"
 )
 
 type Observer struct {
-	config              config.Config
-	serverChannel       chan *PostgresServer
-	schemaChannel       chan *Database
-	settingsChannel     chan []*Setting
-	metricsChannel      chan []*Metric
-	queryStatsChannel   chan []*QueryStats
-	replicationChannel  chan *Replication
-	rawSlowQueryChannel chan *SlowQuery
+	config                config.Config
+	serverChannel         chan *PostgresServer
+	schemaChannel         chan *Database
+	settingsChannel       chan *ServerSettings
+	metricsChannel        chan []*Metric
+	queryStatsChannel     chan []*QueryStats
+	replicationChannel    chan *Replication
+	securityChannel       chan *Security
+	serverEventChannel    chan *ServerEvent
+	rawSlowQueryChannel   chan *SlowQuery
+	rawTempFileChannel    chan *TempFile
+	tempFileChannel       chan *TempFile
+	rawVacuumEventChannel chan *VacuumEvent
+	vacuumEventChannel    chan *VacuumEvent
+	rawCheckpointChannel  chan *CheckpointEvent
+	rawLockEventChannel   chan *LockEvent
+	lockEventChannel      chan *LockEvent
+	rawLogErrorChannel    chan *LogError
+	logErrorChannel       chan *LogError
+	rawLoggedPlanChannel  chan *LoggedPlan
 
 	// stateful stats for the life of the observer
 	databaseSchemaState *DatabaseSchemaState
 	databaseStatsState  *DatabaseStatsState
 	pgBouncerStatsState *PgBouncerStatsState
 	queryStatsState     *QueryStatsState
+	settingsState       *SettingsState
+	hostStatsState      *HostStatsState
+	storageState        *StorageState
+	replicationState    *ReplicationState
+	serverState         *ServerState
 
 	explainer  *Explainer
 	obfuscator *Obfuscator
 
+	// log_line_prefix settings shared with the log parsers
+	logLinePrefixes *LogLinePrefixRegistry
+
 	postgresClients []*PostgresClient
 }
 
@@ -38,6 +59,17 @@ type ServerID struct {
 
 	// db name
 	Database string
+
+	// comma separated config var names that point at the same database - ex. DATABASE_URL
+	// stored as a string to keep ServerID comparable since it's used as a map key
+	ConfigVarAliases string
+}
+
+func (s *ServerID) Aliases() []string {
+	if s.ConfigVarAliases == "" {
+		return []string{}
+	}
+	return strings.Split(s.ConfigVarAliases, ",")
 }
 
 type PostgresServer struct {
@@ -46,37 +78,82 @@ type PostgresServer struct {
 	Platform       string
 	MaxConnections int64
 	PgBouncer      *PgBouncer
+	Extensions     []*Extension
 	Version        string
 	MonitoredAt    int64
 }
 
+// channels the observer reads raw log events from and sends monitored data to
+type ObserverChannels struct {
+	Servers      chan *PostgresServer
+	Schemas      chan *Database
+	Replications chan *Replication
+	Metrics      chan []*Metric
+	QueryStats   chan []*QueryStats
+	Settings     chan *ServerSettings
+	Security     chan *Security
+	ServerEvents chan *ServerEvent
+
+	// raw events parsed from the logs are processed before they're sent on
+	RawSlowQueries  chan *SlowQuery
+	RawTempFiles    chan *TempFile
+	TempFiles       chan *TempFile
+	RawVacuumEvents chan *VacuumEvent
+	VacuumEvents    chan *VacuumEvent
+	RawCheckpoints  chan *CheckpointEvent
+	RawLockEvents   chan *LockEvent
+	LockEvents      chan *LockEvent
+	RawLogErrors    chan *LogError
+	LogErrors       chan *LogError
+	RawLoggedPlans  chan *LoggedPlan
+}
+
 // Creates a new DB observer using the present config env vars
-func NewObserver(config config.Config, serverChannel chan *PostgresServer, schemaChannel chan *Database, replicationChannel chan *Replication, metricsChannel chan []*Metric, queryStatsChannel chan []*QueryStats, settingsChannel chan []*Setting, rawSlowQueryChannel chan *SlowQuery) *Observer {
-	postgresClients := BuildPostgresClients(config)
+func NewObserver(config config.Config, channels ObserverChannels, logLinePrefixes *LogLinePrefixRegistry) *Observer {
+	postgresClients := DedupePostgresClients(BuildPostgresClients(config))
 
 	if len(postgresClients) == 0 {
 		logger.Error("No Postgres servers were found")
 	} else {
 		for _, client := range postgresClients {
-			logger.Info("Monitoring Postgres server", "configName", client.serverID.ConfigName)
+			logger.Info("Monitoring Postgres server", "configName", client.serverID.ConfigName, "aliases", client.serverID.ConfigVarAliases)
 		}
 	}
 
 	return &Observer{
-		config:              config,
-		serverChannel:       serverChannel,
-		schemaChannel:       schemaChannel,
-		settingsChannel:     settingsChannel,
-		replicationChannel:  replicationChannel,
-		queryStatsChannel:   queryStatsChannel,
-		metricsChannel:      metricsChannel,
-		rawSlowQueryChannel: rawSlowQueryChannel,
+		config:                config,
+		serverChannel:         channels.Servers,
+		schemaChannel:         channels.Schemas,
+		settingsChannel:       channels.Settings,
+		replicationChannel:    channels.Replications,
+		queryStatsChannel:     channels.QueryStats,
+		metricsChannel:        channels.Metrics,
+		securityChannel:       channels.Security,
+		serverEventChannel:    channels.ServerEvents,
+		rawSlowQueryChannel:   channels.RawSlowQueries,
+		rawTempFileChannel:    channels.RawTempFiles,
+		tempFileChannel:       channels.TempFiles,
+		rawVacuumEventChannel: channels.RawVacuumEvents,
+		vacuumEventChannel:    channels.VacuumEvents,
+		rawCheckpointChannel:  channels.RawCheckpoints,
+		rawLockEventChannel:   channels.RawLockEvents,
+		lockEventChannel:      channels.LockEvents,
+		rawLogErrorChannel:    channels.RawLogErrors,
+		logErrorChannel:       channels.LogErrors,
+		rawLoggedPlanChannel:  channels.RawLoggedPlans,
+
 		databaseSchemaState: &DatabaseSchemaState{},
 		databaseStatsState:  &DatabaseStatsState{},
 		pgBouncerStatsState: &PgBouncerStatsState{},
 		queryStatsState:     &QueryStatsState{},
+		settingsState:       &SettingsState{},
+		hostStatsState:      &HostStatsState{},
+		storageState:        &StorageState{},
+		replicationState:    &ReplicationState{},
+		serverState:         &ServerState{},
 		explainer:           &Explainer{},
 		obfuscator:          &Obfuscator{},
+		logLinePrefixes:     logLinePrefixes,
 		postgresClients:     postgresClients,
 	}
 }
@@ -104,6 +181,10 @@ func (o *Observer) WriteLogTestMessage() {
 func (o *Observer) Start() {
 	o.BootstrapMetatdataAndSchemas()
 
+	if o.config.MonitorHost && findLocalPostgresClient(o.postgresClients) == nil {
+		logger.Warn("Not monitoring host: none of the Postgres servers are on this host")
+	}
+
 	go schedule.ScheduleAndRunNow(o.Monitor, o.config.MonitorInterval)
 
 	if o.config.MonitorSchema {
@@ -114,11 +195,28 @@ func (o *Observer) Start() {
 		go schedule.ScheduleAndRunNow(o.MonitorSettings, o.config.MonitorSettingsInterval)
 	}
 
+	if o.config.MonitorSecurity {
+		go schedule.ScheduleAndRunNow(o.MonitorSecurity, o.config.MonitorSecurityInterval)
+	}
+
+	if o.config.MonitorStorage {
+		go schedule.ScheduleAndRunNow(o.MonitorStorage, o.config.MonitorStorageInterval)
+	}
+
+	// extensions are loaded during bootstrap so only schedule future runs
+	go schedule.Schedule(o.MonitorExtensions, o.config.MonitorSettingsInterval, 0)
+
 	if o.config.MonitorQueryStats {
 		go schedule.ScheduleAndRunNow(o.MonitorQueryStats, o.config.MonitorQueryStatsInterval)
 	}
 
 	go o.MonitorSlowQueries()
+	go o.MonitorTempFiles()
+	go o.MonitorVacuumEvents()
+	go o.MonitorCheckpoints()
+	go o.MonitorLockEvents()
+	go o.MonitorLogErrors()
+	go o.MonitorLoggedPlans()
 }
 
 func (o *Observer) BootstrapMetatdataAndSchemas() {
@@ -135,6 +233,14 @@ func (o *Observer) BootstrapMetatdataAndSchemas() {
 			).Start()
 		}
 
+		// load extensions before metadata so they are reported with the server
+		// and optional collectors know which extensions are installed
+		NewMonitorWorker(
+			o.config,
+			postgresClient,
+			&ExtensionMonitor{},
+		).Start()
+
 		// monitor metadata next to ensure version and other high level state is set
 		NewMonitorWorker(
 			o.config,
@@ -160,6 +266,22 @@ func (o *Observer) BootstrapMetatdataAndSchemas() {
 }
 
 func (o *Observer) Monitor() {
+	if o.config.MonitorHost {
+		// host metrics describe the machine the agent runs on so they're reported once for a server on it
+		if postgresClient := findLocalPostgresClient(o.postgresClients); postgresClient != nil {
+			go NewMonitorWorker(
+				o.config,
+				postgresClient,
+				&HostMonitor{
+					metricsChannel: o.metricsChannel,
+					hostStatsState: o.hostStatsState,
+					procPath:       o.config.HostProcPath,
+					sysPath:        o.config.HostSysPath,
+				},
+			).Start()
+		}
+	}
+
 	for _, postgresClient := range o.postgresClients {
 		go NewMonitorWorker(
 			o.config,
@@ -169,6 +291,16 @@ func (o *Observer) Monitor() {
 			},
 		).Start()
 
+		go NewMonitorWorker(
+			o.config,
+			postgresClient,
+			&ServerStateMonitor{
+				serverEventChannel: o.serverEventChannel,
+				serverState:        o.serverState,
+				replicationState:   o.replicationState,
+			},
+		).Start()
+
 		if o.config.MonitorPgBouncer {
 			go NewMonitorWorker(
 				o.config,
@@ -187,11 +319,24 @@ func (o *Observer) Monitor() {
 				&ReplicationMonitor{
 					replicationChannel: o.replicationChannel,
 					metricsChannel:     o.metricsChannel,
+					replicationState:   o.replicationState,
 					postgresClients:    o.postgresClients,
 				},
 			).Start()
 		}
 
+		if o.config.MonitorSettings {
+			go NewMonitorWorker(
+				o.config,
+				postgresClient,
+				&SettingsReloadMonitor{
+					settingsChannel: o.settingsChannel,
+					settingsState:   o.settingsState,
+					logLinePrefixes: o.logLinePrefixes,
+				},
+			).Start()
+		}
+
 		go NewMonitorWorker(
 			o.config,
 			postgresClient,
@@ -212,8 +357,28 @@ func (o *Observer) MonitorQueryStats() {
 				queryStatsState:     o.queryStatsState,
 				queryStatsChannel:   o.queryStatsChannel,
 				obfuscator:          o.obfuscator,
+				explainer:           o.explainer,
 				monitorAgentQueries: o.config.MonitorAgentQueries,
 			},
 		).Start()
 	}
 }
+
+func findLocalPostgresClient(postgresClients []*PostgresClient) *PostgresClient {
+	for _, postgresClient := range postgresClients {
+		if postgresClient.IsLocal() {
+			return postgresClient
+		}
+	}
+	return nil
+}
+
+// returns the server id of the client a log line's config name belongs to
+func (o *Observer) serverIDForConfigName(configName string) *ServerID {
+	for _, client := range o.postgresClients {
+		if client.MatchesConfigName(configName) {
+			return client.serverID
+		}
+	}
+	return nil
+}
@@ -108,3 +108,51 @@ func TestFindPostgresServersMultipleConfigVars(t *testing.T) {
 	os.Unsetenv("GREEN_URL")
 	os.Unsetenv("RED_URL")
 }
+
+func TestMergePostgresClients(t *testing.T) {
+	database := &PostgresClient{
+		serverID: &ServerID{ConfigName: "DATABASE", ConfigVarName: "DATABASE_URL", Database: "test"},
+		host:     "ec2-1-2-3-4.compute-1.amazonaws.com",
+	}
+	green := &PostgresClient{
+		serverID: &ServerID{ConfigName: "GREEN", ConfigVarName: "HEROKU_POSTGRESQL_GREEN_URL", Database: "test"},
+		host:     "ec2-1-2-3-4.compute-1.amazonaws.com",
+	}
+	pooled := &PostgresClient{
+		serverID: &ServerID{ConfigName: "POOLED", ConfigVarName: "POOLED_URL", Database: "test"},
+		host:     "localhost",
+	}
+
+	merged := mergePostgresClients([]*PostgresClient{database, pooled, green})
+
+	// named heroku config vars are preferred
+	assert.Equal(t, green, merged)
+	assert.Equal(t, "DATABASE_URL,POOLED_URL", merged.serverID.ConfigVarAliases)
+	assert.Equal(t, []string{"DATABASE_URL", "POOLED_URL"}, merged.serverID.Aliases())
+	assert.Equal(t, []string{"localhost"}, merged.aliasHosts)
+
+	assert.True(t, merged.MatchesConfigName("GREEN"))
+	assert.True(t, merged.MatchesConfigName("DATABASE"))
+	assert.True(t, merged.MatchesConfigName("POOLED"))
+	assert.False(t, merged.MatchesConfigName("RED"))
+
+	assert.True(t, merged.MatchesHost("ec2-1-2-3-4.compute-1.amazonaws.com"))
+	assert.True(t, merged.MatchesHost("localhost"))
+	assert.False(t, merged.MatchesHost("ec2-5-6-7-8.compute-1.amazonaws.com"))
+}
+
+func TestDedupePostgresClientsUnconnected(t *testing.T) {
+	green := &PostgresClient{
+		client:   &Client{},
+		serverID: &ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"},
+	}
+	red := &PostgresClient{
+		client:   &Client{},
+		serverID: &ServerID{ConfigName: "RED", ConfigVarName: "RED_URL", Database: "test"},
+	}
+
+	// clients without a connection can't be identified so they're never merged
+	clients := DedupePostgresClients([]*PostgresClient{green, red})
+	assert.Equal(t, []*PostgresClient{green, red}, clients)
+	assert.Equal(t, "", green.serverID.ConfigVarAliases)
+}
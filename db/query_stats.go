@@ -7,6 +7,7 @@ import (
 	"math"
 	"regexp"
 	"sort"
+	"sync"
 	"time"
 )
 
@@ -22,6 +23,34 @@ var ipAddressRegex = regexp.MustCompile(`(?:[0-9]{1,3}\.){3}[0-9]{1,3}`)
 type QueryStatsState struct {
 	// map of server config name + database to query stats
 	Stats map[ServerID][]*QueryStats
+
+	// servers already warned about a missing pg_stat_statements extension
+	// kept here since a new monitor is built for every run
+	missingExtensionWarned map[ServerID]bool
+	mu                     sync.Mutex
+}
+
+// returns true the first time a server is missing the extension so it's only warned about once
+func (s *QueryStatsState) warnMissingExtension(serverID ServerID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.missingExtensionWarned[serverID] {
+		return false
+	}
+	if s.missingExtensionWarned == nil {
+		s.missingExtensionWarned = make(map[ServerID]bool)
+	}
+	s.missingExtensionWarned[serverID] = true
+	return true
+}
+
+// warns again if the extension is removed after being installed
+func (s *QueryStatsState) clearMissingExtension(serverID ServerID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.missingExtensionWarned, serverID)
 }
 
 // From https://www.postgresql.org/docs/current/sql-explain.html under BUFFERS
@@ -132,10 +161,19 @@ type QueryStatsMonitor struct {
 	queryStatsState     *QueryStatsState
 	queryStatsChannel   chan []*QueryStats
 	obfuscator          *Obfuscator
+	explainer           *Explainer
 	monitorAgentQueries bool
 }
 
 func (m *QueryStatsMonitor) Run(postgresClient *PostgresClient) {
+	if !postgresClient.HasExtension(PgStatStatementsExtension) {
+		if m.queryStatsState.warnMissingExtension(*postgresClient.serverID) {
+			logger.Warn("Not collecting query stats: pg_stat_statements extension is not installed", "configName", postgresClient.serverID.ConfigName)
+		}
+		return
+	}
+	m.queryStatsState.clearMissingExtension(*postgresClient.serverID)
+
 	// initialize map
 	if m.queryStatsState.Stats == nil {
 		m.queryStatsState.Stats = make(map[ServerID][]*QueryStats)
@@ -183,6 +221,15 @@ func (m *QueryStatsMonitor) Run(postgresClient *PostgresClient) {
 	// filter to the top 100 worst queries by category - don't send all of the queries
 	filtered := m.FilterStats(aggregated)
 
+	// attach plans logged by auto_explain for queries that weren't logged as slow queries
+	if m.explainer != nil {
+		for _, stats := range filtered {
+			if stats.Explain == "" {
+				stats.Explain = m.explainer.TakeLoggedPlan(postgresClient.serverID.ConfigName, stats.Fingerprint)
+			}
+		}
+	}
+
 	// report aggregated stats to channel
 	select {
 	case m.queryStatsChannel <- filtered:
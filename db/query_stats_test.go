@@ -265,3 +265,26 @@ func TestCleanQuery(t *testing.T) {
 	assert.Equal(t, "select * from foo;", CleanQuery("\tselect * from foo;\n"))
 	assert.Equal(t, "select * from foo;", CleanQuery("select     *     from       foo;    "))
 }
+
+func TestQueryStatsMonitorMissingExtension(t *testing.T) {
+	postgresClient := &PostgresClient{serverID: &ServerID{ConfigName: "GREEN"}}
+	postgresClient.SetExtensions([]*Extension{})
+
+	// returns before querying pg_stat_statements
+	state := &QueryStatsState{}
+	monitor := &QueryStatsMonitor{queryStatsState: state}
+	monitor.Run(postgresClient)
+	assert.True(t, state.missingExtensionWarned[*postgresClient.serverID])
+
+	// each run builds a new monitor and the server isn't warned about again
+	assert.False(t, state.warnMissingExtension(*postgresClient.serverID))
+	(&QueryStatsMonitor{queryStatsState: state}).Run(postgresClient)
+	assert.Equal(t, 1, len(state.missingExtensionWarned))
+
+	// other servers are still warned about
+	assert.True(t, state.warnMissingExtension(ServerID{ConfigName: "BLUE"}))
+
+	// warns again once the extension is installed and later removed
+	state.clearMissingExtension(*postgresClient.serverID)
+	assert.True(t, state.warnMissingExtension(*postgresClient.serverID))
+}
@@ -1,10 +1,12 @@
 package db
 
 import (
+	"agent/errors"
 	"agent/logger"
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgtype"
@@ -24,9 +26,52 @@ type Replica struct {
 	ApplicationName   string // application_name:backend_start
 	PrimaryHost       string
 	PrimaryConfigName string // ex GREEN
-	Status            string
+	Status            string // empty when there is no wal receiver
 	Lag               pgtype.Interval
 	MeasuredAt        int64
+
+	// recovery state
+	ReplayPaused     bool
+	ReceiveLsn       sql.NullString
+	ReplayLsn        sql.NullString
+	ReceiveReplayLag float64 // bytes received but not yet replayed
+	LastReplayedAt   sql.NullInt64
+}
+
+// stateful replication object that stores cumulative standby conflicts per server
+// deltas are calculated each polling interval and reported as metrics
+type ReplicationState struct {
+	// map of server config name + database to conflict stats
+	Conflicts map[ServerID]*ConflictStats
+	mu        sync.Mutex
+}
+
+// https://www.postgresql.org/docs/current/monitoring-stats.html#MONITORING-PG-STAT-DATABASE-CONFLICTS-VIEW
+type ConflictStats struct {
+	Tablespace float64
+	Lock       float64
+	Snapshot   float64
+	Bufferpin  float64
+	Deadlock   float64
+}
+
+// clears per-replica state after a promotion, demotion, timeline switch or restart
+func (s *ReplicationState) Reset(serverID ServerID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Conflicts, serverID)
+}
+
+// Calculate the delta between the last conflict stats and the latest conflict stats
+func (c *ConflictStats) Delta(latest *ConflictStats) *ConflictStats {
+	return &ConflictStats{
+		Tablespace: latest.Tablespace - c.Tablespace,
+		Lock:       latest.Lock - c.Lock,
+		Snapshot:   latest.Snapshot - c.Snapshot,
+		Bufferpin:  latest.Bufferpin - c.Bufferpin,
+		Deadlock:   latest.Deadlock - c.Deadlock,
+	}
 }
 
 type ReplicaClient struct {
@@ -51,6 +96,7 @@ type ReplicaClient struct {
 type ReplicationMonitor struct {
 	replicationChannel chan *Replication
 	metricsChannel     chan []*Metric
+	replicationState   *ReplicationState
 	postgresClients    []*PostgresClient
 }
 
@@ -72,6 +118,58 @@ func (m *ReplicationMonitor) Run(postgresClient *PostgresClient) {
 	}
 
 	m.ReportReplicationLagMetrics(postgresClient.serverID, replica, replicas)
+	m.ReportConflictMetrics(postgresClient)
+}
+
+// conflicts only occur on standbys but the view is present on all servers
+func (m *ReplicationMonitor) ReportConflictMetrics(postgresClient *PostgresClient) {
+	conflicts, err := m.FindConflicts(postgresClient)
+	if err != nil {
+		logger.Error("Replication conflicts error", "err", err)
+		errors.Report(err)
+		return
+	}
+
+	now := time.Now().UTC().Unix()
+	delta := m.replicationState.ConflictsDelta(*postgresClient.serverID, conflicts)
+	if delta == nil {
+		return
+	}
+
+	entity := "database/" + postgresClient.serverID.Database
+	conflictMetrics := []*Metric{
+		NewMetric("query.conflicts.tablespace", delta.Tablespace, entity, *postgresClient.serverID, now),
+		NewMetric("query.conflicts.lock", delta.Lock, entity, *postgresClient.serverID, now),
+		NewMetric("query.conflicts.snapshot", delta.Snapshot, entity, *postgresClient.serverID, now),
+		NewMetric("query.conflicts.bufferpin", delta.Bufferpin, entity, *postgresClient.serverID, now),
+		NewMetric("query.conflicts.deadlock", delta.Deadlock, entity, *postgresClient.serverID, now),
+	}
+
+	select {
+	case m.metricsChannel <- conflictMetrics:
+		// sent
+	default:
+		logger.Warn("Dropping replication conflict metrics: channel buffer full")
+	}
+}
+
+// stores the latest conflict stats and returns the delta from the previous poll
+// returns nil until there are two consecutive polls
+func (s *ReplicationState) ConflictsDelta(serverID ServerID, conflicts *ConflictStats) *ConflictStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Conflicts == nil {
+		s.Conflicts = make(map[ServerID]*ConflictStats)
+	}
+
+	previous, ok := s.Conflicts[serverID]
+	s.Conflicts[serverID] = conflicts
+	if !ok {
+		return nil
+	}
+
+	return previous.Delta(conflicts)
 }
 
 func (m *ReplicationMonitor) ReportReplicationLagMetrics(serverID *ServerID, replica *Replica, replicaClients []*ReplicaClient) {
@@ -89,6 +187,16 @@ func (m *ReplicationMonitor) ReportReplicationLagMetrics(serverID *ServerID, rep
 		))
 	}
 
+	if replica != nil {
+		replicationMetrics = append(replicationMetrics, NewMetric(
+			"replication.standby.lag.local.bytes",
+			replica.ReceiveReplayLag,
+			"replica/standby/"+replica.ApplicationName,
+			*serverID,
+			replica.MeasuredAt,
+		))
+	}
+
 	// sent for all replicas of current server
 	for _, replicaClient := range replicaClients {
 		if replicaClient == nil {
@@ -229,26 +337,35 @@ func (m *ReplicationMonitor) FindReplicas(postgresClient *PostgresClient) []*Rep
 }
 
 func (m *ReplicationMonitor) FindReplica(postgresClient *PostgresClient) *Replica {
+	var inRecovery bool
+	err := postgresClient.client.QueryRow("select pg_is_in_recovery()" + postgresMonitorQueryComment()).Scan(&inRecovery)
+	if err != nil || !inRecovery {
+		return nil // primaries are not replicas
+	}
+
 	var replica Replica
-	var connInfo string
+	replica.MeasuredAt = time.Now().UTC().Unix()
+	lag := m.FindReplicationLag(postgresClient)
+	if lag != nil {
+		replica.Lag = *lag
+	}
+
+	// recovery state is available even when the standby isn't streaming from a primary
+	err = m.FindRecoveryState(postgresClient, &replica)
+	if err != nil {
+		logger.Error("Replica recovery state error", "err", err)
+		errors.Report(err)
+	}
 
 	// NOTE: PG 10 doesn't have sender_host available so we extract it from the conninfo
 	// PG 11 adds this field https://www.postgresql.org/docs/11/monitoring-stats.html#PG-STAT-WAL-RECEIVER-VIEW
+	var connInfo string
 	query := `select status, conninfo from pg_stat_wal_receiver` + postgresMonitorQueryComment()
 
-	// or use pg_is_in_recovery() to determine if it's a replica
-
-	err := postgresClient.client.QueryRow(query).Scan(&replica.Status, &connInfo)
-
+	// there is no wal receiver when streaming is down or the standby only restores wal from an archive
+	err = postgresClient.client.QueryRow(query).Scan(&replica.Status, &connInfo)
 	if err != nil || connInfo == "" {
-		return nil // return nil to not send replica with 0 lag
-	}
-
-	measuredAt := time.Now().UTC().Unix()
-	replica.MeasuredAt = measuredAt
-	lag := m.FindReplicationLag(postgresClient)
-	if lag != nil {
-		replica.Lag = *lag
+		return &replica
 	}
 
 	primaryHost, applicationName := m.FindHostAndApplicationNameForPrimaryFromConnInfo(connInfo)
@@ -258,7 +375,7 @@ func (m *ReplicationMonitor) FindReplica(postgresClient *PostgresClient) *Replic
 
 	// match server host with client config name
 	for _, postgresClient := range m.postgresClients {
-		if postgresClient.host == replica.PrimaryHost {
+		if postgresClient.MatchesHost(replica.PrimaryHost) {
 			replica.PrimaryConfigName = postgresClient.serverID.ConfigName
 			break
 		}
@@ -275,6 +392,52 @@ func (m *ReplicationMonitor) FindReplica(postgresClient *PostgresClient) *Replic
 	return &replica
 }
 
+// pg_is_wal_replay_paused() raises an error outside of recovery so this is only queried on replicas
+func (m *ReplicationMonitor) FindRecoveryState(postgresClient *PostgresClient, replica *Replica) error {
+	var lastReplayedAt sql.NullFloat64
+
+	query := `select pg_is_wal_replay_paused(), pg_last_wal_receive_lsn()::text, pg_last_wal_replay_lsn()::text,
+						coalesce(pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn()), 0),
+						extract(epoch from pg_last_xact_replay_timestamp())` + postgresMonitorQueryComment()
+
+	err := postgresClient.client.QueryRow(query).Scan(
+		&replica.ReplayPaused,
+		&replica.ReceiveLsn,
+		&replica.ReplayLsn,
+		&replica.ReceiveReplayLag,
+		&lastReplayedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if lastReplayedAt.Valid {
+		replica.LastReplayedAt = sql.NullInt64{Valid: true, Int64: int64(lastReplayedAt.Float64)}
+	}
+
+	return nil
+}
+
+func (m *ReplicationMonitor) FindConflicts(postgresClient *PostgresClient) (*ConflictStats, error) {
+	var conflicts ConflictStats
+
+	query := `select confl_tablespace, confl_lock, confl_snapshot, confl_bufferpin, confl_deadlock
+						from pg_stat_database_conflicts where datname = current_database()` + postgresMonitorQueryComment()
+
+	err := postgresClient.client.QueryRow(query).Scan(
+		&conflicts.Tablespace,
+		&conflicts.Lock,
+		&conflicts.Snapshot,
+		&conflicts.Bufferpin,
+		&conflicts.Deadlock,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conflicts, nil
+}
+
 func (m *ReplicationMonitor) FindWalReceiverBackendStart(postgresClient *PostgresClient) sql.NullTime {
 	query := "select backend_start from pg_stat_activity where backend_type = 'walreceiver'" + postgresMonitorQueryComment()
 
@@ -29,3 +29,25 @@ func TestFindHostAndApplicationNameForPrimaryFromConnInfoValid(t *testing.T) {
 	assert.Equal(t, "ec2-123-456-789.compute-1.amazonaws.com", serverHost)
 	assert.Equal(t, "follower", applicationName)
 }
+
+func TestReplicationStateConflictsDelta(t *testing.T) {
+	state := &ReplicationState{}
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+
+	// first poll has no delta
+	assert.Nil(t, state.ConflictsDelta(serverID, &ConflictStats{Snapshot: 10, Lock: 2}))
+
+	delta := state.ConflictsDelta(serverID, &ConflictStats{Snapshot: 15, Lock: 2, Bufferpin: 1})
+	assert.Equal(t, &ConflictStats{Snapshot: 5, Bufferpin: 1}, delta)
+}
+
+func TestReplicationStateReset(t *testing.T) {
+	state := &ReplicationState{}
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+
+	state.ConflictsDelta(serverID, &ConflictStats{Snapshot: 10})
+	state.Reset(serverID)
+
+	// conflicts are rebaselined after a reset
+	assert.Nil(t, state.ConflictsDelta(serverID, &ConflictStats{Snapshot: 2}))
+}
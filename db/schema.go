@@ -6,6 +6,9 @@ import (
 	"agent/util"
 	"database/sql"
 	"log"
+	"time"
+
+	"github.com/jackc/pgtype"
 )
 
 // stateful stats object that stores all database schema per server id
@@ -22,8 +25,12 @@ type Database struct {
 }
 
 type Schema struct {
-	Name   string
-	Tables []*Table
+	Name              string
+	Tables            []*Table
+	Views             []*View
+	MaterializedViews []*MaterializedView
+	Triggers          []*Trigger
+	Types             []*CustomType
 }
 
 type Table struct {
@@ -72,8 +79,22 @@ type Table struct {
 	DiskToastIndexBlocksRead int64
 	DiskToastIndexBlocksHit  int64
 
-	Columns []*Column
-	Indexes []*Index
+	// heap only tuple updates don't need new index entries
+	HotUpdatedRows  int64
+	HotUpdateRatio  float64
+	HotUpdateAdvice string
+
+	// storage parameters set with ALTER TABLE ... SET - ex. autovacuum_vacuum_scale_factor
+	Fillfactor     int64
+	StorageOptions map[string]string
+
+	// modified rows since analyze are compared with the effective autoanalyze threshold
+	AutoanalyzeThreshold int64
+	StatisticsStale      bool
+
+	Columns            []*Column
+	Indexes            []*Index
+	ExtendedStatistics []*ExtendedStatistic
 }
 
 type Column struct {
@@ -88,6 +109,11 @@ type Column struct {
 	NumericScale     sql.NullInt64
 	IntervalType     sql.NullString
 	IsIdentity       sql.NullString // YES or NO
+
+	// planner statistics from the last analyze
+	NullFraction   sql.NullFloat64
+	DistinctValues sql.NullFloat64
+	Correlation    sql.NullFloat64
 }
 
 type Index struct {
@@ -108,6 +134,50 @@ type Index struct {
 	DiskBlocksHit   int64
 }
 
+type View struct {
+	Name   string
+	Schema string
+
+	// relations the view selects from - ex. public.users
+	Dependencies []string
+}
+
+type MaterializedView struct {
+	Name         string
+	Schema       string
+	Bytes        int64
+	Populated    bool
+	Dependencies []string
+
+	// postgres doesn't track when a materialized view was last refreshed so we
+	// detect refreshes between polls by a changed relfilenode (REFRESH) or
+	// changed row counts (REFRESH CONCURRENTLY)
+	LastRefreshedAt sql.NullInt64
+	FileNode        int64
+	ModifiedRows    int64
+}
+
+type Trigger struct {
+	Name      string
+	Schema    string
+	TableName string
+	Timing    string   // BEFORE, AFTER or INSTEAD OF
+	Events    []string // INSERT, UPDATE, DELETE and / or TRUNCATE
+	Level     string   // ROW or STATEMENT
+	Enabled   string   // enabled, disabled, replica or always
+	Function  string
+}
+
+// enum, domain and composite types
+type CustomType struct {
+	Name       string
+	Schema     string
+	Kind       string   // enum, domain or composite
+	Values     []string // enum labels
+	BaseType   string   // domain base type - ex. character varying(255)
+	Attributes []string // composite attributes - ex. street text
+}
+
 type UnusedIndex struct {
 	Name      string
 	Schema    string
@@ -155,10 +225,16 @@ func (t *Table) Delta(latest *Table) *Table {
 		DiskToastBlocksHit:       latest.DiskToastBlocksHit - t.DiskToastBlocksHit,
 		DiskToastIndexBlocksRead: latest.DiskToastIndexBlocksRead - t.DiskToastIndexBlocksRead,
 		DiskToastIndexBlocksHit:  latest.DiskToastIndexBlocksHit - t.DiskToastIndexBlocksHit,
+		HotUpdatedRows:           latest.HotUpdatedRows - t.HotUpdatedRows,
+		Fillfactor:               latest.Fillfactor,
+		StorageOptions:           latest.StorageOptions,
+		AutoanalyzeThreshold:     latest.AutoanalyzeThreshold,
+		StatisticsStale:          latest.StatisticsStale,
 
 		// we delta tables before columns/indexes are set so these are not really needed
-		Columns: latest.Columns,
-		Indexes: latest.Indexes, // we delta indexes below
+		Columns:            latest.Columns,
+		Indexes:            latest.Indexes, // we delta indexes below
+		ExtendedStatistics: latest.ExtendedStatistics,
 	}
 	// dead row estimate, and bloat bytes can be negative which doesn't make much sense
 	deadRowEstimate := latest.DeadRowEstimateTotal - t.DeadRowEstimateTotal
@@ -170,6 +246,8 @@ func (t *Table) Delta(latest *Table) *Table {
 		table.BloatBytes = bloatBytes
 	}
 	table.DiskBlocksHitPercent = util.HitPercent(float64(table.DiskBlocksHit), float64(table.DiskBlocksRead))
+	table.HotUpdateRatio = hotUpdateRatio(table.HotUpdatedRows, table.UpdatedRows)
+	table.HotUpdateAdvice = hotUpdateAdvice(table)
 	return table
 }
 
@@ -226,6 +304,9 @@ func (m *SchemaMonitor) Run(postgresClient *PostgresClient) {
 	tables := m.FindTables(postgresClient)
 	indexes := m.FindIndexes(postgresClient)
 	bloat := m.FindBloat(postgresClient)
+	views, materializedViews := m.FindViews(postgresClient)
+	triggers := m.FindTriggers(postgresClient)
+	customTypes := m.FindCustomTypes(postgresClient)
 
 	// ordering matters with these
 	// add tables to schemas
@@ -237,6 +318,30 @@ func (m *SchemaMonitor) Run(postgresClient *PostgresClient) {
 		}
 	}
 
+	// add views, materialized views, triggers and types to schemas
+	for _, schema := range schemas {
+		for _, view := range views {
+			if schema.Name == view.Schema {
+				schema.Views = append(schema.Views, view)
+			}
+		}
+		for _, materializedView := range materializedViews {
+			if schema.Name == materializedView.Schema {
+				schema.MaterializedViews = append(schema.MaterializedViews, materializedView)
+			}
+		}
+		for _, trigger := range triggers {
+			if schema.Name == trigger.Schema {
+				schema.Triggers = append(schema.Triggers, trigger)
+			}
+		}
+		for _, customType := range customTypes {
+			if schema.Name == customType.Schema {
+				schema.Types = append(schema.Types, customType)
+			}
+		}
+	}
+
 	// add indexes to tables
 	for _, index := range indexes {
 		for _, schema := range schemas {
@@ -281,11 +386,17 @@ func (m *SchemaMonitor) Run(postgresClient *PostgresClient) {
 	// delta tables and indexes after stitching objects together to make sure bloat and other metrics are set
 	previousDatabase, ok := m.databaseSchemaState.Databases[*postgresClient.serverID]
 	if ok {
+		m.detectMaterializedViewRefreshes(materializedViews, previousDatabase, time.Now().UTC().Unix())
+
 		var deltaSchemas []*Schema
 		for _, schema := range schemas {
 			deltaSchema := &Schema{
-				Name:   schema.Name,
-				Tables: m.deltaTables(schema.Tables, previousDatabase),
+				Name:              schema.Name,
+				Tables:            m.deltaTables(schema.Tables, previousDatabase),
+				Views:             schema.Views,
+				MaterializedViews: schema.MaterializedViews,
+				Triggers:          schema.Triggers,
+				Types:             schema.Types,
 			}
 			deltaSchemas = append(deltaSchemas, deltaSchema)
 		}
@@ -363,6 +474,27 @@ func (m *SchemaMonitor) deltaIndexes(indexes []*Index, previousDatabase *Databas
 	return deltaIndexes
 }
 
+// a materialized view was refreshed since the last poll if its relfilenode changed (REFRESH)
+// or if rows were modified (REFRESH CONCURRENTLY) - otherwise carry over the last refresh time
+func (m *SchemaMonitor) detectMaterializedViewRefreshes(materializedViews []*MaterializedView, previousDatabase *Database, now int64) {
+	for _, materializedView := range materializedViews {
+		for _, previousSchema := range previousDatabase.Schemas {
+			if materializedView.Schema != previousSchema.Name {
+				continue
+			}
+			for _, previous := range previousSchema.MaterializedViews {
+				if materializedView.Name == previous.Name {
+					if materializedView.FileNode != previous.FileNode || materializedView.ModifiedRows != previous.ModifiedRows {
+						materializedView.LastRefreshedAt = sql.NullInt64{Valid: true, Int64: now}
+					} else {
+						materializedView.LastRefreshedAt = previous.LastRefreshedAt
+					}
+				}
+			}
+		}
+	}
+}
+
 func (m *SchemaMonitor) FindSchemas(postgresClient *PostgresClient) []*Schema {
 	query := `select schema_name as name from information_schema.schemata
 						where schema_name not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')
@@ -433,6 +565,19 @@ func (m *SchemaMonitor) FindTables(postgresClient *PostgresClient) []*Table {
 		}
 	}
 
+	// merge in planner statistics for columns
+	mergeColumnStatistics(columns, m.FindColumnStatistics(postgresClient))
+
+	// merge in extended statistics
+	extendedStatistics := m.FindExtendedStatistics(postgresClient)
+	for _, statistic := range extendedStatistics {
+		for _, table := range tables {
+			if table.Schema == statistic.Schema && table.Name == statistic.TableName {
+				table.ExtendedStatistics = append(table.ExtendedStatistics, statistic)
+			}
+		}
+	}
+
 	// merge in table stats
 	tableStats := m.FindTableStats(postgresClient)
 	for _, tableStat := range tableStats {
@@ -464,12 +609,23 @@ func (m *SchemaMonitor) FindTables(postgresClient *PostgresClient) []*Table {
 				table.DiskToastBlocksHit = tableStat.DiskToastBlocksHit
 				table.DiskToastIndexBlocksRead = tableStat.DiskToastIndexBlocksRead
 				table.DiskToastIndexBlocksHit = tableStat.DiskToastIndexBlocksHit
+				table.HotUpdatedRows = tableStat.HotUpdatedRows
+				table.Fillfactor = tableStat.Fillfactor
+				table.StorageOptions = tableStat.StorageOptions
 
 				break
 			}
 		}
 	}
 
+	autoanalyzeSettings := m.FindAutoanalyzeSettings(postgresClient)
+	if autoanalyzeSettings != nil {
+		for _, table := range tables {
+			table.AutoanalyzeThreshold = autoanalyzeThreshold(table, autoanalyzeSettings)
+			table.StatisticsStale = statisticsStale(table, autoanalyzeSettings)
+		}
+	}
+
 	return tables
 }
 
@@ -528,9 +684,10 @@ func (m *SchemaMonitor) FindTableStats(postgresClient *PostgresClient) []*Table
 						extract(epoch from last_autoanalyze)::int as last_autoanalyze,
 						vacuum_count, autovacuum_count, analyze_count, autoanalyze_count,
 						heap_blks_read, heap_blks_hit, idx_blks_read, idx_blks_hit, toast_blks_read, toast_blks_hit,
-						tidx_blks_read, tidx_blks_hit
+						tidx_blks_read, tidx_blks_hit, n_tup_hot_upd, cls.reloptions
 						from pg_stat_user_tables stat
 						join pg_statio_user_tables statio on statio.relid = stat.relid
+						join pg_class cls on cls.oid = stat.relid
 						where stat.schemaname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')` + postgresMonitorQueryComment()
 	var tables []*Table
 	rows, err := postgresClient.client.Query(query)
@@ -548,6 +705,7 @@ func (m *SchemaMonitor) FindTableStats(postgresClient *PostgresClient) []*Table
 		var diskToastBlocksHit sql.NullInt64
 		var diskToastIndexBlocksRead sql.NullInt64
 		var diskToastIndexBlocksHit sql.NullInt64
+		var reloptions pgtype.TextArray
 
 		err := rows.Scan(
 			&table.Name,
@@ -578,6 +736,8 @@ func (m *SchemaMonitor) FindTableStats(postgresClient *PostgresClient) []*Table
 			&diskToastBlocksHit,
 			&diskToastIndexBlocksRead,
 			&diskToastIndexBlocksHit,
+			&table.HotUpdatedRows,
+			&reloptions,
 		)
 		if err != nil {
 			logger.Error("Find table stats error", "err", err)
@@ -596,6 +756,8 @@ func (m *SchemaMonitor) FindTableStats(postgresClient *PostgresClient) []*Table
 		if diskToastIndexBlocksHit.Valid {
 			table.DiskToastIndexBlocksHit = diskToastIndexBlocksHit.Int64
 		}
+		table.StorageOptions = parseStorageOptions(textArrayToStrings(reloptions))
+		table.Fillfactor = tableFillfactor(table.StorageOptions)
 		tables = append(tables, &table)
 	}
 
@@ -718,6 +880,236 @@ func (m *SchemaMonitor) FindUnusedIndexes(postgresClient *PostgresClient) []*Unu
 	return unusedIndexes
 }
 
+// returns both views and materialized views along with the relations they depend on
+func (m *SchemaMonitor) FindViews(postgresClient *PostgresClient) ([]*View, []*MaterializedView) {
+	query := `select v.relname as name, vn.nspname as schema, v.relkind::text as kind,
+							coalesce(pg_total_relation_size(v.oid), 0) as bytes,
+							v.relispopulated as populated,
+							v.relfilenode as file_node,
+							coalesce(stat.n_tup_ins + stat.n_tup_upd + stat.n_tup_del, 0) as modified_rows,
+							coalesce((select array_agg(distinct dn.nspname || '.' || d.relname)
+								from pg_rewrite r
+								join pg_depend dep on dep.objid = r.oid and dep.classid = 'pg_rewrite'::regclass
+								join pg_class d on d.oid = dep.refobjid and d.oid <> v.oid
+								join pg_namespace dn on dn.oid = d.relnamespace
+								where r.ev_class = v.oid), '{}') as dependencies
+						from pg_class v
+						join pg_namespace vn on vn.oid = v.relnamespace
+						left join pg_stat_user_tables stat on stat.relid = v.oid
+						where v.relkind in ('v', 'm')
+						and vn.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')` + postgresMonitorQueryComment()
+
+	var views []*View
+	var materializedViews []*MaterializedView
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find views error", "err", err)
+		errors.Report(err)
+		return views, materializedViews
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var schema string
+		var kind string
+		var bytes int64
+		var populated bool
+		var fileNode int64
+		var modifiedRows int64
+		var dependencies pgtype.TextArray
+
+		err := rows.Scan(&name, &schema, &kind, &bytes, &populated, &fileNode, &modifiedRows, &dependencies)
+		if err != nil {
+			logger.Error("Find views error", "err", err)
+			errors.Report(err)
+			continue
+		}
+
+		if kind == "m" {
+			materializedViews = append(materializedViews, &MaterializedView{
+				Name:         name,
+				Schema:       schema,
+				Bytes:        bytes,
+				Populated:    populated,
+				Dependencies: textArrayToStrings(dependencies),
+				FileNode:     fileNode,
+				ModifiedRows: modifiedRows,
+			})
+		} else {
+			views = append(views, &View{
+				Name:         name,
+				Schema:       schema,
+				Dependencies: textArrayToStrings(dependencies),
+			})
+		}
+	}
+
+	return views, materializedViews
+}
+
+// user defined triggers - internal triggers used for foreign keys are skipped
+func (m *SchemaMonitor) FindTriggers(postgresClient *PostgresClient) []*Trigger {
+	query := `select t.tgname as name, n.nspname as schema, c.relname as table_name,
+							t.tgtype as type, t.tgenabled::text as enabled, p.proname as function
+						from pg_trigger t
+						join pg_class c on c.oid = t.tgrelid
+						join pg_namespace n on n.oid = c.relnamespace
+						join pg_proc p on p.oid = t.tgfoid
+						where not t.tgisinternal
+						and n.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')` + postgresMonitorQueryComment()
+
+	var triggers []*Trigger
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find triggers error", "err", err)
+		errors.Report(err)
+		return triggers
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trigger Trigger
+		var triggerType int64
+		var enabled string
+
+		err := rows.Scan(&trigger.Name, &trigger.Schema, &trigger.TableName, &triggerType, &enabled, &trigger.Function)
+		if err != nil {
+			logger.Error("Find triggers error", "err", err)
+			errors.Report(err)
+			continue
+		}
+
+		trigger.Timing, trigger.Events, trigger.Level = decodeTriggerType(triggerType)
+		trigger.Enabled = decodeTriggerEnabled(enabled)
+
+		triggers = append(triggers, &trigger)
+	}
+
+	return triggers
+}
+
+// trigger type bits from https://github.com/postgres/postgres/blob/master/src/include/catalog/pg_trigger.h
+const (
+	triggerTypeRow      = 1 << 0
+	triggerTypeBefore   = 1 << 1
+	triggerTypeInsert   = 1 << 2
+	triggerTypeDelete   = 1 << 3
+	triggerTypeUpdate   = 1 << 4
+	triggerTypeTruncate = 1 << 5
+	triggerTypeInstead  = 1 << 6
+)
+
+func decodeTriggerType(triggerType int64) (string, []string, string) {
+	timing := "AFTER"
+	if triggerType&triggerTypeBefore != 0 {
+		timing = "BEFORE"
+	} else if triggerType&triggerTypeInstead != 0 {
+		timing = "INSTEAD OF"
+	}
+
+	var events []string
+	if triggerType&triggerTypeInsert != 0 {
+		events = append(events, "INSERT")
+	}
+	if triggerType&triggerTypeUpdate != 0 {
+		events = append(events, "UPDATE")
+	}
+	if triggerType&triggerTypeDelete != 0 {
+		events = append(events, "DELETE")
+	}
+	if triggerType&triggerTypeTruncate != 0 {
+		events = append(events, "TRUNCATE")
+	}
+
+	level := "STATEMENT"
+	if triggerType&triggerTypeRow != 0 {
+		level = "ROW"
+	}
+
+	return timing, events, level
+}
+
+// https://www.postgresql.org/docs/current/catalog-pg-trigger.html
+func decodeTriggerEnabled(enabled string) string {
+	switch enabled {
+	case "O":
+		return "enabled"
+	case "D":
+		return "disabled"
+	case "R":
+		return "replica"
+	case "A":
+		return "always"
+	default:
+		return enabled
+	}
+}
+
+// enum, domain and composite types - composite types backing tables and views are skipped
+func (m *SchemaMonitor) FindCustomTypes(postgresClient *PostgresClient) []*CustomType {
+	query := `select t.typname as name, n.nspname as schema, t.typtype::text as kind,
+							coalesce((select array_agg(e.enumlabel::text order by e.enumsortorder)
+								from pg_enum e where e.enumtypid = t.oid), '{}') as values,
+							case when t.typtype = 'd' then format_type(t.typbasetype, t.typtypmod) else '' end as base_type,
+							coalesce((select array_agg(a.attname || ' ' || format_type(a.atttypid, a.atttypmod) order by a.attnum)
+								from pg_attribute a where a.attrelid = t.typrelid and a.attnum > 0 and not a.attisdropped), '{}') as attributes
+						from pg_type t
+						join pg_namespace n on n.oid = t.typnamespace
+						left join pg_class c on c.oid = t.typrelid
+						where (t.typtype in ('e', 'd') or (t.typtype = 'c' and c.relkind = 'c'))
+						and n.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')` + postgresMonitorQueryComment()
+
+	var customTypes []*CustomType
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find custom types error", "err", err)
+		errors.Report(err)
+		return customTypes
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var customType CustomType
+		var kind string
+		var values pgtype.TextArray
+		var attributes pgtype.TextArray
+
+		err := rows.Scan(&customType.Name, &customType.Schema, &kind, &values, &customType.BaseType, &attributes)
+		if err != nil {
+			logger.Error("Find custom types error", "err", err)
+			errors.Report(err)
+			continue
+		}
+
+		switch kind {
+		case "e":
+			customType.Kind = "enum"
+			customType.Values = textArrayToStrings(values)
+		case "d":
+			customType.Kind = "domain"
+		case "c":
+			customType.Kind = "composite"
+			customType.Attributes = textArrayToStrings(attributes)
+		}
+
+		customTypes = append(customTypes, &customType)
+	}
+
+	return customTypes
+}
+
+func textArrayToStrings(textArray pgtype.TextArray) []string {
+	var values []string
+	if textArray.Status == pgtype.Present {
+		textArray.AssignTo(&values)
+	}
+	return values
+}
+
 type BloatResult struct {
 	Type       string // table or index
 	Schemaname string
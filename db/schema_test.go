@@ -141,6 +141,27 @@ func TestTableDelta(t *testing.T) {
 	assert.Equal(t, int64(0), d.DiskToastIndexBlocksHit)
 }
 
+func TestTableDeltaHotUpdates(t *testing.T) {
+	p := &Table{Name: "table", Schema: "schema", UpdatedRows: 1000, HotUpdatedRows: 900}
+	l := &Table{
+		Name:           "table",
+		Schema:         "schema",
+		UpdatedRows:    11000,
+		HotUpdatedRows: 1900,
+		Fillfactor:     100,
+		StorageOptions: map[string]string{"autovacuum_enabled": "false"},
+	}
+
+	d := p.Delta(l)
+
+	assert.Equal(t, int64(10000), d.UpdatedRows)
+	assert.Equal(t, int64(1000), d.HotUpdatedRows)
+	assert.Equal(t, 0.1, d.HotUpdateRatio)
+	assert.Equal(t, int64(100), d.Fillfactor)
+	assert.Equal(t, map[string]string{"autovacuum_enabled": "false"}, d.StorageOptions)
+	assert.Equal(t, HotUpdateAdviceLowerFillfactor, d.HotUpdateAdvice)
+}
+
 func TestIndexDelta(t *testing.T) {
 	i := &Index{
 		Name:            "index",
@@ -197,3 +218,59 @@ func TestIndexDelta(t *testing.T) {
 	assert.Equal(t, int64(1), d.DiskBlocksRead)
 	assert.Equal(t, int64(90), d.DiskBlocksHit)
 }
+
+func TestDecodeTriggerType(t *testing.T) {
+	// before insert or update for each row
+	timing, events, level := decodeTriggerType(triggerTypeRow | triggerTypeBefore | triggerTypeInsert | triggerTypeUpdate)
+	assert.Equal(t, "BEFORE", timing)
+	assert.Equal(t, []string{"INSERT", "UPDATE"}, events)
+	assert.Equal(t, "ROW", level)
+
+	// after truncate for each statement
+	timing, events, level = decodeTriggerType(triggerTypeTruncate)
+	assert.Equal(t, "AFTER", timing)
+	assert.Equal(t, []string{"TRUNCATE"}, events)
+	assert.Equal(t, "STATEMENT", level)
+
+	// instead of delete on a view
+	timing, events, level = decodeTriggerType(triggerTypeRow | triggerTypeInstead | triggerTypeDelete)
+	assert.Equal(t, "INSTEAD OF", timing)
+	assert.Equal(t, []string{"DELETE"}, events)
+	assert.Equal(t, "ROW", level)
+}
+
+func TestDecodeTriggerEnabled(t *testing.T) {
+	assert.Equal(t, "enabled", decodeTriggerEnabled("O"))
+	assert.Equal(t, "disabled", decodeTriggerEnabled("D"))
+	assert.Equal(t, "replica", decodeTriggerEnabled("R"))
+	assert.Equal(t, "always", decodeTriggerEnabled("A"))
+}
+
+func TestDetectMaterializedViewRefreshes(t *testing.T) {
+	monitor := &SchemaMonitor{}
+
+	previousDatabase := &Database{
+		Schemas: []*Schema{{
+			Name: "public",
+			MaterializedViews: []*MaterializedView{
+				{Name: "refreshed", Schema: "public", FileNode: 1000},
+				{Name: "concurrently", Schema: "public", FileNode: 2000, ModifiedRows: 10},
+				{Name: "unchanged", Schema: "public", FileNode: 3000, LastRefreshedAt: sql.NullInt64{Valid: true, Int64: 100}},
+			},
+		}},
+	}
+
+	materializedViews := []*MaterializedView{
+		{Name: "refreshed", Schema: "public", FileNode: 1001},
+		{Name: "concurrently", Schema: "public", FileNode: 2000, ModifiedRows: 20},
+		{Name: "unchanged", Schema: "public", FileNode: 3000},
+		{Name: "new", Schema: "public", FileNode: 4000},
+	}
+
+	monitor.detectMaterializedViewRefreshes(materializedViews, previousDatabase, 200)
+
+	assert.Equal(t, sql.NullInt64{Valid: true, Int64: 200}, materializedViews[0].LastRefreshedAt)
+	assert.Equal(t, sql.NullInt64{Valid: true, Int64: 200}, materializedViews[1].LastRefreshedAt)
+	assert.Equal(t, sql.NullInt64{Valid: true, Int64: 100}, materializedViews[2].LastRefreshedAt)
+	assert.False(t, materializedViews[3].LastRefreshedAt.Valid)
+}
@@ -0,0 +1,371 @@
+package db
+
+import (
+	"agent/errors"
+	"agent/logger"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+// security audit of roles, role membership and risky privileges for a server
+type Security struct {
+	ServerID                 *ServerID
+	Roles                    []*Role
+	RoleMemberships          []*RoleMembership
+	PublicPrivileges         []*PublicPrivilege
+	SecurityDefinerFunctions []*SecurityDefinerFunction
+	LoginRoleOwnedObjects    []*OwnedObject
+
+	// more than maxLoginRoleOwnedObjects objects are owned by login roles
+	LoginRoleOwnedObjectsTruncated bool
+
+	MeasuredAt int64
+}
+
+// https://www.postgresql.org/docs/current/view-pg-roles.html
+type Role struct {
+	Name            string
+	Superuser       bool
+	CreateRole      bool
+	CreateDB        bool
+	BypassRLS       bool
+	Replication     bool
+	Login           bool
+	ValidUntil      sql.NullInt64
+	ConnectionLimit int64
+}
+
+type RoleMembership struct {
+	Role        string
+	Member      string
+	AdminOption bool
+}
+
+// a schema or relation that grants privileges to PUBLIC
+type PublicPrivilege struct {
+	Schema     string
+	Name       string
+	Kind       string // schema, table, view, etc
+	Privileges []string
+}
+
+// SECURITY DEFINER functions without a pinned search_path can be hijacked
+// by creating objects in a schema that appears earlier in the caller's search_path
+type SecurityDefinerFunction struct {
+	Schema    string
+	Name      string
+	Arguments string
+	Owner     string
+}
+
+type OwnedObject struct {
+	Schema string
+	Name   string
+	Kind   string
+	Owner  string
+}
+
+// owned objects can be numerous since an app's login role usually owns every table
+const maxLoginRoleOwnedObjects = 1000
+
+type SecurityMonitor struct {
+	securityChannel chan *Security
+}
+
+func (o *Observer) MonitorSecurity() {
+	for _, postgresClient := range o.postgresClients {
+		go NewMonitorWorker(
+			o.config,
+			postgresClient,
+			&SecurityMonitor{
+				securityChannel: o.securityChannel,
+			},
+		).Start()
+	}
+}
+
+func (m *SecurityMonitor) Run(postgresClient *PostgresClient) {
+	security := &Security{
+		ServerID:                 postgresClient.serverID,
+		Roles:                    m.FindRoles(postgresClient),
+		RoleMemberships:          m.FindRoleMemberships(postgresClient),
+		PublicPrivileges:         append(m.FindPublicSchemaPrivileges(postgresClient), m.FindPublicRelationPrivileges(postgresClient)...),
+		SecurityDefinerFunctions: m.FindSecurityDefinerFunctions(postgresClient),
+		MeasuredAt:               time.Now().UTC().Unix(),
+	}
+	security.LoginRoleOwnedObjects, security.LoginRoleOwnedObjectsTruncated = truncateOwnedObjects(m.FindLoginRoleOwnedObjects(postgresClient))
+
+	select {
+	case m.securityChannel <- security:
+		// sent
+	default:
+		logger.Warn("Dropping security: channel buffer full")
+	}
+}
+
+func (m *SecurityMonitor) FindRoles(postgresClient *PostgresClient) []*Role {
+	// skip built-in pg_* roles
+	query := `select rolname, rolsuper, rolcreaterole, rolcreatedb, rolbypassrls, rolreplication, rolcanlogin,
+						case when rolvaliduntil is null or rolvaliduntil = 'infinity' then null
+							else extract(epoch from rolvaliduntil)::bigint end as valid_until,
+						rolconnlimit
+						from pg_roles where rolname not like 'pg\_%'` + postgresMonitorQueryComment()
+
+	var roles []*Role
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find roles error", "err", err)
+		errors.Report(err)
+		return roles
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role Role
+		err := rows.Scan(
+			&role.Name,
+			&role.Superuser,
+			&role.CreateRole,
+			&role.CreateDB,
+			&role.BypassRLS,
+			&role.Replication,
+			&role.Login,
+			&role.ValidUntil,
+			&role.ConnectionLimit,
+		)
+		if err != nil {
+			logger.Error("Find roles error", "err", err)
+			errors.Report(err)
+			continue
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles
+}
+
+func (m *SecurityMonitor) FindRoleMemberships(postgresClient *PostgresClient) []*RoleMembership {
+	query := `select r.rolname as role, mr.rolname as member, am.admin_option
+						from pg_auth_members am
+						join pg_roles r on r.oid = am.roleid
+						join pg_roles mr on mr.oid = am.member` + postgresMonitorQueryComment()
+
+	var memberships []*RoleMembership
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find role memberships error", "err", err)
+		errors.Report(err)
+		return memberships
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var membership RoleMembership
+		err := rows.Scan(&membership.Role, &membership.Member, &membership.AdminOption)
+		if err != nil {
+			logger.Error("Find role memberships error", "err", err)
+			errors.Report(err)
+			continue
+		}
+		memberships = append(memberships, &membership)
+	}
+
+	return memberships
+}
+
+// grantee 0 is PUBLIC in an exploded acl
+// a null acl means the default privileges which grant CREATE on the public schema to PUBLIC before postgres 15
+func (m *SecurityMonitor) FindPublicSchemaPrivileges(postgresClient *PostgresClient) []*PublicPrivilege {
+	query := `select n.nspname as schema, array_agg(distinct a.privilege_type) as privileges
+						from pg_namespace n, aclexplode(coalesce(n.nspacl, acldefault('n', n.nspowner))) a
+						where a.grantee = 0
+						and n.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')
+						and n.nspname not like 'pg_toast_temp_%' and n.nspname not like 'pg_temp_%'
+						group by n.nspname` + postgresMonitorQueryComment()
+
+	var privileges []*PublicPrivilege
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find public schema privileges error", "err", err)
+		errors.Report(err)
+		return privileges
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var privilege PublicPrivilege
+		var privilegeTypes pgtype.TextArray
+		err := rows.Scan(&privilege.Schema, &privilegeTypes)
+		if err != nil {
+			logger.Error("Find public schema privileges error", "err", err)
+			errors.Report(err)
+			continue
+		}
+		privilege.Name = privilege.Schema
+		privilege.Kind = "schema"
+		privilege.Privileges = textArrayToStrings(privilegeTypes)
+		privileges = append(privileges, &privilege)
+	}
+
+	return privileges
+}
+
+func (m *SecurityMonitor) FindPublicRelationPrivileges(postgresClient *PostgresClient) []*PublicPrivilege {
+	query := `select n.nspname as schema, c.relname as name, c.relkind::text as kind,
+							array_agg(distinct a.privilege_type) as privileges
+						from pg_class c
+						join pg_namespace n on n.oid = c.relnamespace,
+						aclexplode(coalesce(c.relacl, acldefault(case when c.relkind = 'S' then 's' else 'r' end, c.relowner))) a
+						where a.grantee = 0
+						and c.relkind in ('r', 'p', 'v', 'm', 'f', 'S')
+						and n.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')
+						group by n.nspname, c.relname, c.relkind` + postgresMonitorQueryComment()
+
+	var privileges []*PublicPrivilege
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find public relation privileges error", "err", err)
+		errors.Report(err)
+		return privileges
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var privilege PublicPrivilege
+		var kind string
+		var privilegeTypes pgtype.TextArray
+		err := rows.Scan(&privilege.Schema, &privilege.Name, &kind, &privilegeTypes)
+		if err != nil {
+			logger.Error("Find public relation privileges error", "err", err)
+			errors.Report(err)
+			continue
+		}
+		privilege.Kind = relationKind(kind)
+		privilege.Privileges = textArrayToStrings(privilegeTypes)
+		privileges = append(privileges, &privilege)
+	}
+
+	return privileges
+}
+
+func (m *SecurityMonitor) FindSecurityDefinerFunctions(postgresClient *PostgresClient) []*SecurityDefinerFunction {
+	query := `select n.nspname as schema, p.proname as name,
+							pg_get_function_identity_arguments(p.oid) as arguments, r.rolname as owner, p.proconfig
+						from pg_proc p
+						join pg_namespace n on n.oid = p.pronamespace
+						join pg_roles r on r.oid = p.proowner
+						where p.prosecdef
+						and n.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')` + postgresMonitorQueryComment()
+
+	var functions []*SecurityDefinerFunction
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find security definer functions error", "err", err)
+		errors.Report(err)
+		return functions
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var function SecurityDefinerFunction
+		var config pgtype.TextArray
+		err := rows.Scan(&function.Schema, &function.Name, &function.Arguments, &function.Owner, &config)
+		if err != nil {
+			logger.Error("Find security definer functions error", "err", err)
+			errors.Report(err)
+			continue
+		}
+		if hasPinnedSearchPath(textArrayToStrings(config)) {
+			continue
+		}
+		functions = append(functions, &function)
+	}
+
+	return functions
+}
+
+func (m *SecurityMonitor) FindLoginRoleOwnedObjects(postgresClient *PostgresClient) []*OwnedObject {
+	query := `select n.nspname as schema, c.relname as name, c.relkind::text as kind, r.rolname as owner
+						from pg_class c
+						join pg_namespace n on n.oid = c.relnamespace
+						join pg_roles r on r.oid = c.relowner
+						where r.rolcanlogin
+						and c.relkind in ('r', 'p', 'v', 'm', 'f', 'S')
+						and n.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')
+						order by n.nspname, c.relname
+						limit ` + fmt.Sprint(maxLoginRoleOwnedObjects+1) + postgresMonitorQueryComment()
+
+	var objects []*OwnedObject
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find login role owned objects error", "err", err)
+		errors.Report(err)
+		return objects
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var object OwnedObject
+		var kind string
+		err := rows.Scan(&object.Schema, &object.Name, &kind, &object.Owner)
+		if err != nil {
+			logger.Error("Find login role owned objects error", "err", err)
+			errors.Report(err)
+			continue
+		}
+		object.Kind = relationKind(kind)
+		objects = append(objects, &object)
+	}
+
+	return objects
+}
+
+// a function's SET search_path is stored in proconfig - ex. search_path=public, pg_temp
+func hasPinnedSearchPath(config []string) bool {
+	for _, setting := range config {
+		if strings.HasPrefix(setting, "search_path=") {
+			return true
+		}
+	}
+	return false
+}
+
+// one more object than the limit is queried to know whether the list was truncated
+func truncateOwnedObjects(objects []*OwnedObject) ([]*OwnedObject, bool) {
+	if len(objects) > maxLoginRoleOwnedObjects {
+		return objects[:maxLoginRoleOwnedObjects], true
+	}
+	return objects, false
+}
+
+// https://www.postgresql.org/docs/current/catalog-pg-class.html
+func relationKind(relkind string) string {
+	switch relkind {
+	case "r":
+		return "table"
+	case "p":
+		return "partitioned table"
+	case "v":
+		return "view"
+	case "m":
+		return "materialized view"
+	case "f":
+		return "foreign table"
+	case "S":
+		return "sequence"
+	case "i":
+		return "index"
+	default:
+		return relkind
+	}
+}
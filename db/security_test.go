@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelationKind(t *testing.T) {
+	assert.Equal(t, "table", relationKind("r"))
+	assert.Equal(t, "partitioned table", relationKind("p"))
+	assert.Equal(t, "view", relationKind("v"))
+	assert.Equal(t, "materialized view", relationKind("m"))
+	assert.Equal(t, "foreign table", relationKind("f"))
+	assert.Equal(t, "sequence", relationKind("S"))
+	assert.Equal(t, "index", relationKind("i"))
+
+	// unknown kinds are reported as is
+	assert.Equal(t, "c", relationKind("c"))
+}
+
+func TestHasPinnedSearchPath(t *testing.T) {
+	assert.False(t, hasPinnedSearchPath(nil))
+	assert.False(t, hasPinnedSearchPath([]string{"work_mem=64MB"}))
+	assert.True(t, hasPinnedSearchPath([]string{"work_mem=64MB", "search_path=public, pg_temp"}))
+
+	// an empty search_path is still pinned
+	assert.True(t, hasPinnedSearchPath([]string{`search_path=""`}))
+}
+
+func TestTruncateOwnedObjects(t *testing.T) {
+	var objects []*OwnedObject
+	for i := 0; i < maxLoginRoleOwnedObjects; i++ {
+		objects = append(objects, &OwnedObject{Schema: "public", Name: fmt.Sprintf("table_%d", i), Kind: "table", Owner: "app"})
+	}
+
+	truncated, ok := truncateOwnedObjects(objects)
+	assert.False(t, ok)
+	assert.Equal(t, maxLoginRoleOwnedObjects, len(truncated))
+
+	objects = append(objects, &OwnedObject{Schema: "public", Name: "users", Kind: "table", Owner: "app"})
+	truncated, ok = truncateOwnedObjects(objects)
+	assert.True(t, ok)
+	assert.Equal(t, maxLoginRoleOwnedObjects, len(truncated))
+	assert.Equal(t, "table_999", truncated[len(truncated)-1].Name)
+
+	truncated, ok = truncateOwnedObjects(nil)
+	assert.False(t, ok)
+	assert.Nil(t, truncated)
+}
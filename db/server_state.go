@@ -0,0 +1,152 @@
+package db
+
+import (
+	"agent/errors"
+	"agent/logger"
+	"database/sql"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	ServerEventTypePromotion      = "promotion"
+	ServerEventTypeDemotion       = "demotion"
+	ServerEventTypeTimelineSwitch = "timeline_switch"
+	ServerEventTypeRestart        = "restart"
+)
+
+// stateful server object that stores the last recovery state, timeline and start time per server
+// so that failovers, promotions and restarts can be detected between polls
+type ServerState struct {
+	// map of server config name + database to server status
+	Statuses map[ServerID]*ServerStatus
+	mu       sync.Mutex
+}
+
+type ServerStatus struct {
+	InRecovery          bool
+	TimelineID          sql.NullInt64 // only readable by superusers and pg_monitor members
+	PostmasterStartedAt int64
+}
+
+// a discrete change to a server's role or lifecycle
+type ServerEvent struct {
+	ServerID      *ServerID
+	Type          string
+	PreviousValue string
+	Value         string
+	DetectedAt    int64
+}
+
+// checks the server status each poll and emits server events when it changes
+type ServerStateMonitor struct {
+	serverEventChannel chan *ServerEvent
+	serverState        *ServerState
+	replicationState   *ReplicationState
+}
+
+func (m *ServerStateMonitor) Run(postgresClient *PostgresClient) {
+	status, err := m.FindServerStatus(postgresClient)
+	if err != nil {
+		logger.Error("Server status error", "err", err)
+		errors.Report(err)
+		return
+	}
+
+	events := m.serverState.Update(*postgresClient.serverID, status, time.Now().UTC().Unix())
+	if len(events) == 0 {
+		return
+	}
+
+	// replica state from before the change no longer applies
+	m.replicationState.Reset(*postgresClient.serverID)
+
+	for _, event := range events {
+		logger.Info("Server event detected", "configName", postgresClient.serverID.ConfigName, "type", event.Type)
+
+		select {
+		case m.serverEventChannel <- event:
+			// sent
+		default:
+			logger.Warn("Dropping server event: channel buffer full")
+		}
+	}
+}
+
+func (m *ServerStateMonitor) FindServerStatus(postgresClient *PostgresClient) (*ServerStatus, error) {
+	var status ServerStatus
+
+	query := "select pg_is_in_recovery(), extract(epoch from pg_postmaster_start_time())::bigint" + postgresMonitorQueryComment()
+	err := postgresClient.client.QueryRow(query).Scan(&status.InRecovery, &status.PostmasterStartedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var permitted bool
+	err = postgresClient.client.QueryRow("select has_function_privilege('pg_control_checkpoint()', 'execute')" + postgresMonitorQueryComment()).Scan(&permitted)
+	if err != nil || !permitted {
+		return &status, nil
+	}
+
+	// on a standby this is the timeline of the last restartpoint
+	err = postgresClient.client.QueryRow("select timeline_id from pg_control_checkpoint()" + postgresMonitorQueryComment()).Scan(&status.TimelineID)
+	if err != nil {
+		logger.Error("Server timeline error", "err", err)
+		errors.Report(err)
+	}
+
+	return &status, nil
+}
+
+// stores the latest server status and returns events for any changes from the previous status
+func (s *ServerState) Update(serverID ServerID, status *ServerStatus, detectedAt int64) []*ServerEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Statuses == nil {
+		s.Statuses = make(map[ServerID]*ServerStatus)
+	}
+
+	previous, ok := s.Statuses[serverID]
+	s.Statuses[serverID] = status
+	if !ok {
+		return []*ServerEvent{}
+	}
+
+	var events []*ServerEvent
+
+	newEvent := func(eventType string, previousValue string, value string) *ServerEvent {
+		return &ServerEvent{
+			ServerID:      &serverID,
+			Type:          eventType,
+			PreviousValue: previousValue,
+			Value:         value,
+			DetectedAt:    detectedAt,
+		}
+	}
+
+	if previous.PostmasterStartedAt != status.PostmasterStartedAt {
+		events = append(events, newEvent(
+			ServerEventTypeRestart,
+			strconv.FormatInt(previous.PostmasterStartedAt, 10),
+			strconv.FormatInt(status.PostmasterStartedAt, 10),
+		))
+	}
+
+	if previous.InRecovery && !status.InRecovery {
+		events = append(events, newEvent(ServerEventTypePromotion, "replica", "primary"))
+	} else if !previous.InRecovery && status.InRecovery {
+		events = append(events, newEvent(ServerEventTypeDemotion, "primary", "replica"))
+	}
+
+	if previous.TimelineID.Valid && status.TimelineID.Valid && previous.TimelineID.Int64 != status.TimelineID.Int64 {
+		events = append(events, newEvent(
+			ServerEventTypeTimelineSwitch,
+			strconv.FormatInt(previous.TimelineID.Int64, 10),
+			strconv.FormatInt(status.TimelineID.Int64, 10),
+		))
+	}
+
+	return events
+}
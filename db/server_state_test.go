@@ -0,0 +1,37 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerStateUpdate(t *testing.T) {
+	state := &ServerState{}
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+
+	// first status is the baseline
+	events := state.Update(serverID, &ServerStatus{InRecovery: true, TimelineID: sql.NullInt64{Valid: true, Int64: 1}, PostmasterStartedAt: 1000}, 2000)
+	assert.Empty(t, events)
+
+	events = state.Update(serverID, &ServerStatus{InRecovery: true, TimelineID: sql.NullInt64{Valid: true, Int64: 1}, PostmasterStartedAt: 1000}, 2030)
+	assert.Empty(t, events)
+
+	// follower is promoted onto a new timeline
+	events = state.Update(serverID, &ServerStatus{InRecovery: false, TimelineID: sql.NullInt64{Valid: true, Int64: 2}, PostmasterStartedAt: 1000}, 2060)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, &ServerEvent{ServerID: &serverID, Type: ServerEventTypePromotion, PreviousValue: "replica", Value: "primary", DetectedAt: 2060}, events[0])
+	assert.Equal(t, &ServerEvent{ServerID: &serverID, Type: ServerEventTypeTimelineSwitch, PreviousValue: "1", Value: "2", DetectedAt: 2060}, events[1])
+
+	// restart without a readable timeline
+	events = state.Update(serverID, &ServerStatus{InRecovery: false, PostmasterStartedAt: 3000}, 3030)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, ServerEventTypeRestart, events[0].Type)
+	assert.Equal(t, "1000", events[0].PreviousValue)
+	assert.Equal(t, "3000", events[0].Value)
+
+	events = state.Update(serverID, &ServerStatus{InRecovery: true, PostmasterStartedAt: 3000}, 3060)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, ServerEventTypeDemotion, events[0].Type)
+}
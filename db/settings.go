@@ -1,11 +1,53 @@
 package db
 
 import (
+	"agent/errors"
 	"agent/logger"
 	"database/sql"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+// all settings collected from a server in a single settings poll
+type ServerSettings struct {
+	ServerID         *ServerID
+	Settings         []*Setting
+	SettingOverrides []*SettingOverride
+	HbaRules         []*HbaRule
+	SettingChanges   []*SettingChange
+}
+
+const (
+	SettingChangeTypeChanged        = "changed"
+	SettingChangeTypeAdded          = "added"
+	SettingChangeTypeRemoved        = "removed"
+	SettingChangeTypePendingRestart = "pending_restart"
 )
 
+// stateful settings object that stores the last settings snapshot per server
+// so that changes can be detected between polls
+type SettingsState struct {
+	// map of server config name + database to settings by name
+	Settings map[ServerID]map[string]*Setting
+	// map of server config name + database to pg_conf_load_time() as epoch seconds
+	ConfLoadTimes map[ServerID]int64
+	mu            sync.Mutex
+}
+
+// a setting change detected between two settings snapshots
+type SettingChange struct {
+	ServerID   *ServerID
+	Name       string
+	Type       string
+	OldValue   sql.NullString
+	NewValue   sql.NullString
+	Source     string
+	DetectedAt int64
+}
+
 // https://www.postgresql.org/docs/10/view-pg-settings.html
 type Setting struct {
 	ServerID       *ServerID
@@ -26,8 +68,47 @@ type Setting struct {
 	MeasuredAt     int64
 }
 
+// role and database level setting overrides from ALTER ROLE ... SET and ALTER DATABASE ... SET
+// https://www.postgresql.org/docs/current/catalog-pg-db-role-setting.html
+type SettingOverride struct {
+	ServerID   *ServerID
+	Role       string // empty when the override applies to all roles
+	Database   string // empty when the override applies to all databases
+	Name       string
+	Value      string
+	MeasuredAt int64
+}
+
+// https://www.postgresql.org/docs/current/view-pg-hba-file-rules.html
+type HbaRule struct {
+	ServerID   *ServerID
+	LineNumber sql.NullInt64
+	Type       sql.NullString
+	Databases  []string
+	Users      []string
+	Address    sql.NullString
+	Netmask    sql.NullString
+	AuthMethod sql.NullString
+	Options    []string
+	Error      sql.NullString
+	MeasuredAt int64
+}
+
+// hba addresses that are safe to report since they don't identify hosts
+var reportableHbaAddresses = []string{"all", "samehost", "samenet", "0.0.0.0", "::"}
+
 type SettingsMonitor struct {
-	settingsChannel chan []*Setting
+	settingsChannel chan *ServerSettings
+	settingsState   *SettingsState
+	logLinePrefixes *LogLinePrefixRegistry
+}
+
+// checks for config reloads and new pending restarts at the regular monitor cadence
+// and re-runs the full settings monitor when one is detected
+type SettingsReloadMonitor struct {
+	settingsChannel chan *ServerSettings
+	settingsState   *SettingsState
+	logLinePrefixes *LogLinePrefixRegistry
 }
 
 func (o *Observer) MonitorSettings() {
@@ -37,13 +118,186 @@ func (o *Observer) MonitorSettings() {
 			postgresClient,
 			&SettingsMonitor{
 				settingsChannel: o.settingsChannel,
+				settingsState:   o.settingsState,
+				logLinePrefixes: o.logLinePrefixes,
 			},
 		).Start()
 	}
 }
 
+func (m *SettingsReloadMonitor) Run(postgresClient *PostgresClient) {
+	confLoadTime, err := FindConfLoadTime(postgresClient)
+	if err != nil {
+		logger.Error("Settings reload error", "err", err)
+		errors.Report(err)
+		return
+	}
+
+	var pendingRestarts int
+	query := "select count(*) from pg_settings where pending_restart" + postgresMonitorQueryComment()
+	err = postgresClient.client.QueryRow(query).Scan(&pendingRestarts)
+	if err != nil {
+		logger.Error("Settings pending restart error", "err", err)
+		errors.Report(err)
+		return
+	}
+
+	if !m.settingsState.ReloadDetected(*postgresClient.serverID, confLoadTime, pendingRestarts) {
+		return
+	}
+
+	logger.Info("Settings reload detected", "configName", postgresClient.serverID.ConfigName)
+
+	settingsMonitor := &SettingsMonitor{
+		settingsChannel: m.settingsChannel,
+		settingsState:   m.settingsState,
+		logLinePrefixes: m.logLinePrefixes,
+	}
+	settingsMonitor.Run(postgresClient)
+}
+
+// returns true if the config was reloaded or more settings are pending a restart
+// since the last settings snapshot
+func (s *SettingsState) ReloadDetected(serverID ServerID, confLoadTime int64, pendingRestarts int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previousSettings, ok := s.Settings[serverID]
+	if !ok {
+		// wait for the first full settings snapshot
+		return false
+	}
+
+	if s.ConfLoadTimes[serverID] != confLoadTime {
+		return true
+	}
+
+	var previousPendingRestarts int
+	for _, setting := range previousSettings {
+		if setting.PendingRestart {
+			previousPendingRestarts++
+		}
+	}
+
+	return pendingRestarts > previousPendingRestarts
+}
+
+// stores the latest settings snapshot and returns the changes from the previous snapshot
+func (s *SettingsState) Update(serverID ServerID, settings []*Setting, confLoadTime int64, detectedAt int64) []*SettingChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Settings == nil {
+		s.Settings = make(map[ServerID]map[string]*Setting)
+	}
+	if s.ConfLoadTimes == nil {
+		s.ConfLoadTimes = make(map[ServerID]int64)
+	}
+
+	latestSettings := make(map[string]*Setting)
+	for _, setting := range settings {
+		latestSettings[setting.Name] = setting
+	}
+
+	previousSettings, ok := s.Settings[serverID]
+
+	s.Settings[serverID] = latestSettings
+	s.ConfLoadTimes[serverID] = confLoadTime
+
+	// the first snapshot is the baseline for future changes
+	if !ok {
+		return []*SettingChange{}
+	}
+
+	var changes []*SettingChange
+
+	for _, setting := range settings {
+		previous, ok := previousSettings[setting.Name]
+		if !ok {
+			changes = append(changes, &SettingChange{
+				ServerID:   &serverID,
+				Name:       setting.Name,
+				Type:       SettingChangeTypeAdded,
+				NewValue:   sql.NullString{Valid: true, String: setting.Value},
+				Source:     setting.Source,
+				DetectedAt: detectedAt,
+			})
+			continue
+		}
+
+		if previous.Value != setting.Value {
+			changes = append(changes, &SettingChange{
+				ServerID:   &serverID,
+				Name:       setting.Name,
+				Type:       SettingChangeTypeChanged,
+				OldValue:   sql.NullString{Valid: true, String: previous.Value},
+				NewValue:   sql.NullString{Valid: true, String: setting.Value},
+				Source:     setting.Source,
+				DetectedAt: detectedAt,
+			})
+		}
+
+		// the new value isn't visible in pg_settings until after the restart
+		if !previous.PendingRestart && setting.PendingRestart {
+			changes = append(changes, &SettingChange{
+				ServerID:   &serverID,
+				Name:       setting.Name,
+				Type:       SettingChangeTypePendingRestart,
+				OldValue:   sql.NullString{Valid: true, String: setting.Value},
+				Source:     setting.Source,
+				DetectedAt: detectedAt,
+			})
+		}
+	}
+
+	for name, previous := range previousSettings {
+		if _, ok := latestSettings[name]; !ok {
+			changes = append(changes, &SettingChange{
+				ServerID:   &serverID,
+				Name:       name,
+				Type:       SettingChangeTypeRemoved,
+				OldValue:   sql.NullString{Valid: true, String: previous.Value},
+				Source:     previous.Source,
+				DetectedAt: detectedAt,
+			})
+		}
+	}
+
+	return changes
+}
+
+func FindConfLoadTime(postgresClient *PostgresClient) (int64, error) {
+	var confLoadTime int64
+	query := "select extract(epoch from pg_conf_load_time())::bigint" + postgresMonitorQueryComment()
+	err := postgresClient.client.QueryRow(query).Scan(&confLoadTime)
+	return confLoadTime, err
+}
+
 func (m *SettingsMonitor) Run(postgresClient *PostgresClient) {
-	settings := m.FindSettings(postgresClient)
+	settings := &ServerSettings{
+		ServerID:         postgresClient.serverID,
+		Settings:         m.FindSettings(postgresClient),
+		SettingOverrides: m.FindSettingOverrides(postgresClient),
+		HbaRules:         m.FindHbaRules(postgresClient),
+	}
+
+	// log parsers use the server's log_line_prefix to parse structured fields
+	for _, setting := range settings.Settings {
+		if setting.Name == "log_line_prefix" {
+			m.logLinePrefixes.Register(postgresClient.serverID, setting.Value)
+		}
+	}
+
+	// skip change detection if settings couldn't be loaded so the next snapshot
+	// isn't compared against an empty one
+	if len(settings.Settings) > 0 {
+		confLoadTime, err := FindConfLoadTime(postgresClient)
+		if err != nil {
+			logger.Error("Settings conf load time error", "err", err)
+			errors.Report(err)
+		}
+		settings.SettingChanges = m.settingsState.Update(*postgresClient.serverID, settings.Settings, confLoadTime, time.Now().UTC().Unix())
+	}
 
 	select {
 	case m.settingsChannel <- settings:
@@ -99,3 +353,128 @@ func (m *SettingsMonitor) FindSettings(postgresClient *PostgresClient) []*Settin
 
 	return settings
 }
+
+func (m *SettingsMonitor) FindSettingOverrides(postgresClient *PostgresClient) []*SettingOverride {
+	query := `select coalesce(r.rolname, '') as role, coalesce(d.datname, '') as database, unnest(s.setconfig) as config
+						from pg_db_role_setting s
+						left join pg_roles r on r.oid = s.setrole
+						left join pg_database d on d.oid = s.setdatabase` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		return []*SettingOverride{}
+	}
+	defer rows.Close()
+
+	var overrides []*SettingOverride
+	measuredAt := time.Now().UTC().Unix()
+
+	for rows.Next() {
+		var override SettingOverride
+		var config string
+
+		err := rows.Scan(&override.Role, &override.Database, &config)
+		if err != nil {
+			continue
+		}
+
+		// config is stored as name=value
+		nameValue := strings.SplitN(config, "=", 2)
+		if len(nameValue) != 2 {
+			continue
+		}
+
+		override.Name = nameValue[0]
+		override.Value = nameValue[1]
+		override.MeasuredAt = measuredAt
+		override.ServerID = postgresClient.serverID
+
+		overrides = append(overrides, &override)
+	}
+
+	return overrides
+}
+
+// pg_hba_file_rules is only readable by superusers by default so this
+// returns no rules for most roles
+func (m *SettingsMonitor) FindHbaRules(postgresClient *PostgresClient) []*HbaRule {
+	var permitted bool
+	err := postgresClient.client.QueryRow("select has_function_privilege('pg_hba_file_rules()', 'execute')" + postgresMonitorQueryComment()).Scan(&permitted)
+	if err != nil || !permitted {
+		return []*HbaRule{}
+	}
+
+	query := `select line_number, type, database, user_name, address, netmask, auth_method, options, error
+						from pg_hba_file_rules` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		return []*HbaRule{}
+	}
+	defer rows.Close()
+
+	var rules []*HbaRule
+	measuredAt := time.Now().UTC().Unix()
+
+	for rows.Next() {
+		var rule HbaRule
+		var databases pgtype.TextArray
+		var users pgtype.TextArray
+		var options pgtype.TextArray
+
+		err := rows.Scan(
+			&rule.LineNumber,
+			&rule.Type,
+			&databases,
+			&users,
+			&rule.Address,
+			&rule.Netmask,
+			&rule.AuthMethod,
+			&options,
+			&rule.Error,
+		)
+		if err != nil {
+			continue
+		}
+
+		rule.Databases = textArrayToStrings(databases)
+		rule.Users = textArrayToStrings(users)
+		rule.Options = redactHbaOptions(textArrayToStrings(options))
+		rule.Address = redactHbaAddress(rule.Address)
+		rule.MeasuredAt = measuredAt
+		rule.ServerID = postgresClient.serverID
+
+		rules = append(rules, &rule)
+	}
+
+	return rules
+}
+
+// we do not want to expose hostnames or IPs so only wildcard addresses are reported as is
+func redactHbaAddress(address sql.NullString) sql.NullString {
+	if !address.Valid {
+		return address
+	}
+
+	for _, reportable := range reportableHbaAddresses {
+		if address.String == reportable {
+			return address
+		}
+	}
+
+	return sql.NullString{Valid: true, String: RedactedString}
+}
+
+// auth options can contain credentials - ex. ldapbindpasswd=secret
+func redactHbaOptions(options []string) []string {
+	var redacted []string
+	for _, option := range options {
+		nameValue := strings.SplitN(option, "=", 2)
+		name := strings.ToLower(nameValue[0])
+		if len(nameValue) == 2 && (strings.Contains(name, "passwd") || strings.Contains(name, "password") || strings.Contains(name, "secret")) {
+			option = nameValue[0] + "=" + RedactedString
+		}
+		redacted = append(redacted, option)
+	}
+	return redacted
+}
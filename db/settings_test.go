@@ -0,0 +1,75 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactHbaAddress(t *testing.T) {
+	assert.Equal(t, sql.NullString{}, redactHbaAddress(sql.NullString{}))
+	assert.Equal(t, sql.NullString{Valid: true, String: "all"}, redactHbaAddress(sql.NullString{Valid: true, String: "all"}))
+	assert.Equal(t, sql.NullString{Valid: true, String: "0.0.0.0"}, redactHbaAddress(sql.NullString{Valid: true, String: "0.0.0.0"}))
+	assert.Equal(t, sql.NullString{Valid: true, String: RedactedString}, redactHbaAddress(sql.NullString{Valid: true, String: "10.0.1.12"}))
+	assert.Equal(t, sql.NullString{Valid: true, String: RedactedString}, redactHbaAddress(sql.NullString{Valid: true, String: "db.internal.example.com"}))
+}
+
+func TestRedactHbaOptions(t *testing.T) {
+	options := redactHbaOptions([]string{"ldapserver=ldap.example.com", "ldapbindpasswd=secret", "clientcert=verify-full"})
+	assert.Equal(t, []string{"ldapserver=ldap.example.com", "ldapbindpasswd=" + RedactedString, "clientcert=verify-full"}, options)
+	assert.Empty(t, redactHbaOptions([]string{}))
+}
+
+func TestSettingsStateUpdate(t *testing.T) {
+	state := &SettingsState{}
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+
+	changes := state.Update(serverID, []*Setting{
+		{Name: "work_mem", Value: "4096", Source: "default"},
+		{Name: "shared_buffers", Value: "16384", Source: "configuration file"},
+		{Name: "old_setting", Value: "on", Source: "default"},
+	}, 100, 1000)
+	// first snapshot is the baseline
+	assert.Empty(t, changes)
+
+	changes = state.Update(serverID, []*Setting{
+		{Name: "work_mem", Value: "16384", Source: "configuration file"},
+		{Name: "shared_buffers", Value: "16384", Source: "configuration file", PendingRestart: true},
+		{Name: "new_setting", Value: "off", Source: "default"},
+	}, 200, 2000)
+	assert.Equal(t, 4, len(changes))
+
+	assert.Equal(t, "work_mem", changes[0].Name)
+	assert.Equal(t, SettingChangeTypeChanged, changes[0].Type)
+	assert.Equal(t, "4096", changes[0].OldValue.String)
+	assert.Equal(t, "16384", changes[0].NewValue.String)
+	assert.Equal(t, "configuration file", changes[0].Source)
+	assert.Equal(t, int64(2000), changes[0].DetectedAt)
+
+	assert.Equal(t, "shared_buffers", changes[1].Name)
+	assert.Equal(t, SettingChangeTypePendingRestart, changes[1].Type)
+
+	assert.Equal(t, "new_setting", changes[2].Name)
+	assert.Equal(t, SettingChangeTypeAdded, changes[2].Type)
+
+	assert.Equal(t, "old_setting", changes[3].Name)
+	assert.Equal(t, SettingChangeTypeRemoved, changes[3].Type)
+	assert.Equal(t, "on", changes[3].OldValue.String)
+
+	assert.Equal(t, int64(200), state.ConfLoadTimes[serverID])
+}
+
+func TestSettingsStateReloadDetected(t *testing.T) {
+	state := &SettingsState{}
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+
+	// no snapshot yet
+	assert.False(t, state.ReloadDetected(serverID, 100, 0))
+
+	state.Update(serverID, []*Setting{{Name: "shared_buffers", Value: "16384"}}, 100, 1000)
+
+	assert.False(t, state.ReloadDetected(serverID, 100, 0))
+	assert.True(t, state.ReloadDetected(serverID, 200, 0))
+	assert.True(t, state.ReloadDetected(serverID, 100, 1))
+}
@@ -1,6 +1,9 @@
 package db
 
-import "agent/logger"
+import (
+	"agent/logger"
+	"time"
+)
 
 type SlowQuery struct {
 	SqlErrorCode     string
@@ -12,6 +15,7 @@ type SlowQuery struct {
 	Explain          string
 	Fingerprint      string
 	ServerConfigName string
+	Fields           LogFields
 	MeasuredAt       int64
 }
 
@@ -20,66 +24,93 @@ func (o *Observer) MonitorSlowQueries() {
 	for {
 		select {
 		case slowQuery := <-o.rawSlowQueryChannel:
-			// parse out comment
-			parsedComment := parseComment(slowQuery.Raw)
-			slowQuery.Comment = parsedComment.Comment
-
-			// skip any agent query if configured to
-			if !o.config.MonitorAgentQueries && isAgentQueryComment(parsedComment.Comment) {
-				return
-			}
+			o.handleSlowQuery(slowQuery)
+		}
+	}
+}
 
-			slowQuery.Raw = parsedComment.Query
+func (o *Observer) handleSlowQuery(slowQuery *SlowQuery) {
+	// parse out comment
+	parsedComment := parseComment(slowQuery.Raw)
+	slowQuery.Comment = parsedComment.Comment
 
-			slowQuery.Obfuscated = o.obfuscator.ObfuscateQuery(slowQuery.Raw)
-			// collapse spaces and clean chars after obfuscating but before fingerprinting
-			// we obfuscate first to not collapse any run of spaces in a query string
-			// although it shouldn't matter too much since we don't report raw query params
-			slowQuery.Obfuscated = CleanQuery(slowQuery.Obfuscated)
-			slowQuery.Fingerprint = fingerprintQuery(slowQuery.Obfuscated)
+	// skip any agent query if configured to
+	if !o.config.MonitorAgentQueries && isAgentQueryComment(parsedComment.Comment) {
+		return
+	}
 
-			// if query is more than 5000 chars then truncate and add TRUNCATED suffix
-			if len(slowQuery.Obfuscated) > 5000 {
-				slowQuery.Obfuscated = TruncateQuery(slowQuery.Obfuscated)
-			}
+	slowQuery.Raw = parsedComment.Query
 
-			// find postgres client and serverId for query using config name
-			var serverID *ServerID
-			var postgresClient *PostgresClient
-			for _, client := range o.postgresClients {
-				if client.serverID.ConfigName == slowQuery.ServerConfigName {
-					serverID = client.serverID
-					postgresClient = client
-					break
-				}
-			}
+	slowQuery.Obfuscated = o.obfuscator.ObfuscateQuery(slowQuery.Raw)
+	// collapse spaces and clean chars after obfuscating but before fingerprinting
+	// we obfuscate first to not collapse any run of spaces in a query string
+	// although it shouldn't matter too much since we don't report raw query params
+	slowQuery.Obfuscated = CleanQuery(slowQuery.Obfuscated)
+	slowQuery.Fingerprint = fingerprintQuery(slowQuery.Obfuscated)
 
-			// obfuscate explains since the raw explain can contain query inputs
-			explain := o.explainer.Explain(postgresClient, slowQuery)
-			if len(explain) > 0 {
-				slowQuery.Explain = o.obfuscator.ObfuscateExplain(explain)
-				// if explain is empty, then the query was already explained the last hour
-				logger.Debug("Slow Query", "duration_ms", slowQuery.DurationMs, "query", slowQuery.Raw, "obfuscated", slowQuery.Obfuscated, "fingerprint", slowQuery.Fingerprint, "explain", explain, "obfuscated_explain", slowQuery.Explain, "measured_at", slowQuery.MeasuredAt)
-			}
+	// if query is more than 5000 chars then truncate and add TRUNCATED suffix
+	if len(slowQuery.Obfuscated) > 5000 {
+		slowQuery.Obfuscated = TruncateQuery(slowQuery.Obfuscated)
+	}
 
-			// report slow query stats
-			slowQueryStats := &QueryStats{
-				ServerID:    serverID,
-				Fingerprint: slowQuery.Fingerprint,
-				Query:       slowQuery.Obfuscated,
-				Explain:     slowQuery.Explain,
-				Calls:       1,
-				TotalTime:   slowQuery.DurationMs,
-				MinTime:     slowQuery.DurationMs,
-				MaxTime:     slowQuery.DurationMs,
-				MeasuredAt:  slowQuery.MeasuredAt,
-			}
-			select {
-			case o.queryStatsChannel <- []*QueryStats{slowQueryStats}:
-				// sent
-			default:
-				logger.Warn("Dropping query stats: channel buffer full")
-			}
+	// find postgres client and serverId for query using config name
+	var serverID *ServerID
+	var postgresClient *PostgresClient
+	for _, client := range o.postgresClients {
+		if client.MatchesConfigName(slowQuery.ServerConfigName) {
+			serverID = client.serverID
+			postgresClient = client
+			break
 		}
 	}
+
+	configName := slowQuery.ServerConfigName
+	if serverID != nil {
+		configName = serverID.ConfigName
+	}
+
+	// give auto_explain a chance to log the plan before running EXPLAIN
+	if o.explainer.AutoExplainActive(configName) {
+		time.AfterFunc(loggedPlanWait, func() {
+			o.explainSlowQuery(slowQuery, postgresClient, configName)
+			o.reportSlowQuery(slowQuery, serverID)
+		})
+		return
+	}
+
+	o.explainSlowQuery(slowQuery, postgresClient, configName)
+	o.reportSlowQuery(slowQuery, serverID)
+}
+
+func (o *Observer) explainSlowQuery(slowQuery *SlowQuery, postgresClient *PostgresClient, configName string) {
+	// plans logged by auto_explain are the plans actually used so prefer them to running EXPLAIN
+	if loggedPlan := o.explainer.TakeLoggedPlan(configName, slowQuery.Fingerprint); loggedPlan != "" {
+		slowQuery.Explain = loggedPlan
+	} else if explain := o.explainer.Explain(postgresClient, slowQuery); len(explain) > 0 {
+		// obfuscate explains since the raw explain can contain query inputs
+		slowQuery.Explain = o.obfuscator.ObfuscateExplain(explain)
+		// if explain is empty, then the query was already explained the last hour
+		logger.Debug("Slow Query", "duration_ms", slowQuery.DurationMs, "query", slowQuery.Raw, "obfuscated", slowQuery.Obfuscated, "fingerprint", slowQuery.Fingerprint, "explain", explain, "obfuscated_explain", slowQuery.Explain, "measured_at", slowQuery.MeasuredAt)
+	}
+}
+
+// report slow query stats
+func (o *Observer) reportSlowQuery(slowQuery *SlowQuery, serverID *ServerID) {
+	slowQueryStats := &QueryStats{
+		ServerID:    serverID,
+		Fingerprint: slowQuery.Fingerprint,
+		Query:       slowQuery.Obfuscated,
+		Explain:     slowQuery.Explain,
+		Calls:       1,
+		TotalTime:   slowQuery.DurationMs,
+		MinTime:     slowQuery.DurationMs,
+		MaxTime:     slowQuery.DurationMs,
+		MeasuredAt:  slowQuery.MeasuredAt,
+	}
+	select {
+	case o.queryStatsChannel <- []*QueryStats{slowQueryStats}:
+		// sent
+	default:
+		logger.Warn("Dropping query stats: channel buffer full")
+	}
 }
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"agent/errors"
+	"agent/logger"
+	"database/sql"
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgtype"
+)
+
+const (
+	// autoanalyze should have run well before modified rows reach this multiple of the threshold
+	// modified rows can pass the threshold while autoanalyze waits for autovacuum_naptime or a free
+	// worker so tables are only flagged once they're clearly behind instead of between runs
+	staleStatisticsThresholdFactor = 2
+)
+
+// extended statistics objects created with CREATE STATISTICS
+type ExtendedStatistic struct {
+	Name      string
+	Schema    string
+	TableName string
+	Columns   []string
+	Kinds     []string // d (ndistinct), f (dependencies), m (mcv) and / or e (expressions)
+}
+
+// per column planner statistics from pg_stats
+// most common values and histogram bounds are never collected since they contain table data
+type ColumnStatistic struct {
+	Schema         string
+	TableName      string
+	Name           string
+	NullFraction   sql.NullFloat64
+	DistinctValues sql.NullFloat64 // negative values are a fraction of the row count
+	Correlation    sql.NullFloat64
+}
+
+// global autoanalyze settings that tables fall back to without storage option overrides
+type AutoanalyzeSettings struct {
+	Enabled     bool // autovacuum - autoanalyze never runs when off even if a table enables it
+	Threshold   int64
+	ScaleFactor float64
+}
+
+// identifies a column when merging in its planner statistics
+type columnKey struct {
+	schema string
+	table  string
+	name   string
+}
+
+func (m *SchemaMonitor) FindAutoanalyzeSettings(postgresClient *PostgresClient) *AutoanalyzeSettings {
+	query := `select current_setting('autovacuum')::bool,
+						current_setting('autovacuum_analyze_threshold')::bigint,
+						current_setting('autovacuum_analyze_scale_factor')::float8` + postgresMonitorQueryComment()
+
+	var settings AutoanalyzeSettings
+	err := postgresClient.client.QueryRow(query).Scan(&settings.Enabled, &settings.Threshold, &settings.ScaleFactor)
+	if err != nil {
+		logger.Error("Find autoanalyze settings error", "err", err)
+		errors.Report(err)
+		return nil
+	}
+
+	return &settings
+}
+
+func (m *SchemaMonitor) FindExtendedStatistics(postgresClient *PostgresClient) []*ExtendedStatistic {
+	query := `select stx.stxname, nsp.nspname, tbl.relname,
+						array(select attname from pg_attribute where attrelid = stx.stxrelid and attnum = any(stx.stxkeys) order by attnum),
+						stx.stxkind::text[]
+						from pg_statistic_ext stx
+						join pg_class tbl on tbl.oid = stx.stxrelid
+						join pg_namespace nsp on nsp.oid = tbl.relnamespace
+						where nsp.nspname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find extended statistics error", "err", err)
+		errors.Report(err)
+		return []*ExtendedStatistic{}
+	}
+	defer rows.Close()
+
+	var statistics []*ExtendedStatistic
+
+	for rows.Next() {
+		var statistic ExtendedStatistic
+		var columns pgtype.TextArray
+		var kinds pgtype.TextArray
+
+		err := rows.Scan(
+			&statistic.Name,
+			&statistic.Schema,
+			&statistic.TableName,
+			&columns,
+			&kinds,
+		)
+		if err != nil {
+			logger.Error("Find extended statistics error", "err", err)
+			errors.Report(err)
+			continue
+		}
+
+		statistic.Columns = textArrayToStrings(columns)
+		statistic.Kinds = textArrayToStrings(kinds)
+
+		statistics = append(statistics, &statistic)
+	}
+
+	return statistics
+}
+
+// pg_stats only returns columns the current user can read
+func (m *SchemaMonitor) FindColumnStatistics(postgresClient *PostgresClient) []*ColumnStatistic {
+	query := `select schemaname, tablename, attname, null_frac, n_distinct, correlation
+						from pg_stats where not inherited
+						and schemaname not in ('pg_catalog', 'information_schema', 'pg_toast', 'heroku_ext')` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Find column statistics error", "err", err)
+		errors.Report(err)
+		return []*ColumnStatistic{}
+	}
+	defer rows.Close()
+
+	var statistics []*ColumnStatistic
+
+	for rows.Next() {
+		var statistic ColumnStatistic
+
+		err := rows.Scan(
+			&statistic.Schema,
+			&statistic.TableName,
+			&statistic.Name,
+			&statistic.NullFraction,
+			&statistic.DistinctValues,
+			&statistic.Correlation,
+		)
+		if err != nil {
+			logger.Error("Find column statistics error", "err", err)
+			errors.Report(err)
+			continue
+		}
+
+		statistics = append(statistics, &statistic)
+	}
+
+	return statistics
+}
+
+// looks up each statistic's column by schema, table and name
+func mergeColumnStatistics(columns []*Column, statistics []*ColumnStatistic) {
+	columnsByKey := make(map[columnKey]*Column, len(columns))
+	for _, column := range columns {
+		columnsByKey[columnKey{column.Schema, column.TableName, column.Name}] = column
+	}
+
+	for _, statistic := range statistics {
+		column, ok := columnsByKey[columnKey{statistic.Schema, statistic.TableName, statistic.Name}]
+		if !ok {
+			continue
+		}
+
+		column.NullFraction = statistic.NullFraction
+		column.DistinctValues = statistic.DistinctValues
+		column.Correlation = statistic.Correlation
+	}
+}
+
+// autoanalyze runs once modified rows exceed threshold + scale factor * rows
+// using per table storage options over the global settings
+func autoanalyzeThreshold(table *Table, settings *AutoanalyzeSettings) int64 {
+	threshold := float64(settings.Threshold)
+	scaleFactor := settings.ScaleFactor
+
+	if value, err := strconv.ParseFloat(table.StorageOptions["autovacuum_analyze_threshold"], 64); err == nil {
+		threshold = value
+	}
+	if value, err := strconv.ParseFloat(table.StorageOptions["autovacuum_analyze_scale_factor"], 64); err == nil {
+		scaleFactor = value
+	}
+
+	return int64(math.Round(threshold + scaleFactor*float64(table.LiveRowEstimateTotal)))
+}
+
+// statistics are stale when autoanalyze has fallen behind or is disabled for a table with modified rows
+func statisticsStale(table *Table, settings *AutoanalyzeSettings) bool {
+	if table.ModifiedRowsSinceAnalyze == 0 {
+		return false
+	}
+
+	disabled := !settings.Enabled || table.StorageOptions["autovacuum_enabled"] == "false"
+	if disabled && table.ModifiedRowsSinceAnalyze > table.AutoanalyzeThreshold {
+		return true
+	}
+
+	return table.ModifiedRowsSinceAnalyze > staleStatisticsThresholdFactor*table.AutoanalyzeThreshold
+}
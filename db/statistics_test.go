@@ -0,0 +1,71 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoanalyzeThreshold(t *testing.T) {
+	settings := &AutoanalyzeSettings{Threshold: 50, ScaleFactor: 0.1}
+
+	table := &Table{LiveRowEstimateTotal: 100000}
+	assert.Equal(t, int64(10050), autoanalyzeThreshold(table, settings))
+
+	// per table settings override global settings
+	table.StorageOptions = map[string]string{
+		"autovacuum_analyze_threshold":    "1000",
+		"autovacuum_analyze_scale_factor": "0.01",
+	}
+	assert.Equal(t, int64(2000), autoanalyzeThreshold(table, settings))
+}
+
+func TestStatisticsStale(t *testing.T) {
+	settings := &AutoanalyzeSettings{Enabled: true, Threshold: 50, ScaleFactor: 0.1}
+
+	table := &Table{AutoanalyzeThreshold: 1000, ModifiedRowsSinceAnalyze: 1500}
+	assert.False(t, statisticsStale(table, settings))
+
+	table.ModifiedRowsSinceAnalyze = 2500
+	assert.True(t, statisticsStale(table, settings))
+
+	// autoanalyze will never run
+	table.ModifiedRowsSinceAnalyze = 1500
+	table.StorageOptions = map[string]string{"autovacuum_enabled": "false"}
+	assert.True(t, statisticsStale(table, settings))
+
+	assert.False(t, statisticsStale(&Table{}, settings))
+}
+
+func TestStatisticsStaleAutovacuumDisabled(t *testing.T) {
+	settings := &AutoanalyzeSettings{Enabled: false, Threshold: 50, ScaleFactor: 0.1}
+
+	table := &Table{AutoanalyzeThreshold: 1000, ModifiedRowsSinceAnalyze: 1500}
+	assert.True(t, statisticsStale(table, settings))
+
+	// a table can't enable autoanalyze when autovacuum is off
+	table.StorageOptions = map[string]string{"autovacuum_enabled": "true"}
+	assert.True(t, statisticsStale(table, settings))
+
+	table.ModifiedRowsSinceAnalyze = 500
+	assert.False(t, statisticsStale(table, settings))
+}
+
+func TestMergeColumnStatistics(t *testing.T) {
+	id := &Column{Schema: "public", TableName: "users", Name: "id"}
+	email := &Column{Schema: "public", TableName: "users", Name: "email"}
+	otherID := &Column{Schema: "other", TableName: "users", Name: "id"}
+
+	mergeColumnStatistics([]*Column{id, email, otherID}, []*ColumnStatistic{
+		{Schema: "public", TableName: "users", Name: "id", DistinctValues: sql.NullFloat64{Float64: -1, Valid: true}},
+		{Schema: "public", TableName: "users", Name: "email", NullFraction: sql.NullFloat64{Float64: 0.25, Valid: true}},
+		{Schema: "public", TableName: "missing", Name: "id", NullFraction: sql.NullFloat64{Float64: 1, Valid: true}},
+	})
+
+	assert.Equal(t, sql.NullFloat64{Float64: -1, Valid: true}, id.DistinctValues)
+	assert.Equal(t, sql.NullFloat64{Float64: 0.25, Valid: true}, email.NullFraction)
+	assert.False(t, email.DistinctValues.Valid)
+	assert.False(t, otherID.DistinctValues.Valid)
+	assert.False(t, otherID.NullFraction.Valid)
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"agent/errors"
+	"agent/logger"
+	"agent/util"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// stateful storage object that stores the last size sample per server and entity
+// so that growth rates can be calculated between polls
+type StorageState struct {
+	// map of server config name + database to size samples by metric entity
+	Samples map[ServerID]map[string]*StorageSample
+	mu      sync.Mutex
+}
+
+type StorageSample struct {
+	Bytes      float64
+	MeasuredAt int64
+}
+
+// tracks database, tablespace and wal directory sizes and their growth rates
+type StorageMonitor struct {
+	metricsChannel chan []*Metric
+	storageState   *StorageState
+}
+
+func (o *Observer) MonitorStorage() {
+	for _, postgresClient := range o.postgresClients {
+		go NewMonitorWorker(
+			o.config,
+			postgresClient,
+			&StorageMonitor{
+				metricsChannel: o.metricsChannel,
+				storageState:   o.storageState,
+			},
+		).Start()
+	}
+}
+
+func (m *StorageMonitor) Run(postgresClient *PostgresClient) {
+	now := time.Now().UTC().Unix()
+
+	var metrics []*Metric
+	metrics = append(metrics, m.FindDatabaseSizeMetrics(postgresClient, now)...)
+	metrics = append(metrics, m.FindTablespaceSizeMetrics(postgresClient, now)...)
+	metrics = append(metrics, m.FindWalDirectoryMetrics(postgresClient, now)...)
+
+	select {
+	case m.metricsChannel <- metrics:
+		// sent
+	default:
+		logger.Warn("Dropping storage metrics: channel buffer full")
+	}
+}
+
+func (m *StorageMonitor) FindDatabaseSizeMetrics(postgresClient *PostgresClient, now int64) []*Metric {
+	// pg_database_size requires connect privileges on the database
+	query := `select datname, case when has_database_privilege(oid, 'connect') then pg_database_size(oid) end as bytes
+						from pg_database where not datistemplate and datallowconn` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Database size metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+	defer rows.Close()
+
+	var metrics []*Metric
+
+	for rows.Next() {
+		var name string
+		var bytes sql.NullInt64
+
+		err := rows.Scan(&name, &bytes)
+		if err != nil || !bytes.Valid {
+			continue
+		}
+
+		metrics = append(metrics, m.sizeMetrics("storage.database", float64(bytes.Int64), "database/"+name, *postgresClient.serverID, now)...)
+	}
+
+	return metrics
+}
+
+func (m *StorageMonitor) FindTablespaceSizeMetrics(postgresClient *PostgresClient, now int64) []*Metric {
+	// pg_tablespace_size requires create privileges unless it's the current database's default tablespace
+	query := `select spcname, case when has_tablespace_privilege(oid, 'create')
+							or oid = (select dattablespace from pg_database where datname = current_database())
+							then pg_tablespace_size(oid) end as bytes
+						from pg_tablespace` + postgresMonitorQueryComment()
+
+	rows, err := postgresClient.client.Query(query)
+	if err != nil {
+		logger.Error("Tablespace size metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+	defer rows.Close()
+
+	var metrics []*Metric
+
+	for rows.Next() {
+		var name string
+		var bytes sql.NullInt64
+
+		err := rows.Scan(&name, &bytes)
+		if err != nil || !bytes.Valid {
+			continue
+		}
+
+		metrics = append(metrics, m.sizeMetrics("storage.tablespace", float64(bytes.Int64), "tablespace/"+name, *postgresClient.serverID, now)...)
+	}
+
+	return metrics
+}
+
+// pg_ls_waldir is available in postgres 10+ to superusers and pg_monitor members
+func (m *StorageMonitor) FindWalDirectoryMetrics(postgresClient *PostgresClient, now int64) []*Metric {
+	var permitted bool
+	err := postgresClient.client.QueryRow("select has_function_privilege('pg_ls_waldir()', 'execute')" + postgresMonitorQueryComment()).Scan(&permitted)
+	if err != nil || !permitted {
+		return []*Metric{}
+	}
+
+	var files float64
+	var bytes float64
+
+	query := "select count(*), coalesce(sum(size), 0) from pg_ls_waldir()" + postgresMonitorQueryComment()
+	err = postgresClient.client.QueryRow(query).Scan(&files, &bytes)
+	if err != nil {
+		logger.Error("WAL directory metrics error", "err", err)
+		errors.Report(err)
+		return []*Metric{}
+	}
+
+	metrics := []*Metric{
+		NewMetric("storage.wal.files", files, "", *postgresClient.serverID, now),
+	}
+	return append(metrics, m.sizeMetrics("storage.wal", bytes, "", *postgresClient.serverID, now)...)
+}
+
+// returns the size metric and a growth metric in bytes per hour once there are two samples
+func (m *StorageMonitor) sizeMetrics(name string, bytes float64, entity string, serverID ServerID, now int64) []*Metric {
+	metrics := []*Metric{
+		NewMetric(name+".bytes", bytes, entity, serverID, now),
+	}
+
+	growth, ok := m.storageState.Growth(serverID, name+"/"+entity, bytes, now)
+	if ok {
+		metrics = append(metrics, NewMetric(name+".growth", growth, entity, serverID, now))
+	}
+
+	return metrics
+}
+
+// stores the latest size sample and returns the growth in bytes per hour since the previous sample
+func (s *StorageState) Growth(serverID ServerID, key string, bytes float64, measuredAt int64) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Samples == nil {
+		s.Samples = make(map[ServerID]map[string]*StorageSample)
+	}
+	if s.Samples[serverID] == nil {
+		s.Samples[serverID] = make(map[string]*StorageSample)
+	}
+
+	previous, ok := s.Samples[serverID][key]
+	s.Samples[serverID][key] = &StorageSample{Bytes: bytes, MeasuredAt: measuredAt}
+
+	if !ok || measuredAt <= previous.MeasuredAt {
+		return 0, false
+	}
+
+	hours := float64(measuredAt-previous.MeasuredAt) / 3600
+	return util.Round((bytes - previous.Bytes) / hours), true
+}
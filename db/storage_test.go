@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageStateGrowth(t *testing.T) {
+	state := &StorageState{}
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+
+	// first sample has no growth
+	_, ok := state.Growth(serverID, "storage.database/database/test", 1000000, 1000)
+	assert.False(t, ok)
+
+	// 30000 bytes in 5 minutes
+	growth, ok := state.Growth(serverID, "storage.database/database/test", 1030000, 1300)
+	assert.True(t, ok)
+	assert.Equal(t, float64(360000), growth)
+
+	// shrinking is negative growth
+	growth, ok = state.Growth(serverID, "storage.database/database/test", 1000000, 1600)
+	assert.True(t, ok)
+	assert.Equal(t, float64(-360000), growth)
+
+	// samples are tracked per key
+	_, ok = state.Growth(serverID, "storage.wal/", 1000, 1600)
+	assert.False(t, ok)
+}
+
+func TestStorageMonitorSizeMetrics(t *testing.T) {
+	monitor := &StorageMonitor{storageState: &StorageState{}}
+	serverID := ServerID{ConfigName: "GREEN", ConfigVarName: "GREEN_URL", Database: "test"}
+
+	metrics := monitor.sizeMetrics("storage.tablespace", 2000, "tablespace/pg_default", serverID, 1000)
+	assert.Equal(t, 1, len(metrics))
+	assert.Equal(t, "storage.tablespace.bytes", metrics[0].Name)
+	assert.Equal(t, "tablespace/pg_default", metrics[0].Entity)
+
+	metrics = monitor.sizeMetrics("storage.tablespace", 5600, "tablespace/pg_default", serverID, 4600)
+	assert.Equal(t, 2, len(metrics))
+	assert.Equal(t, "storage.tablespace.growth", metrics[1].Name)
+	assert.Equal(t, float64(3600), metrics[1].Value)
+}
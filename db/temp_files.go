@@ -0,0 +1,158 @@
+package db
+
+import (
+	"agent/logger"
+	"sort"
+)
+
+const (
+	// postgres block size used by pg_stat_statements temp block counts
+	tempBlockBytes = 8192
+
+	// max number of spilling queries reported per server
+	MaxTempSpills = 10
+)
+
+// a temp file logged by postgres when log_temp_files is enabled
+// ex. LOG:  temporary file: path "base/pgsql_tmp/pgsql_tmp12345.0", size 104857600
+type TempFile struct {
+	ServerConfigName string
+	ServerID         *ServerID
+	SizeBytes        int64
+	Raw              string // raw STATEMENT logged with the temp file
+	Obfuscated       string
+	Fingerprint      string
+	Fields           LogFields
+	MeasuredAt       int64
+}
+
+// temp file spills attributed to a query fingerprint
+type TempSpill struct {
+	Fingerprint      string
+	Query            string
+	Calls            int64
+	TempBytesWritten int64 // from pg_stat_statements temp_blks_written
+	TempFiles        int64 // from temp file log lines
+	TempFileBytes    int64
+	MaxTempFileBytes int64
+
+	// the smallest power of two work_mem that would have kept the largest spill in memory
+	RecommendedWorkMemBytes int64
+}
+
+// runs forever
+func (o *Observer) MonitorTempFiles() {
+	for {
+		select {
+		case tempFile := <-o.rawTempFileChannel:
+			// temp files logged without a statement can't be attributed to a query
+			if tempFile.Raw == "" {
+				continue
+			}
+
+			parsedComment := parseComment(tempFile.Raw)
+			tempFile.Raw = parsedComment.Query
+
+			tempFile.Obfuscated, tempFile.Fingerprint = o.obfuscateAndFingerprint(tempFile.Raw)
+
+			tempFile.ServerID = o.serverIDForConfigName(tempFile.ServerConfigName)
+
+			select {
+			case o.tempFileChannel <- tempFile:
+				// sent
+			default:
+				logger.Warn("Dropping temp file: channel buffer full")
+			}
+		}
+	}
+}
+
+// returns the queries that spilled the most to temp files using pg_stat_statements temp blocks written
+// and any logged temp files for the same fingerprint
+func TopTempSpills(stats []QueryStats, tempFiles []TempFile, limit int) []*TempSpill {
+	spills := make(map[string]*TempSpill)
+
+	spillFor := func(fingerprint string, query string) *TempSpill {
+		spill, ok := spills[fingerprint]
+		if !ok {
+			spill = &TempSpill{Fingerprint: fingerprint, Query: query}
+			spills[fingerprint] = spill
+		}
+		return spill
+	}
+
+	for _, stat := range stats {
+		if stat.TempBlocksWritten <= 0 || stat.Fingerprint == "" {
+			continue
+		}
+
+		spill := spillFor(stat.Fingerprint, stat.Query)
+		spill.Calls += stat.Calls
+		spill.TempBytesWritten += stat.TempBlocksWritten * tempBlockBytes
+	}
+
+	for _, tempFile := range tempFiles {
+		if tempFile.Fingerprint == "" {
+			continue
+		}
+
+		spill := spillFor(tempFile.Fingerprint, tempFile.Obfuscated)
+		spill.TempFiles++
+		spill.TempFileBytes += tempFile.SizeBytes
+		if tempFile.SizeBytes > spill.MaxTempFileBytes {
+			spill.MaxTempFileBytes = tempFile.SizeBytes
+		}
+	}
+
+	var sorted []*TempSpill
+	for _, spill := range spills {
+		spill.RecommendedWorkMemBytes = recommendedWorkMemBytes(spill)
+		sorted = append(sorted, spill)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if spillBytes(sorted[i]) == spillBytes(sorted[j]) {
+			return sorted[i].Fingerprint < sorted[j].Fingerprint
+		}
+		return spillBytes(sorted[i]) > spillBytes(sorted[j])
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
+
+// pg_stat_statements and temp file logs overlap so use whichever saw more
+func spillBytes(spill *TempSpill) int64 {
+	if spill.TempFileBytes > spill.TempBytesWritten {
+		return spill.TempFileBytes
+	}
+	return spill.TempBytesWritten
+}
+
+// a single logged temp file is the best estimate of a sort or hash that didn't fit in memory
+// otherwise fall back to the average temp bytes written per call
+func recommendedWorkMemBytes(spill *TempSpill) int64 {
+	var bytes int64
+	if spill.MaxTempFileBytes > 0 {
+		bytes = spill.MaxTempFileBytes
+	} else if spill.Calls > 0 {
+		bytes = spill.TempBytesWritten / spill.Calls
+	}
+
+	if bytes <= 0 {
+		return 0
+	}
+
+	return nextPowerOfTwo(bytes)
+}
+
+func nextPowerOfTwo(n int64) int64 {
+	power := int64(1)
+	for power < n {
+		power *= 2
+	}
+	return power
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopTempSpills(t *testing.T) {
+	stats := []QueryStats{
+		{Fingerprint: "sort", Query: "select * from users order by name", Calls: 4, TempBlocksWritten: 2048},
+		{Fingerprint: "hash", Query: "select * from a join b using (id)", Calls: 1, TempBlocksWritten: 128},
+		{Fingerprint: "fast", Query: "select 1", Calls: 100},
+	}
+	tempFiles := []TempFile{
+		{Fingerprint: "sort", Obfuscated: "select * from users order by name", SizeBytes: 3000000},
+		{Fingerprint: "sort", Obfuscated: "select * from users order by name", SizeBytes: 5000000},
+		{Fingerprint: "logged", Obfuscated: "select * from events order by id", SizeBytes: 1000},
+	}
+
+	spills := TopTempSpills(stats, tempFiles, 10)
+
+	assert.Equal(t, 3, len(spills))
+
+	assert.Equal(t, "sort", spills[0].Fingerprint)
+	assert.Equal(t, int64(4), spills[0].Calls)
+	assert.Equal(t, int64(2048*8192), spills[0].TempBytesWritten)
+	assert.Equal(t, int64(2), spills[0].TempFiles)
+	assert.Equal(t, int64(8000000), spills[0].TempFileBytes)
+	assert.Equal(t, int64(5000000), spills[0].MaxTempFileBytes)
+	// largest logged temp file rounded up to a power of two
+	assert.Equal(t, int64(8388608), spills[0].RecommendedWorkMemBytes)
+
+	// no logged temp files so uses temp bytes written per call
+	assert.Equal(t, "hash", spills[1].Fingerprint)
+	assert.Equal(t, int64(1048576), spills[1].RecommendedWorkMemBytes)
+
+	// only seen in the logs
+	assert.Equal(t, "logged", spills[2].Fingerprint)
+	assert.Equal(t, "select * from events order by id", spills[2].Query)
+	assert.Equal(t, int64(1024), spills[2].RecommendedWorkMemBytes)
+}
+
+func TestTopTempSpillsLimit(t *testing.T) {
+	stats := []QueryStats{
+		{Fingerprint: "a", Calls: 1, TempBlocksWritten: 1},
+		{Fingerprint: "b", Calls: 1, TempBlocksWritten: 3},
+		{Fingerprint: "c", Calls: 1, TempBlocksWritten: 2},
+	}
+
+	spills := TopTempSpills(stats, []TempFile{}, 2)
+
+	assert.Equal(t, 2, len(spills))
+	assert.Equal(t, "b", spills[0].Fingerprint)
+	assert.Equal(t, "c", spills[1].Fingerprint)
+}
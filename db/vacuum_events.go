@@ -0,0 +1,161 @@
+package db
+
+import (
+	"agent/logger"
+	"sort"
+)
+
+const (
+	VacuumEventKindVacuum  = "vacuum"
+	VacuumEventKindAnalyze = "analyze"
+
+	// max number of tables with vacuum activity reported per server
+	MaxTableVacuums = 50
+)
+
+// an automatic vacuum or analyze logged by postgres when log_autovacuum_min_duration is set
+// ex. LOG:  automatic vacuum of table "db.public.events": index scans: 1 ...
+type VacuumEvent struct {
+	ServerConfigName string
+	ServerID         *ServerID
+	Kind             string
+	Aggressive       bool
+	Wraparound       bool // to prevent wraparound
+
+	// table identity - ex. db.public.events
+	Database string
+	Schema   string
+	Table    string
+
+	IndexScans             int64
+	PagesRemoved           int64
+	PagesRemaining         int64
+	TuplesRemoved          int64
+	TuplesRemaining        int64
+	TuplesDeadNotRemovable int64
+	BufferHits             int64
+	BufferMisses           int64
+	BufferDirtied          int64
+	WalRecords             int64
+	WalFullPageImages      int64
+	WalBytes               int64
+	AvgReadRateMBs         float64
+	AvgWriteRateMBs        float64
+	CpuUserSeconds         float64
+	CpuSystemSeconds       float64
+	ElapsedSeconds         float64
+
+	Fields     LogFields
+	MeasuredAt int64
+}
+
+// vacuum and analyze runs aggregated per table over a report interval
+type TableVacuum struct {
+	Database string
+	Schema   string
+	Table    string
+
+	Vacuums                 int64
+	AggressiveVacuums       int64
+	WraparoundVacuums       int64
+	VacuumElapsedSeconds    float64
+	MaxVacuumElapsedSeconds float64
+	Analyzes                int64
+	AnalyzeElapsedSeconds   float64
+	PagesRemoved            int64
+	TuplesRemoved           int64
+	TuplesDeadNotRemovable  int64 // from the latest vacuum
+	BufferMisses            int64
+	BufferDirtied           int64
+	WalBytes                int64
+	LastVacuumAt            int64
+	LastAnalyzeAt           int64
+}
+
+// runs forever
+func (o *Observer) MonitorVacuumEvents() {
+	for {
+		select {
+		case vacuumEvent := <-o.rawVacuumEventChannel:
+			vacuumEvent.ServerID = o.serverIDForConfigName(vacuumEvent.ServerConfigName)
+
+			select {
+			case o.vacuumEventChannel <- vacuumEvent:
+				// sent
+			default:
+				logger.Warn("Dropping vacuum event: channel buffer full")
+			}
+		}
+	}
+}
+
+// aggregates vacuum events by table and returns the tables that spent the most time vacuuming
+func AggregateVacuumEvents(events []VacuumEvent, limit int) []*TableVacuum {
+	type tableKey struct {
+		database string
+		schema   string
+		table    string
+	}
+
+	tables := make(map[tableKey]*TableVacuum)
+
+	for _, event := range events {
+		key := tableKey{database: event.Database, schema: event.Schema, table: event.Table}
+		table, ok := tables[key]
+		if !ok {
+			table = &TableVacuum{Database: event.Database, Schema: event.Schema, Table: event.Table}
+			tables[key] = table
+		}
+
+		switch event.Kind {
+		case VacuumEventKindVacuum:
+			table.Vacuums++
+			if event.Aggressive {
+				table.AggressiveVacuums++
+			}
+			if event.Wraparound {
+				table.WraparoundVacuums++
+			}
+			table.VacuumElapsedSeconds += event.ElapsedSeconds
+			if event.ElapsedSeconds > table.MaxVacuumElapsedSeconds {
+				table.MaxVacuumElapsedSeconds = event.ElapsedSeconds
+			}
+			table.PagesRemoved += event.PagesRemoved
+			table.TuplesRemoved += event.TuplesRemoved
+			if event.MeasuredAt >= table.LastVacuumAt {
+				table.LastVacuumAt = event.MeasuredAt
+				table.TuplesDeadNotRemovable = event.TuplesDeadNotRemovable
+			}
+		case VacuumEventKindAnalyze:
+			table.Analyzes++
+			table.AnalyzeElapsedSeconds += event.ElapsedSeconds
+			if event.MeasuredAt > table.LastAnalyzeAt {
+				table.LastAnalyzeAt = event.MeasuredAt
+			}
+		}
+
+		table.BufferMisses += event.BufferMisses
+		table.BufferDirtied += event.BufferDirtied
+		table.WalBytes += event.WalBytes
+	}
+
+	var sorted []*TableVacuum
+	for _, table := range tables {
+		sorted = append(sorted, table)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		elapsedI := sorted[i].VacuumElapsedSeconds + sorted[i].AnalyzeElapsedSeconds
+		elapsedJ := sorted[j].VacuumElapsedSeconds + sorted[j].AnalyzeElapsedSeconds
+		if elapsedI == elapsedJ {
+			return sorted[i].Database+"."+sorted[i].Schema+"."+sorted[i].Table < sorted[j].Database+"."+sorted[j].Schema+"."+sorted[j].Table
+		}
+		return elapsedI > elapsedJ
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateVacuumEvents(t *testing.T) {
+	events := []VacuumEvent{
+		{Kind: VacuumEventKindVacuum, Database: "prod", Schema: "public", Table: "events", ElapsedSeconds: 2.5, PagesRemoved: 10, TuplesRemoved: 100, TuplesDeadNotRemovable: 50, MeasuredAt: 100},
+		{Kind: VacuumEventKindVacuum, Database: "prod", Schema: "public", Table: "events", Aggressive: true, Wraparound: true, ElapsedSeconds: 4, PagesRemoved: 5, TuplesRemoved: 20, TuplesDeadNotRemovable: 5, WalBytes: 1024, MeasuredAt: 200},
+		{Kind: VacuumEventKindAnalyze, Database: "prod", Schema: "public", Table: "events", ElapsedSeconds: 1, MeasuredAt: 150},
+		{Kind: VacuumEventKindAnalyze, Database: "prod", Schema: "public", Table: "users", ElapsedSeconds: 0.5, MeasuredAt: 120},
+	}
+
+	tables := AggregateVacuumEvents(events, MaxTableVacuums)
+
+	assert.Equal(t, 2, len(tables))
+	assert.Equal(t, &TableVacuum{
+		Database:                "prod",
+		Schema:                  "public",
+		Table:                   "events",
+		Vacuums:                 2,
+		AggressiveVacuums:       1,
+		WraparoundVacuums:       1,
+		VacuumElapsedSeconds:    6.5,
+		MaxVacuumElapsedSeconds: 4,
+		Analyzes:                1,
+		AnalyzeElapsedSeconds:   1,
+		PagesRemoved:            15,
+		TuplesRemoved:           120,
+		TuplesDeadNotRemovable:  5,
+		WalBytes:                1024,
+		LastVacuumAt:            200,
+		LastAnalyzeAt:           150,
+	}, tables[0])
+	assert.Equal(t, "users", tables[1].Table)
+	assert.Equal(t, int64(1), tables[1].Analyzes)
+
+	assert.Equal(t, 1, len(AggregateVacuumEvents(events, 1)))
+}
+
+func TestAggregateVacuumEventsTies(t *testing.T) {
+	events := []VacuumEvent{
+		{Kind: VacuumEventKindVacuum, Database: "prod", Schema: "public", Table: "users", ElapsedSeconds: 1, MeasuredAt: 100},
+		{Kind: VacuumEventKindVacuum, Database: "prod", Schema: "public", Table: "events", ElapsedSeconds: 1, MeasuredAt: 100},
+		{Kind: VacuumEventKindVacuum, Database: "prod", Schema: "other", Table: "users", ElapsedSeconds: 0.5, MeasuredAt: 100},
+	}
+
+	// tables with the same elapsed time are ordered by name so the reported tables are stable
+	tables := AggregateVacuumEvents(events, 2)
+	assert.Equal(t, 2, len(tables))
+	assert.Equal(t, "events", tables[0].Table)
+	assert.Equal(t, "users", tables[1].Table)
+	assert.Equal(t, "public", tables[1].Schema)
+
+	assert.Nil(t, AggregateVacuumEvents(nil, MaxTableVacuums))
+}
@@ -1,6 +1,9 @@
 package logs
 
 import (
+	"agent/config"
+	"agent/util"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -10,26 +13,86 @@ import (
 const allowedContentType = "application/logplex-1"
 const logplexDrainTokenPrefix = "d."
 
+// gin context key for the server config name a drain token routes logs to
+const serverNameContextKey = "serverName"
+
 // Authentication gin middleware to validate that requests to /logs server are legitimate
-func Authentication() gin.HandlerFunc {
+func Authentication(config config.Config, stats *util.Stats) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// skip GET root requests since we redirect to postgresmonitor.com
 		if c.Request.Method != "GET" && c.Request.URL.Path != "/" {
+			// non-logplex senders authenticate with basic auth or a bearer token instead of a drain token
+			if c.Request.Header.Get("Authorization") != "" {
+				if !validCredentials(config, c.Request) {
+					reject(c, stats, "logs.rejected.credentials", http.StatusUnauthorized)
+					return
+				}
+
+				c.Next()
+				return
+			}
+
 			// validate content type
 			contentType := c.Request.Header.Get("Content-Type")
 			if contentType != allowedContentType {
-				c.AbortWithStatus(http.StatusBadRequest)
+				reject(c, stats, "logs.rejected.content_type", http.StatusBadRequest)
 				return
 			}
 
 			// validate logplex drain token
 			drainToken := c.Request.Header.Get("Logplex-Drain-Token")
-			if len(drainToken) == 0 || !strings.HasPrefix(drainToken, logplexDrainTokenPrefix) {
-				c.AbortWithStatus(http.StatusBadRequest)
+			serverName, ok := allowedDrainToken(config.LogDrainTokens, drainToken)
+			if !ok {
+				reject(c, stats, "logs.rejected.drain_token", http.StatusForbidden)
 				return
 			}
+
+			if serverName != "" {
+				c.Set(serverNameContextKey, serverName)
+			}
 		}
 
 		c.Next()
 	}
 }
+
+// returns the server config name the drain token is routed to if the token is allowed
+// no tokens are allowed until LOG_DRAIN_TOKENS is configured so anyone who finds the url can't send logs
+func allowedDrainToken(drainTokens map[string]string, drainToken string) (string, bool) {
+	if len(drainToken) == 0 || !strings.HasPrefix(drainToken, logplexDrainTokenPrefix) {
+		return "", false
+	}
+
+	serverName, ok := drainTokens[drainToken]
+	return serverName, ok
+}
+
+// credentials are compared in constant time and are only valid when configured
+func validCredentials(config config.Config, request *http.Request) bool {
+	if config.LogBearerToken != "" {
+		authorization := request.Header.Get("Authorization")
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if token != authorization && secureCompare(token, config.LogBearerToken) {
+			return true
+		}
+	}
+
+	if config.LogBasicAuthUser != "" && config.LogBasicAuthPassword != "" {
+		user, password, ok := request.BasicAuth()
+		if ok && secureCompare(user, config.LogBasicAuthUser) && secureCompare(password, config.LogBasicAuthPassword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func secureCompare(given string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
+
+func reject(c *gin.Context, stats *util.Stats, reason string, status int) {
+	stats.Increment("logs.rejected")
+	stats.Increment(reason)
+	c.AbortWithStatus(status)
+}
@@ -0,0 +1,109 @@
+package logs
+
+import (
+	"agent/config"
+	"agent/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAuthenticationRouter(config config.Config, stats *util.Stats) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Authentication(config, stats))
+	router.POST("/logs", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(serverNameContextKey))
+	})
+	return router
+}
+
+func postTestLogs(router *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("POST", "/logs", nil)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAuthenticationDrainTokens(t *testing.T) {
+	stats := &util.Stats{}
+	router := newTestAuthenticationRouter(config.Config{
+		LogDrainTokens: map[string]string{"d.123": "GREEN", "d.456": ""},
+	}, stats)
+
+	response := postTestLogs(router, map[string]string{"Content-Type": allowedContentType, "Logplex-Drain-Token": "d.123"})
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "GREEN", response.Body.String())
+
+	response = postTestLogs(router, map[string]string{"Content-Type": allowedContentType, "Logplex-Drain-Token": "d.456"})
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "", response.Body.String())
+
+	response = postTestLogs(router, map[string]string{"Content-Type": allowedContentType, "Logplex-Drain-Token": "d.789"})
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	response = postTestLogs(router, map[string]string{"Content-Type": "text/plain", "Logplex-Drain-Token": "d.123"})
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	statsMap := stats.ToMap()
+	assert.Equal(t, 2, statsMap["logs.rejected"])
+	assert.Equal(t, 1, statsMap["logs.rejected.drain_token"])
+	assert.Equal(t, 1, statsMap["logs.rejected.content_type"])
+}
+
+func TestAuthenticationWithoutDrainTokens(t *testing.T) {
+	stats := &util.Stats{}
+	router := newTestAuthenticationRouter(config.Config{LogDrainTokens: map[string]string{}}, stats)
+
+	// drain tokens must be allowlisted before any logplex drain is accepted
+	response := postTestLogs(router, map[string]string{"Content-Type": allowedContentType, "Logplex-Drain-Token": "d.anything"})
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	response = postTestLogs(router, map[string]string{"Content-Type": allowedContentType, "Logplex-Drain-Token": "invalid"})
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	assert.Equal(t, 2, stats.ToMap()["logs.rejected.drain_token"])
+
+	// credentials still work without drain tokens
+	router = newTestAuthenticationRouter(config.Config{LogBearerToken: "token"}, &util.Stats{})
+	response = postTestLogs(router, map[string]string{"Authorization": "Bearer token"})
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestAuthenticationCredentials(t *testing.T) {
+	stats := &util.Stats{}
+	router := newTestAuthenticationRouter(config.Config{
+		LogBasicAuthUser:     "logs",
+		LogBasicAuthPassword: "secret",
+		LogBearerToken:       "token",
+	}, stats)
+
+	response := postTestLogs(router, map[string]string{"Authorization": "Bearer token"})
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	request := httptest.NewRequest("POST", "/logs", nil)
+	request.SetBasicAuth("logs", "secret")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	response = postTestLogs(router, map[string]string{"Authorization": "Bearer wrong"})
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+
+	assert.Equal(t, 1, stats.ToMap()["logs.rejected.credentials"])
+}
+
+func TestAuthenticationCredentialsNotConfigured(t *testing.T) {
+	router := newTestAuthenticationRouter(config.Config{}, &util.Stats{})
+
+	response := postTestLogs(router, map[string]string{"Authorization": "Bearer "})
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+}
@@ -0,0 +1,104 @@
+package logs
+
+import (
+	"agent/db"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//
+// These functions parse the plans logged by auto_explain in its text and json formats.
+// Multi-line plans can arrive with their lines joined so the text format is split on its first plan node.
+//
+
+// ex. LOG:  duration: 1000.123 ms  plan:
+var autoExplainRegex = regexp.MustCompile(`(?s)^LOG:\s+duration:\s+(?P<duration>\d+\.\d+) ms\s+plan:\s*(?P<plan>.*)`)
+
+// plan node names from EXPLAIN - https://github.com/postgres/postgres/blob/master/src/backend/commands/explain.c
+var planNodeNames = `(?:(?:Partial|Finalize|Parallel|Async|Foreign|Custom) )*(?:` +
+	`Result|ProjectSet|Insert|Update|Delete|Merge Append|Merge Join|Merge|Append|Recursive Union|BitmapAnd|BitmapOr|` +
+	`Nested Loop|Hash Join|Seq Scan|Sample Scan|Gather Merge|Gather|Index Only Scan|Index Scan|Bitmap Index Scan|` +
+	`Bitmap Heap Scan|Tid Range Scan|Tid Scan|Subquery Scan|Function Scan|Table Function Scan|Values Scan|CTE Scan|` +
+	`Named Tuplestore Scan|WorkTable Scan|Foreign Scan|Custom Scan|Materialize|Memoize|Incremental Sort|Sort|` +
+	`GroupAggregate|HashAggregate|MixedAggregate|Aggregate|Group|WindowAgg|Unique|HashSetOp|SetOp|LockRows|Limit|Hash)`
+
+// the first plan node ends the query text since each node prints two spaces before its costs
+// postgres 16 logs the bind parameters between the query text and the plan when log_parameter_max_length is set
+// ex. Query Text: SELECT * FROM users WHERE id = $1 Query Parameters: $1 = '1' Seq Scan on users  (cost=0.00..1.01 rows=1 width=4)
+var autoExplainTextRegex = regexp.MustCompile(`(?s)^Query Text:\s*(?P<query>.*?)\s*(?:Query Parameters:.*?\s*)?(?P<plan>\b` + planNodeNames + `\b[^(\n]*?  \((?:cost|actual)=.*)$`)
+
+func parseAutoExplainSyslogLine(line *SyslogLine, message string, fields db.LogFields, timestamp int64) *ParsedLogLine {
+	captureGroups := matchRegex(autoExplainRegex, message)
+	if len(captureGroups) == 0 {
+		return nil
+	}
+
+	var plan *db.LoggedPlan
+	text := trimTrailingLogLinePrefix(line, captureGroups["plan"])
+	if strings.HasPrefix(text, "{") {
+		plan = parseAutoExplainJSON(text)
+	} else {
+		plan = parseAutoExplainText(text)
+	}
+
+	if plan == nil {
+		return nil
+	}
+
+	plan.DurationMs, _ = strconv.ParseFloat(captureGroups["duration"], 64)
+	plan.ServerConfigName = line.color
+	plan.Fields = fields
+	plan.MeasuredAt = timestamp
+
+	return &ParsedLogLine{
+		LoggedPlan: plan,
+	}
+}
+
+func parseAutoExplainText(text string) *db.LoggedPlan {
+	captureGroups := matchRegex(autoExplainTextRegex, text)
+	if len(captureGroups) == 0 || captureGroups["query"] == "" {
+		return nil
+	}
+
+	return &db.LoggedPlan{
+		Format: db.LoggedPlanFormatText,
+		Query:  captureGroups["query"],
+		Plan:   strings.TrimSpace(captureGroups["plan"]),
+	}
+}
+
+// ex. { "Query Text": "SELECT * FROM users", "Plan": { "Node Type": "Seq Scan", ... } }
+func parseAutoExplainJSON(text string) *db.LoggedPlan {
+	var plan map[string]interface{}
+	err := json.Unmarshal([]byte(text), &plan)
+	if err != nil {
+		return nil
+	}
+
+	query, _ := plan["Query Text"].(string)
+	if query == "" {
+		return nil
+	}
+
+	// the query is reported separately after obfuscating and parameters are unredacted query inputs
+	delete(plan, "Query Text")
+	delete(plan, "Query Parameters")
+
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(plan)
+	if err != nil {
+		return nil
+	}
+
+	return &db.LoggedPlan{
+		Format: db.LoggedPlanFormatJSON,
+		Query:  query,
+		Plan:   strings.TrimSpace(encoded.String()),
+	}
+}
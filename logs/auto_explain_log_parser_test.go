@@ -0,0 +1,76 @@
+package logs
+
+import (
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAutoExplainTextSyslogLine(t *testing.T) {
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   "sql_error_code = 00000 LOG:  duration: 1002.345 ms  plan:\n\tQuery Text: SELECT * FROM users\n\t  WHERE id = 1\n\tSeq Scan on users  (cost=0.00..1.01 rows=1 width=4) (actual time=0.010..0.011 rows=1 loops=1)\n\t  Filter: (id = 1)\n\t  Buffers: shared hit=1",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	assert.Equal(t, &db.LoggedPlan{
+		ServerConfigName: "GREEN",
+		Format:           db.LoggedPlanFormatText,
+		DurationMs:       1002.345,
+		Query:            "SELECT * FROM users\n\t  WHERE id = 1",
+		Plan:             "Seq Scan on users  (cost=0.00..1.01 rows=1 width=4) (actual time=0.010..0.011 rows=1 loops=1)\n\t  Filter: (id = 1)\n\t  Buffers: shared hit=1",
+		Fields:           db.LogFields{SqlState: "00000"},
+		MeasuredAt:       1660188131,
+	}, parseSqlSyslogLine(line).LoggedPlan)
+
+	// plan lines can arrive joined without their line breaks
+	line = &SyslogLine{
+		color:   "GREEN",
+		message: "sql_error_code = 00000 LOG:  duration: 12.5 ms  plan: Query Text: SELECT count(*) FROM events WHERE kind = 'click' Aggregate  (cost=10.00..10.01 rows=1 width=8) -> Index Only Scan using events_kind_idx on events  (cost=0.15..9.50 rows=200 width=0) Index Cond: (kind = 'click'::text)",
+	}
+	plan := parseSqlSyslogLine(line).LoggedPlan
+	assert.Equal(t, "SELECT count(*) FROM events WHERE kind = 'click'", plan.Query)
+	assert.Equal(t, "Aggregate  (cost=10.00..10.01 rows=1 width=8) -> Index Only Scan using events_kind_idx on events  (cost=0.15..9.50 rows=200 width=0) Index Cond: (kind = 'click'::text)", plan.Plan)
+
+	// bind parameters are neither part of the query nor the plan
+	line = &SyslogLine{
+		color:   "GREEN",
+		message: "sql_error_code = 00000 LOG:  duration: 12.5 ms  plan:\n\tQuery Text: SELECT * FROM users WHERE email = $1\n\tQuery Parameters: $1 = 'alice@example.com'\n\tIndex Scan using users_email_idx on users  (cost=0.15..8.17 rows=1 width=4)\n\t  Index Cond: (email = $1)",
+	}
+	plan = parseSqlSyslogLine(line).LoggedPlan
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1", plan.Query)
+	assert.Equal(t, "Index Scan using users_email_idx on users  (cost=0.15..8.17 rows=1 width=4)\n\t  Index Cond: (email = $1)", plan.Plan)
+
+	// slow queries are still parsed as statements
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 00000 LOG:  duration: 1002.345 ms  statement: SELECT 1"}
+	parsed := parseSqlSyslogLine(line)
+	assert.Nil(t, parsed.LoggedPlan)
+	assert.Equal(t, "SELECT 1", parsed.SlowQuery.Raw)
+}
+
+func TestParseAutoExplainJSONSyslogLine(t *testing.T) {
+	line := &SyslogLine{
+		color:   "GREEN",
+		message: "sql_error_code = 00000 LOG:  duration: 1002.345 ms  plan:{  \"Query Text\": \"SELECT * FROM users WHERE id < 5\",  \"Plan\": {    \"Node Type\": \"Seq Scan\",    \"Relation Name\": \"users\",    \"Filter\": \"(id < 5)\",    \"Shared Hit Blocks\": 1  }}",
+	}
+
+	plan := parseSqlSyslogLine(line).LoggedPlan
+	assert.Equal(t, db.LoggedPlanFormatJSON, plan.Format)
+	assert.Equal(t, "SELECT * FROM users WHERE id < 5", plan.Query)
+	assert.Equal(t, `{"Plan":{"Filter":"(id < 5)","Node Type":"Seq Scan","Relation Name":"users","Shared Hit Blocks":1}}`, plan.Plan)
+
+	// bind parameters are unredacted so they are dropped with the query text
+	line = &SyslogLine{
+		color:   "GREEN",
+		message: "sql_error_code = 00000 LOG:  duration: 1002.345 ms  plan:{  \"Query Text\": \"SELECT * FROM users WHERE email = $1\",  \"Query Parameters\": \"$1 = 'alice@example.com'\",  \"Plan\": {    \"Node Type\": \"Seq Scan\",    \"Relation Name\": \"users\"  }}",
+	}
+	plan = parseSqlSyslogLine(line).LoggedPlan
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1", plan.Query)
+	assert.Equal(t, `{"Plan":{"Node Type":"Seq Scan","Relation Name":"users"}}`, plan.Plan)
+	assert.NotContains(t, plan.Plan, "alice")
+
+	// truncated plans aren't reported
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 00000 LOG:  duration: 1002.345 ms  plan:{  \"Query Text\": \"SELECT * FROM users\",  \"Plan\": {"}
+	assert.Nil(t, parseSqlSyslogLine(line))
+}
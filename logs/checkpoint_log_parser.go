@@ -0,0 +1,67 @@
+package logs
+
+import (
+	"agent/db"
+	"regexp"
+	"strings"
+)
+
+//
+// These functions parse the checkpoint entries logged when log_checkpoints is enabled - the default since postgres 15.
+//
+
+var checkpointStartingRegex = regexp.MustCompile(`^LOG:\s+checkpoint starting: (?P<reason>.*)`)
+var checkpointCompleteRegex = regexp.MustCompile(`^LOG:\s+checkpoint complete: `)
+var checkpointTooFrequentRegex = regexp.MustCompile(`^LOG:\s+checkpoints are occurring too frequently \((\d+) seconds? apart\)`)
+
+// postgres 17 also logs SLRU buffers after the buffers written
+var checkpointBuffersRegex = regexp.MustCompile(`wrote (\d+) buffers \(([\d.]+)%\)`)
+var checkpointWalFilesRegex = regexp.MustCompile(`(\d+) WAL file\(s\) added, (\d+) removed, (\d+) recycled`)
+var checkpointTimesRegex = regexp.MustCompile(`write=([\d.]+) s, sync=([\d.]+) s, total=([\d.]+) s`)
+var checkpointSyncFilesRegex = regexp.MustCompile(`sync files=(\d+)`)
+var checkpointDistanceRegex = regexp.MustCompile(`distance=(\d+) kB, estimate=(\d+) kB`)
+
+func parseCheckpointSyslogLine(line *SyslogLine, message string, fields db.LogFields, timestamp int64) *ParsedLogLine {
+	event := &db.CheckpointEvent{
+		ServerConfigName: line.color,
+		Fields:           fields,
+		MeasuredAt:       timestamp,
+	}
+
+	if captureGroups := matchRegex(checkpointStartingRegex, message); len(captureGroups) > 0 {
+		event.Kind = db.CheckpointEventKindStarting
+		event.Reason = strings.TrimSpace(captureGroups["reason"])
+	} else if checkpointCompleteRegex.MatchString(message) {
+		event.Kind = db.CheckpointEventKindComplete
+		parseCheckpointComplete(event, message)
+	} else if values := matchInts(checkpointTooFrequentRegex, message); values != nil {
+		event.Kind = db.CheckpointEventKindTooFrequent
+		event.SecondsApart = values[0]
+	} else {
+		return nil
+	}
+
+	return &ParsedLogLine{
+		CheckpointEvent: event,
+	}
+}
+
+// ex. wrote 3306 buffers (20.2%); 0 WAL file(s) added, 0 removed, 2 recycled; write=269.551 s, sync=0.007 s, total=269.566 s;
+// sync files=31, longest=0.002 s, average=0.001 s; distance=32751 kB, estimate=32751 kB
+func parseCheckpointComplete(event *db.CheckpointEvent, message string) {
+	if values := matchFloats(checkpointBuffersRegex, message); values != nil {
+		event.BuffersWritten, event.BuffersWrittenPercent = int64(values[0]), values[1]
+	}
+	if values := matchInts(checkpointWalFilesRegex, message); values != nil {
+		event.WalFilesAdded, event.WalFilesRemoved, event.WalFilesRecycled = values[0], values[1], values[2]
+	}
+	if values := matchFloats(checkpointTimesRegex, message); values != nil {
+		event.WriteSeconds, event.SyncSeconds, event.TotalSeconds = values[0], values[1], values[2]
+	}
+	if values := matchInts(checkpointSyncFilesRegex, message); values != nil {
+		event.SyncFiles = values[0]
+	}
+	if values := matchInts(checkpointDistanceRegex, message); values != nil {
+		event.DistanceBytes, event.EstimateBytes = values[0]*1024, values[1]*1024
+	}
+}
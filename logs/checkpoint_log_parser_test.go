@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCheckpointCompleteSyslogLine(t *testing.T) {
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   "sql_error_code = 00000 LOG:  checkpoint complete: wrote 3306 buffers (20.2%); 0 WAL file(s) added, 1 removed, 2 recycled; write=269.551 s, sync=0.007 s, total=269.566 s; sync files=31, longest=0.002 s, average=0.001 s; distance=32751 kB, estimate=32760 kB; lsn=0/3B2F8C8, redo lsn=0/3B2F890",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	assert.Equal(t, &db.CheckpointEvent{
+		ServerConfigName:      "GREEN",
+		Kind:                  db.CheckpointEventKindComplete,
+		BuffersWritten:        3306,
+		BuffersWrittenPercent: 20.2,
+		WalFilesRemoved:       1,
+		WalFilesRecycled:      2,
+		WriteSeconds:          269.551,
+		SyncSeconds:           0.007,
+		TotalSeconds:          269.566,
+		SyncFiles:             31,
+		DistanceBytes:         32751 * 1024,
+		EstimateBytes:         32760 * 1024,
+		Fields:                db.LogFields{SqlState: "00000"},
+		MeasuredAt:            1660188131,
+	}, parseSqlSyslogLine(line).CheckpointEvent)
+}
+
+func TestParseCheckpointSyslogLines(t *testing.T) {
+	line := &SyslogLine{color: "GREEN", message: "sql_error_code = 00000 LOG:  checkpoint starting: immediate force wait"}
+	event := parseSqlSyslogLine(line).CheckpointEvent
+	assert.Equal(t, db.CheckpointEventKindStarting, event.Kind)
+	assert.Equal(t, "immediate force wait", event.Reason)
+
+	// the hint is stitched into the same message
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 00000 LOG:  checkpoints are occurring too frequently (9 seconds apart)sql_error_code = 00000 HINT:  Consider increasing the configuration parameter \"max_wal_size\"."}
+	event = parseSqlSyslogLine(line).CheckpointEvent
+	assert.Equal(t, db.CheckpointEventKindTooFrequent, event.Kind)
+	assert.Equal(t, int64(9), event.SecondsApart)
+
+	// restartpoints on replicas aren't parsed
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 00000 LOG:  restartpoint starting: xlog"}
+	assert.Nil(t, parseSqlSyslogLine(line))
+}
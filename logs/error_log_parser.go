@@ -0,0 +1,49 @@
+package logs
+
+import (
+	"agent/db"
+	"regexp"
+	"strings"
+)
+
+//
+// These functions parse ERROR, FATAL and PANIC entries so errors can be counted by SQLSTATE and message.
+// Error messages can include identifiers and values so only a normalized template of the message is kept.
+//
+
+var errorLogLineRegex = regexp.MustCompile(`(?s)^(?P<severity>ERROR|FATAL|PANIC):\s+(?P<message>.*)`)
+
+// ex. relation "users" does not exist or invalid input syntax for type integer: 'abc'
+var errorQuotedIdentifierRegex = regexp.MustCompile(`"(?:[^"]|"")*"`)
+var errorQuotedLiteralRegex = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// ex. 57014, 10.0.0.1 or 1.5
+var errorNumberRegex = regexp.MustCompile(`\b\d+(?:[.:]\d+)*\b`)
+
+func parseErrorSyslogLine(line *SyslogLine, message string, fields db.LogFields, timestamp int64) *db.LogError {
+	first, entries := splitLogEntries(line, message)
+
+	captureGroups := matchRegex(errorLogLineRegex, first)
+	if len(captureGroups) == 0 {
+		return nil
+	}
+
+	return &db.LogError{
+		ServerConfigName: line.color,
+		Severity:         captureGroups["severity"],
+		SqlState:         fields.SqlState,
+		Message:          errorMessageTemplate(captureGroups["message"]),
+		Raw:              entries[logEntryStatement],
+		Fields:           fields,
+		MeasuredAt:       timestamp,
+	}
+}
+
+// strips identifiers and literals so the same error groups together
+// ex. relation "users" does not exist => relation "?" does not exist
+func errorMessageTemplate(message string) string {
+	message = errorQuotedIdentifierRegex.ReplaceAllString(message, `"?"`)
+	message = errorQuotedLiteralRegex.ReplaceAllString(message, `'?'`)
+	message = errorNumberRegex.ReplaceAllString(message, `?`)
+	return strings.TrimSpace(message)
+}
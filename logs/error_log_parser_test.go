@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorSyslogLine(t *testing.T) {
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   " sql_error_code = 23505 time_ms = \"2022-08-11 02:47:50.521 UTC\" pid=\"275326\" database=\"app\" user=\"app_user\" ERROR:  duplicate key value violates unique constraint \"idx_index_123\" sql_error_code = 23505 time_ms = \"2022-08-11 02:47:50.521 UTC\" pid=\"275326\" DETAIL:  Key (user_id)=(345769) already exists. sql_error_code = 23505 time_ms = \"2022-08-11 02:47:50.521 UTC\" pid=\"275326\" STATEMENT:  INSERT INTO \"notifications\" (\"user_id\") VALUES (345769) RETURNING \"id\"",
+		timestamp: "2022-08-11T02:47:50+00:00",
+	}
+
+	assert.Equal(t, &db.LogError{
+		ServerConfigName: "GREEN",
+		Severity:         "ERROR",
+		SqlState:         "23505",
+		Message:          "duplicate key value violates unique constraint \"?\"",
+		Raw:              "INSERT INTO \"notifications\" (\"user_id\") VALUES (345769) RETURNING \"id\"",
+		Fields:           db.LogFields{Pid: 275326, Database: "app", User: "app_user", SqlState: "23505"},
+		MeasuredAt:       1660186070,
+	}, parseSqlSyslogLine(line).LogError)
+
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 53300 FATAL:  remaining connection slots are reserved for non-replication superuser connections"}
+	logError := parseSqlSyslogLine(line).LogError
+	assert.Equal(t, "FATAL", logError.Severity)
+	assert.Equal(t, "53300", logError.SqlState)
+	assert.Equal(t, "", logError.Raw)
+
+	// deadlocks are both a lock event and an error
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 40P01 ERROR:  deadlock detected sql_error_code = 40P01 DETAIL:  Process 123 waits for ShareLock on transaction 5678; blocked by process 456."}
+	parsed := parseSqlSyslogLine(line)
+	assert.NotNil(t, parsed.LockEvent)
+	assert.Equal(t, "deadlock detected", parsed.LogError.Message)
+
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 00000 LOG:  connection received: host=10.0.0.1 port=5432"}
+	assert.Nil(t, parseSqlSyslogLine(line))
+}
+
+func TestErrorMessageTemplate(t *testing.T) {
+	assert.Equal(t, "canceling statement due to statement timeout", errorMessageTemplate("canceling statement due to statement timeout"))
+	assert.Equal(t, "relation \"?\" does not exist", errorMessageTemplate("relation \"public.users\" does not exist"))
+	assert.Equal(t, "invalid input syntax for type integer: \"?\"", errorMessageTemplate("invalid input syntax for type integer: \"abc\""))
+	assert.Equal(t, "value too long for type character varying(?)", errorMessageTemplate("value too long for type character varying(255)"))
+	assert.Equal(t, "no pg_hba.conf entry for host \"?\", user \"?\", database \"?\", no encryption", errorMessageTemplate("no pg_hba.conf entry for host \"10.0.0.1\", user \"app\", database \"prod\", no encryption"))
+	assert.Equal(t, "syntax error at or near '?' at position ?", errorMessageTemplate("syntax error at or near 'it''s' at position 12"))
+}
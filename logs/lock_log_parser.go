@@ -0,0 +1,163 @@
+package logs
+
+import (
+	"agent/db"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//
+// These functions parse the lock waits logged when log_lock_waits is enabled and deadlock errors.
+// The processes involved are listed in the DETAIL: entry and the reporting process's query in the STATEMENT: entry.
+//
+
+// ex. LOG:  process 123 still waiting for ShareLock on transaction 5678 after 1000.072 ms
+var lockWaitRegex = regexp.MustCompile(`^LOG:\s+process (?P<pid>\d+) (?P<kind>still waiting for|acquired) (?P<mode>\w+) on (?P<object>.+?) after (?P<wait>[\d.]+) ms`)
+var deadlockRegex = regexp.MustCompile(`^ERROR:\s+deadlock detected`)
+
+// ex. DETAIL:  Processes holding the lock: 456, 789. Wait queue: 123.
+var lockHoldersRegex = regexp.MustCompile(`Process(?:es)? holding the lock: ([\d, ]+)\.`)
+var lockWaitQueueRegex = regexp.MustCompile(`Wait queue: ([\d, ]+)\.`)
+
+// ex. DETAIL:  Process 123 waits for ShareLock on transaction 5678; blocked by process 456.
+var deadlockWaitRegex = regexp.MustCompile(`Process (\d+) waits for (\w+) on (.+?); blocked by process (\d+)\.`)
+
+// each process's statement follows the wait cycle - ex. Process 123: UPDATE accounts SET ...
+var deadlockStatementRegex = regexp.MustCompile(`Process (\d+): `)
+
+func parseLockSyslogLine(line *SyslogLine, message string, fields db.LogFields, timestamp int64) *ParsedLogLine {
+	var event *db.LockEvent
+
+	if strings.HasPrefix(message, "LOG:") {
+		event = parseLockWait(line, message)
+	} else if deadlockRegex.MatchString(message) {
+		event = parseDeadlock(line, message)
+	}
+
+	if event == nil {
+		return nil
+	}
+
+	event.ServerConfigName = line.color
+	event.Fields = fields
+	event.MeasuredAt = timestamp
+
+	return &ParsedLogLine{
+		LockEvent: event,
+	}
+}
+
+func parseLockWait(line *SyslogLine, message string) *db.LockEvent {
+	first, entries := splitLogEntries(line, message)
+
+	captureGroups := matchRegex(lockWaitRegex, first)
+	if len(captureGroups) == 0 {
+		return nil
+	}
+
+	pid, _ := strconv.ParseInt(captureGroups["pid"], 10, 64)
+	wait, _ := strconv.ParseFloat(captureGroups["wait"], 64)
+
+	event := &db.LockEvent{
+		Kind:       db.LockEventKindWaiting,
+		LockMode:   captureGroups["mode"],
+		LockObject: captureGroups["object"],
+		WaitMs:     wait,
+	}
+	if captureGroups["kind"] == "acquired" {
+		event.Kind = db.LockEventKindAcquired
+	}
+
+	detail := entries[logEntryDetail]
+	if match := lockHoldersRegex.FindStringSubmatch(detail); match != nil {
+		event.HolderPids = parsePids(match[1])
+	}
+	if match := lockWaitQueueRegex.FindStringSubmatch(detail); match != nil {
+		event.WaitQueuePids = parsePids(match[1])
+	}
+
+	participant := &db.LockParticipant{
+		Pid:        pid,
+		LockMode:   event.LockMode,
+		LockObject: event.LockObject,
+		Raw:        entries[logEntryStatement],
+	}
+	if len(event.HolderPids) > 0 {
+		participant.BlockedByPid = event.HolderPids[0]
+	}
+	event.Participants = []*db.LockParticipant{participant}
+
+	return event
+}
+
+// ex. ERROR:  deadlock detected
+// DETAIL:  Process 123 waits for ShareLock on transaction 5678; blocked by process 456.
+//
+//	Process 456 waits for ShareLock on transaction 5677; blocked by process 123.
+//	Process 123: UPDATE accounts SET balance = 0 WHERE id = 1
+//	Process 456: UPDATE accounts SET balance = 0 WHERE id = 2
+func parseDeadlock(line *SyslogLine, message string) *db.LockEvent {
+	_, entries := splitLogEntries(line, message)
+	detail := entries[logEntryDetail]
+
+	event := &db.LockEvent{
+		Kind: db.LockEventKindDeadlock,
+	}
+
+	participants := make(map[int64]*db.LockParticipant)
+	for _, match := range deadlockWaitRegex.FindAllStringSubmatch(detail, -1) {
+		pid, _ := strconv.ParseInt(match[1], 10, 64)
+		blockedBy, _ := strconv.ParseInt(match[4], 10, 64)
+
+		participant := &db.LockParticipant{
+			Pid:          pid,
+			LockMode:     match[2],
+			LockObject:   match[3],
+			BlockedByPid: blockedBy,
+		}
+		participants[pid] = participant
+		event.Participants = append(event.Participants, participant)
+	}
+
+	if len(event.Participants) == 0 {
+		return nil
+	}
+
+	// the lock the reporting process was waiting for
+	event.LockMode = event.Participants[0].LockMode
+	event.LockObject = event.Participants[0].LockObject
+
+	// statements run until the next process's statement
+	matches := deadlockStatementRegex.FindAllStringSubmatchIndex(detail, -1)
+	for i, match := range matches {
+		end := len(detail)
+		if i < len(matches)-1 {
+			end = matches[i+1][0]
+		}
+
+		pid, _ := strconv.ParseInt(detail[match[2]:match[3]], 10, 64)
+		if participant, ok := participants[pid]; ok {
+			participant.Raw = strings.TrimSpace(detail[match[1]:end])
+		}
+	}
+
+	// the detail statements are only logged to the server log so fall back to the reporting process's statement
+	if participant := event.Participants[0]; participant.Raw == "" {
+		participant.Raw = entries[logEntryStatement]
+	}
+
+	return event
+}
+
+// ex. 456, 789
+func parsePids(list string) []int64 {
+	var pids []int64
+	for _, value := range strings.Split(list, ",") {
+		pid, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,101 @@
+package logs
+
+import (
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLockWaitSyslogLine(t *testing.T) {
+	// heroku stitches each entry into the same message with its own prefix
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   "sql_error_code = 00000 time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"123\" database=\"app\" user=\"app_user\" LOG:  process 123 still waiting for ShareLock on transaction 5678 after 1000.072 ms sql_error_code = 00000 time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"123\" database=\"app\" user=\"app_user\" DETAIL:  Processes holding the lock: 456, 789. Wait queue: 123. sql_error_code = 00000 time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"123\" database=\"app\" user=\"app_user\" CONTEXT:  while updating tuple (0,1) in relation \"accounts\" sql_error_code = 00000 time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"123\" database=\"app\" user=\"app_user\" STATEMENT:  UPDATE accounts SET balance = 10 WHERE id = 1",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	assert.Equal(t, &db.LockEvent{
+		ServerConfigName: "GREEN",
+		Kind:             db.LockEventKindWaiting,
+		LockMode:         "ShareLock",
+		LockObject:       "transaction 5678",
+		WaitMs:           1000.072,
+		HolderPids:       []int64{456, 789},
+		WaitQueuePids:    []int64{123},
+		Participants: []*db.LockParticipant{
+			{
+				Pid:          123,
+				LockMode:     "ShareLock",
+				LockObject:   "transaction 5678",
+				BlockedByPid: 456,
+				Raw:          "UPDATE accounts SET balance = 10 WHERE id = 1",
+			},
+		},
+		Fields:     db.LogFields{Pid: 123, Database: "app", User: "app_user", SqlState: "00000"},
+		MeasuredAt: 1660188131,
+	}, parseSqlSyslogLine(line).LockEvent)
+
+	line = &SyslogLine{color: "GREEN", message: "sql_error_code = 00000 LOG:  process 123 acquired AccessExclusiveLock on relation 16384 of database 16385 after 2500.5 ms"}
+	event := parseSqlSyslogLine(line).LockEvent
+	assert.Equal(t, db.LockEventKindAcquired, event.Kind)
+	assert.Equal(t, "relation 16384 of database 16385", event.LockObject)
+	assert.Equal(t, 2500.5, event.WaitMs)
+	assert.Equal(t, int64(0), event.Participants[0].BlockedByPid)
+}
+
+func TestParseDeadlockSyslogLine(t *testing.T) {
+	line := &SyslogLine{
+		color:   "GREEN",
+		message: "sql_error_code = 40P01 pid=\"123\" ERROR:  deadlock detected sql_error_code = 40P01 pid=\"123\" DETAIL:  Process 123 waits for ShareLock on transaction 5678; blocked by process 456.\tProcess 456 waits for ShareLock on transaction 5677; blocked by process 123.\tProcess 123: UPDATE accounts SET balance = 10 WHERE id = 1\tProcess 456: UPDATE accounts\n\tSET balance = 20 WHERE id = 2 sql_error_code = 40P01 pid=\"123\" HINT:  See server log for query details. sql_error_code = 40P01 pid=\"123\" STATEMENT:  UPDATE accounts SET balance = 10 WHERE id = 1",
+	}
+
+	event := parseSqlSyslogLine(line).LockEvent
+	assert.Equal(t, db.LockEventKindDeadlock, event.Kind)
+	assert.Equal(t, "ShareLock", event.LockMode)
+	assert.Equal(t, "transaction 5678", event.LockObject)
+	assert.Equal(t, "40P01", event.Fields.SqlState)
+	assert.Equal(t, []*db.LockParticipant{
+		{
+			Pid:          123,
+			LockMode:     "ShareLock",
+			LockObject:   "transaction 5678",
+			BlockedByPid: 456,
+			Raw:          "UPDATE accounts SET balance = 10 WHERE id = 1",
+		},
+		{
+			Pid:          456,
+			LockMode:     "ShareLock",
+			LockObject:   "transaction 5677",
+			BlockedByPid: 123,
+			Raw:          "UPDATE accounts\n\tSET balance = 20 WHERE id = 2",
+		},
+	}, event.Participants)
+}
+
+func TestParseDeadlockLogFileLine(t *testing.T) {
+	// stderr logs join continuation lines with the prefix of each entry
+	registry := &db.LogLinePrefixRegistry{}
+	registry.Register(&db.ServerID{ConfigName: "DEADLOCK"}, "%m [%p] ")
+
+	_, lines := parseStderrData([]byte("2022-08-11 03:22:11.987 UTC [123] ERROR:  deadlock detected\n"+
+		"2022-08-11 03:22:11.987 UTC [123] DETAIL:  Process 123 waits for ShareLock on transaction 5678; blocked by process 456.\n"+
+		"\tProcess 456 waits for ShareLock on transaction 5677; blocked by process 123.\n"+
+		"\tProcess 123: UPDATE accounts SET balance = 10 WHERE id = 1\n"+
+		"\tProcess 456: UPDATE accounts SET balance = 20 WHERE id = 2\n"+
+		"2022-08-11 03:22:11.987 UTC [123] HINT:  See server log for query details.\n"), "DEADLOCK")
+
+	assert.Equal(t, 1, len(lines))
+	lines[0].prefix = findLogLinePrefix(registry, "DEADLOCK")
+	event := parseSqlSyslogLine(lines[0]).LockEvent
+	assert.Equal(t, 2, len(event.Participants))
+	assert.Equal(t, "UPDATE accounts SET balance = 10 WHERE id = 1", event.Participants[0].Raw)
+	assert.Equal(t, "UPDATE accounts SET balance = 20 WHERE id = 2", event.Participants[1].Raw)
+
+	// csvlog has the detail in its own column
+	_, lines = parseCsvlogData([]byte(`2022-08-11 03:22:11.987 UTC,"app_user","app",123,"10.0.0.1:5432",62f468c2.7b,1,"UPDATE",2022-08-11 03:22:10 UTC,3/2,0,LOG,00000,"process 123 still waiting for ShareLock on transaction 5678 after 1000.072 ms","Process holding the lock: 456. Wait queue: 123.",,,,"while updating tuple (0,1) in relation ""accounts""","UPDATE accounts SET balance = 10 WHERE id = 1",,,"psql","client backend",,0`+"\n"), "DEADLOCK")
+
+	event = parseSqlSyslogLine(lines[0]).LockEvent
+	assert.Equal(t, []int64{456}, event.HolderPids)
+	assert.Equal(t, "UPDATE accounts SET balance = 10 WHERE id = 1", event.Participants[0].Raw)
+}
@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+)
+
+//
+// Postgres logs the details of a message as separate entries - ex. ERROR: followed by DETAIL: and STATEMENT:.
+// Heroku stitches them into one segmented message and log files join them as continuation lines
+// so each entry may end with the prefix that was logged before the next entry.
+//
+
+const (
+	logEntryDetail    = "DETAIL"
+	logEntryHint      = "HINT"
+	logEntryContext   = "CONTEXT"
+	logEntryStatement = "STATEMENT"
+	logEntryQuery     = "QUERY"
+)
+
+// postgres always logs two spaces after the entry severity
+var logEntryRegex = regexp.MustCompile(`\b(DETAIL|HINT|CONTEXT|STATEMENT|QUERY):  `)
+
+// splits a message into its first entry and the entries that follow it by severity
+func splitLogEntries(line *SyslogLine, message string) (string, map[string]string) {
+	entries := make(map[string]string)
+
+	matches := logEntryRegex.FindAllStringSubmatchIndex(message, -1)
+	if len(matches) == 0 {
+		return strings.TrimSpace(message), entries
+	}
+
+	first := trimTrailingLogLinePrefix(line, message[:matches[0][0]])
+
+	for i, match := range matches {
+		end := len(message)
+		if i < len(matches)-1 {
+			end = matches[i+1][0]
+		}
+
+		severity := message[match[2]:match[3]]
+		// only the first entry of a severity is kept - ex. a STATEMENT: logged after a QUERY:
+		if _, ok := entries[severity]; !ok {
+			entries[severity] = trimTrailingLogLinePrefix(line, message[match[1]:end])
+		}
+	}
+
+	return first, entries
+}
+
+// removes the prefix of the next entry from the end of an entry
+func trimTrailingLogLinePrefix(line *SyslogLine, entry string) string {
+	// structured log entries have no prefix
+	if line.fields != nil {
+		return strings.TrimSpace(entry)
+	}
+
+	// heroku prefixes each entry with sql_error_code = 00000
+	if index := strings.LastIndex(entry, "sql_error_code = "); index >= 0 {
+		return strings.TrimSpace(entry[:index])
+	}
+
+	if line.prefix != nil {
+		entry = line.prefix.TrimTrailing(entry)
+	}
+
+	return strings.TrimSpace(entry)
+}
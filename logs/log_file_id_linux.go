@@ -0,0 +1,18 @@
+//go:build linux
+
+package logs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// the device and inode identify a file even after it is renamed or replaced
+func logFileIdentity(info os.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", stat.Dev, stat.Ino)
+}
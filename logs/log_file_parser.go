@@ -0,0 +1,271 @@
+package logs
+
+import (
+	"agent/db"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// These functions parse postgres log files written by the logging collector
+// into syslog lines so they share the sql log parsers with heroku and syslog logs.
+//
+
+const (
+	LogFormatStderr  = "stderr"
+	LogFormatCsvlog  = "csvlog"
+	LogFormatJsonlog = "jsonlog"
+)
+
+// csvlog columns - https://www.postgresql.org/docs/current/runtime-config-logging.html#RUNTIME-CONFIG-LOGGING-CSVLOG
+const (
+	csvLogTime         = 0
+	csvUserName        = 1
+	csvDatabaseName    = 2
+	csvProcessID       = 3
+	csvConnectionFrom  = 4
+	csvSessionID       = 5
+	csvErrorSeverity   = 11
+	csvSqlStateCode    = 12
+	csvMessage         = 13
+	csvDetail          = 14
+	csvQuery           = 19
+	csvApplicationName = 22
+	csvBackendType     = 23 // postgres 13+
+	csvQueryID         = 25 // postgres 14+
+)
+
+// stderr lines that continue the previous log entry - ex. a STATEMENT: after an ERROR:
+var stderrContinuationRegex = regexp.MustCompile(`^.*?(DETAIL|HINT|CONTEXT|STATEMENT|QUERY):  `)
+
+// default log_line_prefix timestamps - ex. 2022-08-11 03:22:11.987 UTC
+var stderrTimestampRegex = regexp.MustCompile(`(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})(?:\.\d+)? (\w+)`)
+
+// pid from the default log_line_prefix - ex. [278908]
+var stderrPidRegex = regexp.MustCompile(`\[(\d+)\]`)
+
+// csvlog connection_from includes the remote port - ex. 10.0.0.1:5432
+var connectionFromPortRegex = regexp.MustCompile(`:\d+$`)
+
+// jsonlog keys added in postgres 15
+type jsonLogEntry struct {
+	Timestamp       string `json:"timestamp"`
+	User            string `json:"user"`
+	Dbname          string `json:"dbname"`
+	Pid             int64  `json:"pid"`
+	RemoteHost      string `json:"remote_host"`
+	SessionID       string `json:"session_id"`
+	ErrorSeverity   string `json:"error_severity"`
+	StateCode       string `json:"state_code"`
+	Message         string `json:"message"`
+	Detail          string `json:"detail"`
+	Statement       string `json:"statement"`
+	ApplicationName string `json:"application_name"`
+	BackendType     string `json:"backend_type"`
+	QueryID         int64  `json:"query_id"`
+}
+
+func detectLogFormat(path string) string {
+	switch filepath.Ext(path) {
+	case ".csv":
+		return LogFormatCsvlog
+	case ".json":
+		return LogFormatJsonlog
+	case ".log":
+		return LogFormatStderr
+	}
+	return ""
+}
+
+// parses complete entries from data and returns the number of bytes consumed
+// incomplete trailing entries are left for the next read
+func parseLogFileData(format string, data []byte, serverName string) (int, []*SyslogLine) {
+	switch format {
+	case LogFormatCsvlog:
+		return parseCsvlogData(data, serverName)
+	case LogFormatJsonlog:
+		return parseJsonlogData(data, serverName)
+	default:
+		return parseStderrData(data, serverName)
+	}
+}
+
+func parseStderrData(data []byte, serverName string) (int, []*SyslogLine) {
+	consumed := bytes.LastIndexByte(data, '\n') + 1
+	if consumed == 0 {
+		return 0, nil
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(data[:consumed-1]), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		// multi-line queries are continued with a leading tab and detail lines follow their entry
+		isContinuation := strings.HasPrefix(line, "\t") || stderrContinuationRegex.MatchString(line)
+		if isContinuation && len(entries) > 0 {
+			// keep the line breaks so multi-line queries and plans keep their structure
+			entries[len(entries)-1] += "\n" + line
+		} else {
+			entries = append(entries, line)
+		}
+	}
+
+	var lines []*SyslogLine
+	for _, entry := range entries {
+		var pid string
+		if match := stderrPidRegex.FindStringSubmatch(entry); match != nil {
+			pid = match[1]
+		}
+
+		lines = append(lines, &SyslogLine{
+			color:     serverName,
+			message:   entry,
+			process:   postgresProcessPrefix + pid,
+			timestamp: parseStderrTimestamp(entry),
+		})
+	}
+
+	return consumed, lines
+}
+
+func parseStderrTimestamp(entry string) string {
+	match := stderrTimestampRegex.FindStringSubmatch(entry)
+	if match == nil {
+		return time.Now().UTC().Format(time.RFC3339)
+	}
+	return parseLogFileTimestamp(match[1] + " " + match[2])
+}
+
+// parses 2022-08-11 03:22:11.987 UTC into an RFC 3339 timestamp
+func parseLogFileTimestamp(timestamp string) string {
+	parsed, err := time.Parse("2006-01-02 15:04:05.999 MST", timestamp)
+	if err != nil {
+		parsed, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			parsed = time.Now()
+		}
+	}
+	return parsed.UTC().Format(time.RFC3339)
+}
+
+func parseCsvlogData(data []byte, serverName string) (int, []*SyslogLine) {
+	var lines []*SyslogLine
+	var consumed int
+
+	// records end at a newline outside of a quoted field since messages can contain newlines
+	inQuotes := false
+	start := 0
+	for i, b := range data {
+		if b == '"' {
+			inQuotes = !inQuotes
+		}
+		if b != '\n' || inQuotes {
+			continue
+		}
+
+		record, err := csv.NewReader(bytes.NewReader(data[start:i])).Read()
+		start = i + 1
+		consumed = start
+		if err != nil || len(record) <= csvApplicationName {
+			continue
+		}
+
+		pid, _ := strconv.ParseInt(record[csvProcessID], 10, 64)
+		fields := db.LogFields{
+			Pid:         pid,
+			User:        record[csvUserName],
+			Database:    record[csvDatabaseName],
+			Application: record[csvApplicationName],
+			Client:      connectionFromPortRegex.ReplaceAllString(record[csvConnectionFrom], ""),
+			SqlState:    record[csvSqlStateCode],
+			SessionID:   record[csvSessionID],
+		}
+		if len(record) > csvBackendType {
+			fields.BackendType = record[csvBackendType]
+		}
+		if len(record) > csvQueryID {
+			fields.QueryId, _ = strconv.ParseInt(record[csvQueryID], 10, 64)
+		}
+
+		lines = append(lines, newLogFileSyslogLine(
+			serverName,
+			parseLogFileTimestamp(record[csvLogTime]),
+			fields,
+			record[csvErrorSeverity],
+			record[csvMessage],
+			record[csvDetail],
+			record[csvQuery],
+		))
+	}
+
+	return consumed, lines
+}
+
+func parseJsonlogData(data []byte, serverName string) (int, []*SyslogLine) {
+	consumed := bytes.LastIndexByte(data, '\n') + 1
+	if consumed == 0 {
+		return 0, nil
+	}
+
+	var lines []*SyslogLine
+	for _, line := range bytes.Split(data[:consumed-1], []byte("\n")) {
+		var entry jsonLogEntry
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			continue
+		}
+
+		fields := db.LogFields{
+			Pid:         entry.Pid,
+			User:        entry.User,
+			Database:    entry.Dbname,
+			Application: entry.ApplicationName,
+			Client:      entry.RemoteHost,
+			SqlState:    entry.StateCode,
+			QueryId:     entry.QueryID,
+			SessionID:   entry.SessionID,
+			BackendType: entry.BackendType,
+		}
+
+		lines = append(lines, newLogFileSyslogLine(
+			serverName,
+			parseLogFileTimestamp(entry.Timestamp),
+			fields,
+			entry.ErrorSeverity,
+			entry.Message,
+			entry.Detail,
+			entry.Statement,
+		))
+	}
+
+	return consumed, lines
+}
+
+// structured log entries already have their fields so only the message is parsed
+// ex. LOG:  duration: 1.0 ms  statement: select 1
+func newLogFileSyslogLine(serverName string, timestamp string, fields db.LogFields, severity string, message string, detail string, statement string) *SyslogLine {
+	message = severity + ":  " + message
+	if detail != "" {
+		message += " DETAIL:  " + detail
+	}
+	if statement != "" {
+		message += " STATEMENT:  " + statement
+	}
+
+	return &SyslogLine{
+		color:     serverName,
+		fields:    &fields,
+		message:   message,
+		process:   postgresProcessPrefix + strconv.FormatInt(fields.Pid, 10),
+		timestamp: timestamp,
+	}
+}
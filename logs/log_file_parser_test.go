@@ -0,0 +1,93 @@
+package logs
+
+import (
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLogFormat(t *testing.T) {
+	assert.Equal(t, LogFormatStderr, detectLogFormat("/var/log/postgresql/postgresql-2022-08-11_000000.log"))
+	assert.Equal(t, LogFormatCsvlog, detectLogFormat("postgresql-2022-08-11_000000.csv"))
+	assert.Equal(t, LogFormatJsonlog, detectLogFormat("postgresql-2022-08-11_000000.json"))
+	assert.Equal(t, "", detectLogFormat("postgresql.conf"))
+}
+
+func TestParseStderrData(t *testing.T) {
+	data := "2022-08-11 03:22:11.987 UTC [278908] LOG:  duration: 2681.599 ms  statement: SELECT *\n" +
+		"\tFROM users\n" +
+		"2022-08-11 03:22:12.001 UTC [278910] LOG:  temporary file: path \"base/pgsql_tmp/pgsql_tmp278910.0\", size 8192\n" +
+		"2022-08-11 03:22:12.001 UTC [278910] STATEMENT:  SELECT * FROM events ORDER BY id\n" +
+		"2022-08-11 03:22:13.000 UTC [278911] LOG:  incomplete"
+
+	consumed, lines := parseStderrData([]byte(data), "GREEN")
+
+	// the incomplete last line is left for the next read
+	assert.Equal(t, len(data)-len("2022-08-11 03:22:13.000 UTC [278911] LOG:  incomplete"), consumed)
+	assert.Equal(t, 2, len(lines))
+
+	assert.Equal(t, &SyslogLine{
+		color:     "GREEN",
+		message:   "2022-08-11 03:22:11.987 UTC [278908] LOG:  duration: 2681.599 ms  statement: SELECT *\n\tFROM users",
+		process:   "postgres.278908",
+		timestamp: "2022-08-11T03:22:11Z",
+	}, lines[0])
+
+	// continuation lines keep their line breaks like logplex parts
+	parsed := parseSqlSyslogLine(lines[0])
+	assert.Equal(t, "SELECT *\n\tFROM users", parsed.SlowQuery.Raw)
+	assert.Equal(t, int64(1660188131), parsed.SlowQuery.MeasuredAt)
+
+	parsed = parseSqlSyslogLine(lines[1])
+	assert.Equal(t, int64(8192), parsed.TempFile.SizeBytes)
+	assert.Equal(t, "SELECT * FROM events ORDER BY id", parsed.TempFile.Raw)
+}
+
+func TestParseCsvlogData(t *testing.T) {
+	record := `2022-08-11 03:22:11.987 UTC,"app","prod",278908,"10.0.0.1:5432",62f474f2.4417c,4,"SELECT",2022-08-11 03:18:10 UTC,34/62754118,0,LOG,00000,"duration: 2681.599 ms  statement: SELECT *` + "\n" + `FROM ""users""",,,,,,,,,"sidekiq","client backend",,0`
+	partial := `2022-08-11 03:22:12.987 UTC,"app","prod",278908,"10.0.0.1:5432",62f474f2.4417c,5,"SELECT",2022-08-11 03:18:10 UTC,34/62754118,0,LOG,00000,"duration: 1.0 ms  statement: SELECT`
+	data := record + "\n" + partial
+
+	consumed, lines := parseCsvlogData([]byte(data), "GREEN")
+
+	assert.Equal(t, len(record)+1, consumed)
+	assert.Equal(t, 1, len(lines))
+	assert.Equal(t, "LOG:  duration: 2681.599 ms  statement: SELECT *\nFROM \"users\"", lines[0].message)
+	assert.Equal(t, "postgres.278908", lines[0].process)
+	assert.Equal(t, "2022-08-11T03:22:11Z", lines[0].timestamp)
+
+	parsed := parseSqlSyslogLine(lines[0])
+	assert.Equal(t, "00000", parsed.SlowQuery.SqlErrorCode)
+	assert.Equal(t, db.LogFields{
+		Pid:         278908,
+		User:        "app",
+		Database:    "prod",
+		Application: "sidekiq",
+		Client:      "10.0.0.1",
+		SqlState:    "00000",
+		SessionID:   "62f474f2.4417c",
+		BackendType: "client backend",
+	}, parsed.SlowQuery.Fields)
+	assert.Equal(t, 2681.599, parsed.SlowQuery.DurationMs)
+	assert.Equal(t, "SELECT *\nFROM \"users\"", parsed.SlowQuery.Raw)
+}
+
+func TestParseJsonlogData(t *testing.T) {
+	data := `{"timestamp":"2022-08-11 03:22:11.987 UTC","user":"app","dbname":"prod","pid":278908,"error_severity":"LOG","state_code":"00000","message":"temporary file: path \"base/pgsql_tmp/pgsql_tmp278908.0\", size 104857600","statement":"SELECT * FROM users ORDER BY name"}` + "\n" +
+		"not json\n" +
+		`{"timestamp":"2022-08-11 03:22:12.987 UTC"`
+
+	consumed, lines := parseJsonlogData([]byte(data), "GREEN")
+
+	assert.Equal(t, len(data)-len(`{"timestamp":"2022-08-11 03:22:12.987 UTC"`), consumed)
+	assert.Equal(t, 1, len(lines))
+
+	parsed := parseSqlSyslogLine(lines[0])
+	assert.Equal(t, "GREEN", parsed.TempFile.ServerConfigName)
+	assert.Equal(t, int64(104857600), parsed.TempFile.SizeBytes)
+	assert.Equal(t, "SELECT * FROM users ORDER BY name", parsed.TempFile.Raw)
+	assert.Equal(t, int64(1660188131), parsed.TempFile.MeasuredAt)
+	assert.Equal(t, int64(278908), parsed.TempFile.Fields.Pid)
+	assert.Equal(t, "prod", parsed.TempFile.Fields.Database)
+}
@@ -0,0 +1,249 @@
+package logs
+
+import (
+	"agent/db"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//
+// These functions build a parser from a server's log_line_prefix setting
+// so the fields it logs can be parsed out of each log line.
+//
+
+// a log line message starts with its severity after the prefix
+var severityRegex = `(?:DEBUG\d?|INFO|NOTICE|WARNING|ERROR|LOG|FATAL|PANIC|DETAIL|HINT|QUERY|CONTEXT|LOCATION|STATEMENT):`
+
+// used when the server's log_line_prefix isn't known yet - ex. before settings are loaded
+// heroku logs are prefixed with sql_error_code = 00000 followed by key="value" metadata
+var fallbackPrefixRegex = regexp.MustCompile(`(?s)^(?:sql_error_code = (?P<sqlstate>\w+)\s*)?(?P<metadata>.*?)\s*(?P<rest>` + severityRegex + `.*)$`)
+var herokuMetadataRegex = regexp.MustCompile(`(\w+)\s*=\s*"([^"]*)"`)
+
+// %r logs the remote port in parentheses - ex. 10.0.0.1(5432)
+var remotePortRegex = regexp.MustCompile(`\(\d+\)$`)
+
+// log_line_prefix escapes - https://www.postgresql.org/docs/current/runtime-config-logging.html#GUC-LOG-LINE-PREFIX
+var logLinePrefixEscapes = map[byte]struct {
+	field string
+	regex string
+}{
+	'a': {"application", `.*?`},
+	'u': {"user", `.*?`},
+	'd': {"database", `.*?`},
+	'r': {"client", `.*?`},
+	'h': {"client", `.*?`},
+	'L': {"", `.*?`},
+	'b': {"backend_type", `.*?`},
+	'p': {"pid", `\d+`},
+	'P': {"", `\d*`},
+	't': {"", `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} \S+`},
+	'm': {"", `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d+ \S+`},
+	'n': {"", `\d+(?:\.\d+)?`},
+	'i': {"", `.*?`},
+	'e': {"sqlstate", `[0-9A-Z]{5}`},
+	'c': {"session_id", `[0-9a-f]+\.[0-9a-f]+`},
+	'l': {"", `\d+`},
+	's': {"", `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} \S+`},
+	'v': {"", `\S*`},
+	'x': {"", `\d+`},
+	'Q': {"query_id", `-?\d+`},
+}
+
+type LogLinePrefix struct {
+	regex *regexp.Regexp
+
+	// matches the prefix repeated at the end of an entry before the next entry in a multi-line message
+	trailingRegex *regexp.Regexp
+}
+
+// compiled prefixes by log_line_prefix value since compiling for each line is expensive
+var compiledLogLinePrefixes = struct {
+	prefixes map[string]*LogLinePrefix
+	mu       sync.Mutex
+}{prefixes: make(map[string]*LogLinePrefix)}
+
+func compileLogLinePrefix(prefix string) (*LogLinePrefix, error) {
+	var builder strings.Builder
+
+	usedFields := make(map[string]bool)
+	optional := false
+
+	// the prefix and message are trimmed of leading whitespace before matching
+	prefix = strings.TrimLeft(prefix, " \t")
+
+	for i := 0; i < len(prefix); i++ {
+		if prefix[i] != '%' || i == len(prefix)-1 {
+			builder.WriteString(regexp.QuoteMeta(string(prefix[i])))
+			continue
+		}
+
+		// escapes can be padded to a width - ex. %-10u
+		i++
+		padded := false
+		for i < len(prefix)-1 && (prefix[i] == '-' || (prefix[i] >= '0' && prefix[i] <= '9')) {
+			padded = true
+			i++
+		}
+
+		escape := prefix[i]
+		switch escape {
+		case '%':
+			builder.WriteString(`%`)
+			continue
+		case 'q':
+			// everything after %q is only logged by session processes
+			builder.WriteString(`(?:`)
+			optional = true
+			continue
+		}
+
+		pattern := `.*?`
+		field := ""
+		if known, ok := logLinePrefixEscapes[escape]; ok {
+			pattern = known.regex
+			field = known.field
+		}
+
+		if padded {
+			builder.WriteString(` *`)
+		}
+		if field != "" && !usedFields[field] {
+			usedFields[field] = true
+			builder.WriteString(`(?P<` + field + `>` + pattern + `)`)
+		} else {
+			builder.WriteString(`(?:` + pattern + `)`)
+		}
+		if padded {
+			builder.WriteString(` *`)
+		}
+	}
+
+	if optional {
+		builder.WriteString(`)?`)
+	}
+
+	// the message must start right after the prefix which keeps lazy fields from matching too little
+	regex, err := regexp.Compile(`(?s)^` + builder.String() + `\s*(?P<rest>` + severityRegex + `.*)$`)
+	if err != nil {
+		return nil, err
+	}
+
+	trailingRegex, err := regexp.Compile(`(?s)(?:^|\s)` + builder.String() + `\s*$`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogLinePrefix{regex: regex, trailingRegex: trailingRegex}, nil
+}
+
+// returns the compiled prefix for a server config name if its log_line_prefix is known
+func findLogLinePrefix(registry *db.LogLinePrefixRegistry, configName string) *LogLinePrefix {
+	if registry == nil {
+		return nil
+	}
+
+	prefix, ok := registry.Get(configName)
+	if !ok {
+		return nil
+	}
+
+	compiledLogLinePrefixes.mu.Lock()
+	defer compiledLogLinePrefixes.mu.Unlock()
+
+	compiled, ok := compiledLogLinePrefixes.prefixes[prefix]
+	if !ok {
+		// invalid prefixes are cached as nil and fall back to the default parser
+		compiled, _ = compileLogLinePrefix(prefix)
+		compiledLogLinePrefixes.prefixes[prefix] = compiled
+	}
+
+	return compiled
+}
+
+// returns the structured fields, the prefix metadata and the rest of the message after the prefix
+func (p *LogLinePrefix) Parse(message string) (db.LogFields, string, string, bool) {
+	captureGroups := matchRegex(p.regex, message)
+	rest, ok := captureGroups["rest"]
+	if !ok {
+		return db.LogFields{}, "", "", false
+	}
+
+	metadata := strings.TrimSpace(strings.TrimSuffix(message, rest))
+	return logFieldsFromCaptureGroups(captureGroups), metadata, rest, true
+}
+
+// removes the prefix of the next entry from the end of an entry
+func (p *LogLinePrefix) TrimTrailing(entry string) string {
+	loc := p.trailingRegex.FindStringIndex(entry)
+	if loc == nil {
+		return entry
+	}
+	return entry[:loc[0]]
+}
+
+// parses the log line prefix using the server's log_line_prefix, structured fields from csvlog or jsonlog,
+// or falls back to heroku's sql_error_code prefix and key="value" metadata
+func parseLogLinePrefix(line *SyslogLine, message string) (db.LogFields, string, string, bool) {
+	if line.fields != nil {
+		return *line.fields, "", message, true
+	}
+
+	if line.prefix != nil {
+		fields, metadata, rest, ok := line.prefix.Parse(message)
+		if ok {
+			return fields, metadata, rest, true
+		}
+	}
+
+	captureGroups := matchRegex(fallbackPrefixRegex, message)
+	rest, ok := captureGroups["rest"]
+	if !ok {
+		return db.LogFields{}, "", "", false
+	}
+
+	metadata := strings.TrimSpace(captureGroups["metadata"])
+	fields := herokuLogFields(metadata)
+	fields.SqlState = captureGroups["sqlstate"]
+
+	return fields, metadata, rest, true
+}
+
+func herokuLogFields(metadata string) db.LogFields {
+	captureGroups := make(map[string]string)
+	for _, match := range herokuMetadataRegex.FindAllStringSubmatch(metadata, -1) {
+		switch match[1] {
+		case "pid":
+			captureGroups["pid"] = match[2]
+		case "user":
+			captureGroups["user"] = match[2]
+		case "database":
+			captureGroups["database"] = match[2]
+		case "application_name":
+			captureGroups["application"] = match[2]
+		case "connection_source":
+			captureGroups["client"] = match[2]
+		case "session_id":
+			captureGroups["session_id"] = match[2]
+		}
+	}
+	return logFieldsFromCaptureGroups(captureGroups)
+}
+
+func logFieldsFromCaptureGroups(captureGroups map[string]string) db.LogFields {
+	pid, _ := strconv.ParseInt(captureGroups["pid"], 10, 64)
+	queryId, _ := strconv.ParseInt(captureGroups["query_id"], 10, 64)
+
+	return db.LogFields{
+		Pid:         pid,
+		User:        captureGroups["user"],
+		Database:    captureGroups["database"],
+		Application: captureGroups["application"],
+		Client:      remotePortRegex.ReplaceAllString(captureGroups["client"], ""),
+		SqlState:    captureGroups["sqlstate"],
+		QueryId:     queryId,
+		SessionID:   captureGroups["session_id"],
+		BackendType: captureGroups["backend_type"],
+	}
+}
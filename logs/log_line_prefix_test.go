@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileLogLinePrefix(t *testing.T) {
+	prefix, err := compileLogLinePrefix("%t [%p]: [%l-1] user=%u,db=%d,app=%a,client=%r ")
+	assert.Nil(t, err)
+
+	fields, metadata, rest, ok := prefix.Parse("2022-08-11 03:22:11 UTC [278908]: [4-1] user=app,db=prod,app=psql,client=10.0.0.1(52144) LOG:  duration: 1.000 ms  statement: SELECT 1")
+	assert.True(t, ok)
+	assert.Equal(t, db.LogFields{
+		Pid:         278908,
+		User:        "app",
+		Database:    "prod",
+		Application: "psql",
+		Client:      "10.0.0.1",
+	}, fields)
+	assert.Equal(t, "2022-08-11 03:22:11 UTC [278908]: [4-1] user=app,db=prod,app=psql,client=10.0.0.1(52144)", metadata)
+	assert.Equal(t, "LOG:  duration: 1.000 ms  statement: SELECT 1", rest)
+}
+
+func TestCompileLogLinePrefixPadding(t *testing.T) {
+	prefix, err := compileLogLinePrefix("%m %-6p %c %b %% ")
+	assert.Nil(t, err)
+
+	fields, _, rest, ok := prefix.Parse("2022-08-11 03:22:11.987 UTC 4211   62f474f2.4417c checkpointer % LOG:  checkpoint starting: time")
+	assert.True(t, ok)
+	assert.Equal(t, int64(4211), fields.Pid)
+	assert.Equal(t, "62f474f2.4417c", fields.SessionID)
+	assert.Equal(t, "checkpointer", fields.BackendType)
+	assert.Equal(t, "LOG:  checkpoint starting: time", rest)
+}
+
+func TestCompileLogLinePrefixSessionOnly(t *testing.T) {
+	prefix, err := compileLogLinePrefix("[%p] %q%u@%d ")
+	assert.Nil(t, err)
+
+	// background processes don't log anything after %q
+	fields, _, rest, ok := prefix.Parse("[4211] LOG:  checkpoint starting: time")
+	assert.True(t, ok)
+	assert.Equal(t, int64(4211), fields.Pid)
+	assert.Equal(t, "", fields.User)
+	assert.Equal(t, "LOG:  checkpoint starting: time", rest)
+
+	_, _, _, ok = prefix.Parse("not a postgres log line")
+	assert.False(t, ok)
+}
+
+func TestParseLogLinePrefixFallback(t *testing.T) {
+	// servers without a known log_line_prefix use heroku's format
+	line := &SyslogLine{color: "UNKNOWN"}
+
+	fields, metadata, rest, ok := parseLogLinePrefix(line, `sql_error_code = 28000 pid="123" user="app" database="prod" connection_source="10.0.0.1(5432)" FATAL:  password authentication failed`)
+	assert.True(t, ok)
+	assert.Equal(t, db.LogFields{
+		Pid:      123,
+		User:     "app",
+		Database: "prod",
+		Client:   "10.0.0.1",
+		SqlState: "28000",
+	}, fields)
+	assert.Equal(t, `pid="123" user="app" database="prod" connection_source="10.0.0.1(5432)"`, metadata)
+	assert.Equal(t, "FATAL:  password authentication failed", rest)
+}
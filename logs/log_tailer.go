@@ -0,0 +1,304 @@
+package logs
+
+import (
+	"agent/logger"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	logTailerPollInterval = 1 * time.Second
+
+	// max bytes read from a single file per poll so a large backlog doesn't block other files
+	maxLogFileReadBytes = 16 * 1024 * 1024
+)
+
+// read offsets per log file path that are checkpointed to a state file
+type LogFileState struct {
+	Offsets    map[string]int64  `json:"offsets"`
+	Identities map[string]string `json:"identities,omitempty"` // device and inode of each file
+	path       string
+	loaded     bool
+	changed    bool
+	mu         sync.Mutex
+}
+
+// LogTailer follows postgres log files in log_directory through rotation and truncation
+type LogTailer struct {
+	directory  string
+	format     string
+	serverName string
+	state      *LogFileState
+	handle     func(lines []*SyslogLine)
+}
+
+func NewLogTailer(directory string, format string, serverName string, statePath string, handle func(lines []*SyslogLine)) *LogTailer {
+	return &LogTailer{
+		directory:  directory,
+		format:     format,
+		serverName: serverName,
+		state:      LoadLogFileState(statePath),
+		handle:     handle,
+	}
+}
+
+// starts tailing log files when a log directory is configured
+func (s *Server) StartLogTailer() {
+	if s.config.LogDirectory == "" {
+		return
+	}
+
+	if s.config.LogServerName == "" {
+		logger.Warn("Not tailing log files: LOG_SERVER_NAME is not set", "directory", s.config.LogDirectory)
+		return
+	}
+
+	// a temporary default would be lost on restarts and silently skip the lines logged in between
+	if s.config.LogStateFile == "" {
+		logger.Warn("Not tailing log files: LOG_STATE_FILE is not set", "directory", s.config.LogDirectory)
+		return
+	}
+
+	tailer := NewLogTailer(s.config.LogDirectory, s.config.LogFormat, s.config.LogServerName, s.config.LogStateFile, s.handleLogFileLines)
+	if !tailer.state.loaded {
+		logger.Warn("No log state file found: tailing log files from their end", "path", s.config.LogStateFile)
+	}
+
+	logger.Info("Tailing log files", "directory", s.config.LogDirectory, "format", s.config.LogFormat)
+
+	go func() {
+		for {
+			tailer.Poll()
+			time.Sleep(logTailerPollInterval)
+		}
+	}()
+}
+
+func (s *Server) handleLogFileLines(lines []*SyslogLine) {
+	s.stats.IncrementBy("logs.received", len(lines))
+	s.stats.IncrementBy("logs.postgres", len(lines))
+	s.setLogLinePrefixes(lines)
+
+	var parsedLines []*ParsedLogLine
+	for _, line := range lines {
+		if s.config.LogPostgresLogs || s.config.IsDevelopment() {
+			logger.Info("Log file line", "line", line.message)
+		}
+
+		if shouldHandleTestLogLine(line.message) {
+			s.handleLogTest(line.message)
+		}
+
+		parsed := parseSqlSyslogLine(line)
+		if parsed != nil {
+			parsedLines = append(parsedLines, parsed)
+		}
+	}
+
+	s.stats.IncrementBy("logs.handled", len(parsedLines))
+	s.handleParsedLogLines(parsedLines)
+}
+
+// reads any new complete entries from each log file and checkpoints the read offsets
+func (t *LogTailer) Poll() {
+	entries, err := os.ReadDir(t.directory)
+	if err != nil {
+		logger.Error("Log directory error", "err", err, "directory", t.directory)
+		return
+	}
+
+	// log files are named by their start time so they are read in order
+	present := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(t.directory, entry.Name())
+		format := detectLogFormat(path)
+		if format == "" || (t.format != "" && t.format != format) {
+			continue
+		}
+
+		present[path] = true
+		t.tailFile(path, format)
+	}
+
+	t.state.Prune(present)
+	t.state.loaded = true
+
+	err = t.state.Save()
+	if err != nil {
+		logger.Error("Log state file error", "err", err)
+	}
+}
+
+func (t *LogTailer) tailFile(path string, format string) {
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Error("Log file error", "err", err, "path", path)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	identity := logFileIdentity(info)
+
+	offset, ok := t.state.Offset(path)
+	if !ok && !t.state.loaded {
+		// without a previous checkpoint only new entries are read to avoid reporting old slow queries
+		offset = info.Size()
+	}
+
+	// the path now points to a different file - ex. rotated by renaming a new file over it
+	if previous := t.state.Identity(path); ok && previous != "" && identity != "" && previous != identity {
+		offset = 0
+	}
+
+	// the file was truncated when rotated with log_truncate_on_rotation
+	if info.Size() < offset {
+		offset = 0
+	}
+
+	if info.Size() == offset {
+		t.state.SetOffset(path, identity, offset)
+		return
+	}
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxLogFileReadBytes))
+	if err != nil {
+		logger.Error("Log file read error", "err", err, "path", path)
+		return
+	}
+
+	consumed, lines := parseLogFileData(format, data, t.serverName)
+
+	// skip entries larger than the read limit instead of getting stuck on them
+	if consumed == 0 && len(data) == maxLogFileReadBytes {
+		consumed = len(data)
+	}
+
+	t.state.SetOffset(path, identity, offset+int64(consumed))
+
+	if len(lines) > 0 {
+		t.handle(lines)
+	}
+}
+
+// loads saved offsets - a missing or invalid state file starts from the end of existing files
+func LoadLogFileState(path string) *LogFileState {
+	state := &LogFileState{
+		Offsets:    make(map[string]int64),
+		Identities: make(map[string]string),
+		path:       path,
+		// written on the first save so a restart resumes from it
+		changed: true,
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+
+	err = json.Unmarshal(contents, state)
+	if err != nil || state.Offsets == nil {
+		state.Offsets = make(map[string]int64)
+		state.Identities = make(map[string]string)
+		return state
+	}
+
+	// state files from before identities were tracked
+	if state.Identities == nil {
+		state.Identities = make(map[string]string)
+	}
+
+	state.changed = false
+
+	state.loaded = true
+	return state
+}
+
+func (s *LogFileState) Offset(path string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offset, ok := s.Offsets[path]
+	return offset, ok
+}
+
+func (s *LogFileState) Identity(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Identities[path]
+}
+
+func (s *LogFileState) SetOffset(path string, identity string, offset int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, ok := s.Offsets[path]; ok && previous == offset && s.Identities[path] == identity {
+		return
+	}
+
+	s.Offsets[path] = offset
+	s.Identities[path] = identity
+	s.changed = true
+}
+
+// removes offsets for log files that were deleted
+func (s *LogFileState) Prune(present map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for path := range s.Offsets {
+		if !present[path] {
+			delete(s.Offsets, path)
+			delete(s.Identities, path)
+			s.changed = true
+		}
+	}
+}
+
+// writes to a temp file and renames it so a crash never leaves a partial state file
+// the file is only written when an offset changed since the last save
+func (s *LogFileState) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.changed {
+		return nil
+	}
+
+	contents, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tempPath := s.path + ".tmp"
+	err = os.WriteFile(tempPath, contents, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tempPath, s.path)
+	if err != nil {
+		return err
+	}
+
+	s.changed = false
+	return nil
+}
@@ -0,0 +1,160 @@
+package logs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogHandler struct {
+	lines []*SyslogLine
+}
+
+func (h *testLogHandler) handle(lines []*SyslogLine) {
+	h.lines = append(h.lines, lines...)
+}
+
+func appendLogFile(t *testing.T, path string, contents string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	_, err = file.WriteString(contents)
+	assert.Nil(t, err)
+}
+
+func TestLogTailerPoll(t *testing.T) {
+	directory := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	logPath := filepath.Join(directory, "postgresql-2022-08-11_000000.log")
+
+	appendLogFile(t, logPath, "2022-08-11 03:22:10 UTC [1] LOG:  existing\n")
+
+	handler := &testLogHandler{}
+	tailer := NewLogTailer(directory, "", "GREEN", statePath, handler.handle)
+
+	// existing entries are skipped without a previous checkpoint
+	tailer.Poll()
+	assert.Equal(t, 0, len(handler.lines))
+
+	appendLogFile(t, logPath, "2022-08-11 03:22:11 UTC [1] LOG:  first\n2022-08-11 03:22:11 UTC [1] LOG:  partial")
+	tailer.Poll()
+	assert.Equal(t, 1, len(handler.lines))
+	assert.Contains(t, handler.lines[0].message, "first")
+
+	appendLogFile(t, logPath, " line\n")
+	tailer.Poll()
+	assert.Equal(t, 2, len(handler.lines))
+	assert.Contains(t, handler.lines[1].message, "partial line")
+
+	// a new file after rotation is read from the start
+	rotatedPath := filepath.Join(directory, "postgresql-2022-08-11_010000.log")
+	appendLogFile(t, rotatedPath, "2022-08-11 04:00:00 UTC [2] LOG:  rotated\n")
+	tailer.Poll()
+	assert.Equal(t, 3, len(handler.lines))
+	assert.Contains(t, handler.lines[2].message, "rotated")
+
+	// truncated files are read from the start
+	assert.Nil(t, os.WriteFile(logPath, []byte("2022-08-11 05:00:00 UTC [3] LOG:  truncated\n"), 0600))
+	tailer.Poll()
+	assert.Equal(t, 4, len(handler.lines))
+	assert.Contains(t, handler.lines[3].message, "truncated")
+
+	// deleted files are removed from the state
+	assert.Nil(t, os.Remove(rotatedPath))
+	tailer.Poll()
+	_, ok := tailer.state.Offset(rotatedPath)
+	assert.False(t, ok)
+}
+
+func TestLogTailerReplacedFile(t *testing.T) {
+	directory := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	logPath := filepath.Join(directory, "postgresql.log")
+
+	handler := &testLogHandler{}
+	tailer := NewLogTailer(directory, "", "GREEN", statePath, handler.handle)
+	tailer.Poll()
+
+	appendLogFile(t, logPath, "2022-08-11 03:22:10 UTC [1] LOG:  first\n")
+	tailer.Poll()
+	assert.Equal(t, 1, len(handler.lines))
+
+	// a new file renamed over the old one is read from the start even though it is larger than the offset
+	replacementPath := filepath.Join(t.TempDir(), "postgresql.log")
+	appendLogFile(t, replacementPath, "2022-08-11 04:00:00 UTC [2] LOG:  replaced\n2022-08-11 04:00:01 UTC [2] LOG:  second\n")
+	assert.Nil(t, os.Rename(replacementPath, logPath))
+
+	tailer.Poll()
+	assert.Equal(t, 3, len(handler.lines))
+	assert.Contains(t, handler.lines[1].message, "replaced")
+}
+
+func TestLogTailerSavesOnlyChanges(t *testing.T) {
+	directory := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	logPath := filepath.Join(directory, "postgresql.log")
+
+	handler := &testLogHandler{}
+	tailer := NewLogTailer(directory, "", "GREEN", statePath, handler.handle)
+	tailer.Poll()
+	_, err := os.Stat(statePath)
+	assert.Nil(t, err)
+
+	// an idle poll doesn't rewrite the state file
+	assert.Nil(t, os.Remove(statePath))
+	tailer.Poll()
+	_, err = os.Stat(statePath)
+	assert.True(t, os.IsNotExist(err))
+
+	appendLogFile(t, logPath, "2022-08-11 03:22:10 UTC [1] LOG:  first\n")
+	tailer.Poll()
+	_, err = os.Stat(statePath)
+	assert.Nil(t, err)
+}
+
+func TestLogTailerResumesFromState(t *testing.T) {
+	directory := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	logPath := filepath.Join(directory, "postgresql.csv")
+
+	handler := &testLogHandler{}
+	NewLogTailer(directory, "", "GREEN", statePath, handler.handle).Poll()
+
+	// written while the agent was restarting
+	appendLogFile(t, logPath, `2022-08-11 03:22:11.987 UTC,"app","prod",1,,,1,,,,0,LOG,00000,"duration: 1.0 ms  statement: SELECT 1",,,,,,,,,"psql"`+"\n")
+
+	restarted := NewLogTailer(directory, "", "GREEN", statePath, handler.handle)
+	restarted.Poll()
+	restarted.Poll()
+
+	assert.Equal(t, 1, len(handler.lines))
+	assert.Contains(t, handler.lines[0].message, "SELECT 1")
+}
+
+func TestLogTailerFormat(t *testing.T) {
+	directory := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	handler := &testLogHandler{}
+	tailer := NewLogTailer(directory, LogFormatJsonlog, "GREEN", statePath, handler.handle)
+	tailer.Poll()
+
+	appendLogFile(t, filepath.Join(directory, "postgresql.log"), "2022-08-11 03:22:10 UTC [1] LOG:  stderr\n")
+	appendLogFile(t, filepath.Join(directory, "postgresql.json"), `{"timestamp":"2022-08-11 03:22:11.987 UTC","pid":1,"error_severity":"LOG","message":"json"}`+"\n")
+	tailer.Poll()
+
+	assert.Equal(t, 1, len(handler.lines))
+	assert.Equal(t, "LOG:  json", handler.lines[0].message)
+}
+
+func TestLoadLogFileStateInvalid(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	assert.Nil(t, os.WriteFile(statePath, []byte("invalid"), 0600))
+
+	state := LoadLogFileState(statePath)
+	assert.False(t, state.loaded)
+	assert.Equal(t, map[string]int64{}, state.Offsets)
+}
@@ -0,0 +1,177 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// max partial log lines buffered while waiting for the rest of their segment
+	maxBufferedSegments = 1000
+
+	// heroku sends the parts of a segment within a few seconds of each other
+	segmentBufferTTL = 5 * time.Second
+)
+
+// decodes a logplex drain body of octet counted syslog frames - ex. 83 <40>1 2012-11-30T06:45:29+00:00 host app ...
+// https://devcenter.heroku.com/articles/log-drains#https-drains
+func decodeLogplexFrames(body []byte) ([]string, error) {
+	reader := bufio.NewReader(bytes.NewReader(body))
+
+	var frames []string
+	for {
+		frame, err := readSyslogFrame(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// a frame that doesn't start with a syslog priority means the octet count was wrong
+		if !strings.HasPrefix(frame, "<") {
+			return nil, errInvalidSyslogFrame
+		}
+
+		frames = append(frames, strings.TrimRight(frame, "\r\n"))
+	}
+
+	return frames, nil
+}
+
+// checks the Logplex-Msg-Count header against the number of decoded frames
+func logplexMsgCountMatches(header string, frames []string) bool {
+	if header == "" {
+		return true
+	}
+
+	count, err := strconv.Atoi(header)
+	return err == nil && count == len(frames)
+}
+
+type segmentKey struct {
+	color   string
+	process string
+	segment string
+}
+
+type bufferedSegment struct {
+	line      *SyslogLine
+	parts     map[int]string
+	updatedAt time.Time
+}
+
+// SegmentBuffer stitches together long log lines that heroku splits into [123-1], [123-2] parts
+// since the parts can be sent in separate drain requests
+type SegmentBuffer struct {
+	segments    map[segmentKey]*bufferedSegment
+	maxSegments int
+	ttl         time.Duration
+	now         func() time.Time
+	mu          sync.Mutex
+}
+
+func NewSegmentBuffer(maxSegments int, ttl time.Duration) *SegmentBuffer {
+	return &SegmentBuffer{
+		segments:    make(map[segmentKey]*bufferedSegment),
+		maxSegments: maxSegments,
+		ttl:         ttl,
+		now:         time.Now,
+	}
+}
+
+// buffers a part of a segment and returns any lines that are complete
+// a postgres process logs one segment at a time so a new segment completes the previous one
+func (b *SegmentBuffer) Add(line *SyslogLine, part int) []*SyslogLine {
+	if line.segment == "" {
+		return []*SyslogLine{line}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var completed []*SyslogLine
+	for key := range b.segments {
+		if key.color == line.color && key.process == line.process && key.segment != line.segment {
+			completed = append(completed, b.remove(key))
+		}
+	}
+
+	key := segmentKey{color: line.color, process: line.process, segment: line.segment}
+	buffered, ok := b.segments[key]
+	if !ok {
+		if len(b.segments) >= b.maxSegments {
+			completed = append(completed, b.remove(b.oldest()))
+		}
+
+		buffered = &bufferedSegment{line: line, parts: make(map[int]string)}
+		b.segments[key] = buffered
+	}
+
+	// parts can be repeated by a retried drain request
+	buffered.parts[part] = line.message
+	buffered.updatedAt = b.now()
+
+	return completed
+}
+
+// returns lines that haven't received another part within the ttl
+func (b *SegmentBuffer) Expire() []*SyslogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var expired []*SyslogLine
+	for key, buffered := range b.segments {
+		if b.now().Sub(buffered.updatedAt) >= b.ttl {
+			expired = append(expired, b.remove(key))
+		}
+	}
+
+	return expired
+}
+
+func (b *SegmentBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.segments)
+}
+
+func (b *SegmentBuffer) oldest() segmentKey {
+	var oldestKey segmentKey
+	var oldestAt time.Time
+	for key, buffered := range b.segments {
+		if oldestAt.IsZero() || buffered.updatedAt.Before(oldestAt) {
+			oldestKey = key
+			oldestAt = buffered.updatedAt
+		}
+	}
+	return oldestKey
+}
+
+// removes a segment and joins its parts in order
+func (b *SegmentBuffer) remove(key segmentKey) *SyslogLine {
+	buffered := b.segments[key]
+	delete(b.segments, key)
+
+	var parts []int
+	for part := range buffered.parts {
+		parts = append(parts, part)
+	}
+	sort.Ints(parts)
+
+	var message strings.Builder
+	for _, part := range parts {
+		message.WriteString(buffered.parts[part])
+	}
+
+	line := *buffered.line
+	line.message = message.String()
+	return &line
+}
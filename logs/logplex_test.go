@@ -0,0 +1,143 @@
+package logs
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func logplexFrame(message string) string {
+	return strconv.Itoa(len(message)) + " " + message
+}
+
+func TestDecodeLogplexFrames(t *testing.T) {
+	first := "<134>1 2022-08-11T03:22:11+00:00 host app postgres.278908 - [GREEN] [3-1]  sql_error_code = 00000 LOG:  duration: 1.000 ms  statement: SELECT *\nFROM users\n"
+	second := "<134>1 2022-08-11T03:22:12+00:00 host app postgres.278910 - [GREEN] [4-1]  sql_error_code = 00000 LOG:  checkpoint starting: time\n"
+
+	frames, err := decodeLogplexFrames([]byte(logplexFrame(first) + logplexFrame(second)))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(frames))
+
+	// newlines within a message are kept and the trailing newline is trimmed
+	assert.Equal(t, first[:len(first)-1], frames[0])
+	assert.Equal(t, second[:len(second)-1], frames[1])
+
+	assert.True(t, logplexMsgCountMatches("2", frames))
+	assert.True(t, logplexMsgCountMatches("", frames))
+	assert.False(t, logplexMsgCountMatches("3", frames))
+	assert.False(t, logplexMsgCountMatches("two", frames))
+}
+
+func TestDecodeLogplexFramesInvalid(t *testing.T) {
+	// the octet count doesn't match the message length
+	_, err := decodeLogplexFrames([]byte("10 <134>1 2022-08-11T03:22:11+00:00 host app postgres.278908 - LOG:  hello"))
+	assert.Equal(t, errInvalidSyslogFrame, err)
+}
+
+func TestParseSyslogFrame(t *testing.T) {
+	line, part := parseSyslogFrame("<134>1 2022-08-11T03:22:11+00:00 host app postgres.278908 - [GREEN] [3-2]  FROM users\nWHERE id = 1")
+
+	assert.Equal(t, &SyslogLine{
+		color:     "GREEN",
+		message:   " FROM users\nWHERE id = 1",
+		process:   "postgres.278908",
+		segment:   "3",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}, line)
+	assert.Equal(t, 2, part)
+
+	line, _ = parseSyslogFrame("not syslog")
+	assert.Nil(t, line)
+}
+
+func TestSegmentBufferStitchesParts(t *testing.T) {
+	buffer := NewSegmentBuffer(10, time.Minute)
+
+	// parts arrive out of order in separate requests
+	assert.Empty(t, buffer.Add(&SyslogLine{color: "GREEN", process: "postgres.1", segment: "3", message: " FROM users"}, 2))
+	assert.Empty(t, buffer.Add(&SyslogLine{color: "GREEN", process: "postgres.1", segment: "3", message: "SELECT *"}, 1))
+	assert.Empty(t, buffer.Add(&SyslogLine{color: "GREEN", process: "postgres.2", segment: "7", message: "other"}, 1))
+
+	// the next segment from the same process completes the previous one
+	completed := buffer.Add(&SyslogLine{color: "GREEN", process: "postgres.1", segment: "4", message: "next"}, 1)
+	assert.Equal(t, 1, len(completed))
+	assert.Equal(t, "SELECT * FROM users", completed[0].message)
+	assert.Equal(t, "3", completed[0].segment)
+	assert.Equal(t, 2, buffer.Len())
+
+	// lines without a segment aren't buffered
+	completed = buffer.Add(&SyslogLine{process: "heroku-postgres", message: "sample#db_size=1bytes"}, 0)
+	assert.Equal(t, 1, len(completed))
+	assert.Equal(t, 2, buffer.Len())
+}
+
+func TestSegmentBufferExpire(t *testing.T) {
+	now := time.Unix(1660188131, 0)
+	buffer := NewSegmentBuffer(10, 5*time.Second)
+	buffer.now = func() time.Time { return now }
+
+	buffer.Add(&SyslogLine{color: "GREEN", process: "postgres.1", segment: "3", message: "SELECT 1"}, 1)
+
+	now = now.Add(4 * time.Second)
+	assert.Empty(t, buffer.Expire())
+
+	now = now.Add(time.Second)
+	expired := buffer.Expire()
+	assert.Equal(t, 1, len(expired))
+	assert.Equal(t, "SELECT 1", expired[0].message)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestSegmentBufferMaxSegments(t *testing.T) {
+	now := time.Unix(1660188131, 0)
+	buffer := NewSegmentBuffer(2, time.Minute)
+	buffer.now = func() time.Time { return now }
+
+	buffer.Add(&SyslogLine{process: "postgres.1", segment: "1", message: "first"}, 1)
+	now = now.Add(time.Second)
+	buffer.Add(&SyslogLine{process: "postgres.2", segment: "1", message: "second"}, 1)
+	now = now.Add(time.Second)
+
+	// the oldest segment is flushed to make room
+	completed := buffer.Add(&SyslogLine{process: "postgres.3", segment: "1", message: "third"}, 1)
+	assert.Equal(t, 1, len(completed))
+	assert.Equal(t, "first", completed[0].message)
+	assert.Equal(t, 2, buffer.Len())
+}
+
+func TestProcessLogFramesAcrossRequests(t *testing.T) {
+	server := newTestSyslogServer(nil)
+
+	server.processLogFrames([]string{
+		"<134>1 2022-08-11T03:22:11+00:00 host app postgres.278908 - [GREEN] [3-1]  sql_error_code = 00000 LOG:  duration: 2681.599 ms  statement: SELECT *",
+	}, "")
+	assert.Equal(t, 0, len(server.rawSlowQueryChannel))
+
+	server.processLogFrames([]string{
+		"<134>1 2022-08-11T03:22:11+00:00 host app postgres.278908 - [GREEN] [3-2]  FROM users",
+		"<134>1 2022-08-11T03:22:12+00:00 host app postgres.278908 - [GREEN] [4-1]  sql_error_code = 00000 LOG:  checkpoint starting: time",
+	}, "")
+
+	slowQuery := <-server.rawSlowQueryChannel
+	assert.Equal(t, "SELECT * FROM users", slowQuery.Raw)
+	assert.Equal(t, "GREEN", slowQuery.ServerConfigName)
+
+	stats := server.stats.ToMap()
+	assert.Equal(t, 2, stats["logs.received"])
+	assert.Equal(t, 3, stats["logs.postgres"])
+	assert.Equal(t, 1, stats["logs.handled"])
+}
+
+func TestProcessLogFramesRouting(t *testing.T) {
+	server := newTestSyslogServer(nil)
+
+	// lines without a color are routed to the drain token's server
+	server.processLogFrames([]string{
+		"<134>1 2022-08-11T03:22:11+00:00 host app postgres.278908 - sql_error_code = 00000 LOG:  duration: 1.000 ms  statement: SELECT 1",
+	}, "GREEN")
+
+	slowQuery := <-server.rawSlowQueryChannel
+	assert.Equal(t, "GREEN", slowQuery.ServerConfigName)
+}
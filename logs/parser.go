@@ -17,6 +17,13 @@ const logTestMessage = "NOTICE:  POSTGRES_MONITOR_AGENT_TEST"
 type ParsedLogLine struct {
 	Metrics   map[string]string
 	SlowQuery *db.SlowQuery
+	TempFile  *db.TempFile
+
+	VacuumEvent     *db.VacuumEvent
+	CheckpointEvent *db.CheckpointEvent
+	LockEvent       *db.LockEvent
+	LogError        *db.LogError
+	LoggedPlan      *db.LoggedPlan
 }
 
 func shouldHandleTestLogLine(line string) bool {
@@ -78,6 +85,11 @@ func parseLogLine(line string) []*ParsedLogLine {
 		return nil
 	}
 
+	return parseSyslogLines(syslogLines)
+}
+
+// parses metrics or queries out of syslog lines
+func parseSyslogLines(syslogLines []*SyslogLine) []*ParsedLogLine {
 	var parsedLogLines []*ParsedLogLine
 
 	for _, syslogLine := range syslogLines {
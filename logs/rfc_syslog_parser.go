@@ -0,0 +1,184 @@
+package logs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// These functions decode syslog messages sent by rsyslog, syslog-ng or postgres
+// directly to the syslog listener in either RFC 5424 or RFC 3164 (BSD) format.
+//
+
+const nilValue = "-"
+
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [STRUCTURED-DATA] MSG
+var rfc5424HeaderRegex = regexp.MustCompile(`^<(?P<pri>\d{1,3})>(?P<version>\d{1,2}) (?P<timestamp>\S+) (?P<hostname>\S+) (?P<appname>\S+) (?P<procid>\S+) (?P<msgid>\S+) `)
+
+// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+var rfc3164Regex = regexp.MustCompile(`^<(?P<pri>\d{1,3})>(?P<timestamp>[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (?P<hostname>\S+) (?P<tag>[^\[:\s]+)(?:\[(?P<procid>[^\]]+)\])?: ?(?P<message>.*)`)
+
+// postgres prefixes each line with [sequence-chunk] when syslog_sequence_numbers is on
+var postgresSequenceRegex = regexp.MustCompile(`^\[(?P<segment>\d+)-(?P<part>\d+)\] ?`)
+
+// decoded syslog message
+type RFCSyslogMessage struct {
+	Hostname  string
+	AppName   string
+	ProcID    string
+	Timestamp string // RFC 3339
+	Message   string
+}
+
+func parseRFCSyslogMessage(raw string, now time.Time) (*RFCSyslogMessage, bool) {
+	raw = strings.TrimRight(raw, "\r\n\x00")
+
+	if message, ok := parseRFC5424Message(raw); ok {
+		return message, true
+	}
+
+	return parseRFC3164Message(raw, now)
+}
+
+func parseRFC5424Message(raw string) (*RFCSyslogMessage, bool) {
+	match := rfc5424HeaderRegex.FindStringSubmatch(raw)
+	if match == nil {
+		return nil, false
+	}
+
+	header := make(map[string]string)
+	for i, name := range rfc5424HeaderRegex.SubexpNames() {
+		if i != 0 && name != "" {
+			header[name] = match[i]
+		}
+	}
+
+	rest, ok := skipStructuredData(raw[len(match[0]):])
+	if !ok {
+		return nil, false
+	}
+
+	// messages may start with a utf-8 byte order mark
+	message := strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+
+	return &RFCSyslogMessage{
+		Hostname:  nilToEmpty(header["hostname"]),
+		AppName:   nilToEmpty(header["appname"]),
+		ProcID:    nilToEmpty(header["procid"]),
+		Timestamp: nilToEmpty(header["timestamp"]),
+		Message:   message,
+	}, true
+}
+
+// structured data is either - or one or more [id key="value"] elements where values can escape ]
+func skipStructuredData(data string) (string, bool) {
+	if strings.HasPrefix(data, nilValue) {
+		return data[1:], true
+	}
+
+	i := 0
+	for i < len(data) && data[i] == '[' {
+		inValue := false
+		closed := false
+
+		for i++; i < len(data); i++ {
+			switch {
+			case data[i] == '\\' && inValue:
+				i++
+			case data[i] == '"':
+				inValue = !inValue
+			case data[i] == ']' && !inValue:
+				closed = true
+			}
+
+			if closed {
+				i++
+				break
+			}
+		}
+
+		if !closed {
+			return "", false
+		}
+	}
+
+	if i == 0 {
+		return "", false
+	}
+
+	return data[i:], true
+}
+
+func parseRFC3164Message(raw string, now time.Time) (*RFCSyslogMessage, bool) {
+	match := rfc3164Regex.FindStringSubmatch(raw)
+	if match == nil {
+		return nil, false
+	}
+
+	fields := make(map[string]string)
+	for i, name := range rfc3164Regex.SubexpNames() {
+		if i != 0 && name != "" {
+			fields[name] = match[i]
+		}
+	}
+
+	return &RFCSyslogMessage{
+		Hostname:  fields["hostname"],
+		AppName:   fields["tag"],
+		ProcID:    fields["procid"],
+		Timestamp: parseRFC3164Timestamp(fields["timestamp"], now),
+		Message:   fields["message"],
+	}, true
+}
+
+// RFC 3164 timestamps don't include a year or timezone so assume the current year in UTC
+// unless that would put the message in the future - ex. a December message received in January
+func parseRFC3164Timestamp(timestamp string, now time.Time) string {
+	parsed, err := time.Parse(time.Stamp, timestamp)
+	if err != nil {
+		return now.UTC().Format(time.RFC3339)
+	}
+
+	parsed = parsed.AddDate(now.UTC().Year(), 0, 0)
+	if parsed.After(now.UTC().Add(24 * time.Hour)) {
+		parsed = parsed.AddDate(-1, 0, 0)
+	}
+
+	return parsed.Format(time.RFC3339)
+}
+
+// converts a decoded message into a syslog line for the sql log parsers and returns the line's chunk number
+func (m *RFCSyslogMessage) toSyslogLine(configName string) (*SyslogLine, int) {
+	message := m.Message
+	var segment string
+	part := 1
+
+	match := postgresSequenceRegex.FindStringSubmatch(message)
+	if match != nil {
+		segment = match[1]
+		part, _ = strconv.Atoi(match[2])
+		message = message[len(match[0]):]
+
+		// postgres drops the line break when it splits a message and only splits long lines at whitespace
+		if part > 1 {
+			message = "\n" + message
+		}
+	}
+
+	return &SyslogLine{
+		color:     configName,
+		message:   message,
+		process:   postgresProcessPrefix + m.ProcID,
+		segment:   segment,
+		timestamp: m.Timestamp,
+	}, part
+}
+
+func nilToEmpty(value string) string {
+	if value == nilValue {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2022, 8, 11, 12, 0, 0, 0, time.UTC)
+
+func TestParseRFC5424Message(t *testing.T) {
+	raw := "<134>1 2022-08-11T03:22:11.987Z db-1.internal postgres 278908 - - [3-1] user=app,db=prod LOG:  duration: 2681.599 ms  statement: SELECT 1\n"
+
+	message, ok := parseRFCSyslogMessage(raw, testNow)
+
+	assert.True(t, ok)
+	assert.Equal(t, &RFCSyslogMessage{
+		Hostname:  "db-1.internal",
+		AppName:   "postgres",
+		ProcID:    "278908",
+		Timestamp: "2022-08-11T03:22:11.987Z",
+		Message:   "[3-1] user=app,db=prod LOG:  duration: 2681.599 ms  statement: SELECT 1",
+	}, message)
+}
+
+func TestParseRFC5424MessageStructuredData(t *testing.T) {
+	raw := `<134>1 2022-08-11T03:22:11Z db-1.internal postgres 278908 ID47 [exampleSDID@32473 iut="3" eventSource="App\]lication"][meta seq="1"] ` + "\ufeff" + `LOG:  checkpoint starting: time`
+
+	message, ok := parseRFCSyslogMessage(raw, testNow)
+
+	assert.True(t, ok)
+	assert.Equal(t, "LOG:  checkpoint starting: time", message.Message)
+
+	// unterminated structured data
+	_, ok = parseRFC5424Message(`<134>1 2022-08-11T03:22:11Z host postgres 1 - [meta seq="1" LOG: foo`)
+	assert.False(t, ok)
+}
+
+func TestParseRFC5424MessageNilValues(t *testing.T) {
+	message, ok := parseRFCSyslogMessage("<134>1 - - - - - -", testNow)
+
+	assert.True(t, ok)
+	assert.Equal(t, &RFCSyslogMessage{}, message)
+}
+
+func TestParseRFC3164Message(t *testing.T) {
+	raw := "<134>Aug  1 03:22:11 db-1 postgres[278908]: [3-1] LOG:  duration: 10.5 ms  statement: SELECT 1"
+
+	message, ok := parseRFCSyslogMessage(raw, testNow)
+
+	assert.True(t, ok)
+	assert.Equal(t, &RFCSyslogMessage{
+		Hostname:  "db-1",
+		AppName:   "postgres",
+		ProcID:    "278908",
+		Timestamp: "2022-08-01T03:22:11Z",
+		Message:   "[3-1] LOG:  duration: 10.5 ms  statement: SELECT 1",
+	}, message)
+}
+
+func TestParseRFC3164Timestamp(t *testing.T) {
+	assert.Equal(t, "2022-08-11T03:22:11Z", parseRFC3164Timestamp("Aug 11 03:22:11", testNow))
+
+	// messages from last year received just after new year
+	newYear := time.Date(2023, 1, 1, 0, 0, 5, 0, time.UTC)
+	assert.Equal(t, "2022-12-31T23:59:59Z", parseRFC3164Timestamp("Dec 31 23:59:59", newYear))
+
+	assert.Equal(t, "2022-08-11T12:00:00Z", parseRFC3164Timestamp("invalid", testNow))
+}
+
+func TestParseRFCSyslogMessageInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"not syslog",
+		"<134>",
+		"<134>1 2022-08-11T03:22:11Z host",
+	}
+
+	for _, raw := range invalid {
+		_, ok := parseRFCSyslogMessage(raw, testNow)
+		assert.False(t, ok, raw)
+	}
+}
+
+func TestToSyslogLine(t *testing.T) {
+	message := &RFCSyslogMessage{
+		Hostname:  "db-1",
+		AppName:   "postgres",
+		ProcID:    "278908",
+		Timestamp: "2022-08-11T03:22:11Z",
+		Message:   "[3-1] LOG:  duration: 10.5 ms  statement: SELECT 1",
+	}
+
+	line, part := message.toSyslogLine("GREEN")
+	assert.Equal(t, &SyslogLine{
+		color:     "GREEN",
+		message:   "LOG:  duration: 10.5 ms  statement: SELECT 1",
+		process:   "postgres.278908",
+		segment:   "3",
+		timestamp: "2022-08-11T03:22:11Z",
+	}, line)
+	assert.Equal(t, 1, part)
+
+	message.Message = "[3-2]  FROM users"
+	line, part = message.toSyslogLine("GREEN")
+	assert.Equal(t, "\n FROM users", line.message)
+	assert.Equal(t, 2, part)
+}
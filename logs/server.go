@@ -8,27 +8,57 @@ import (
 	"agent/util"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Server starts a gin based router for a Heroku postgres /logs endpoint
 type Server struct {
-	config              config.Config
-	logMetricChannel    chan data.LogMetrics
-	logTestChannel      chan string
-	rawSlowQueryChannel chan *db.SlowQuery
-	router              *gin.Engine
-	stats               *util.Stats
+	config               config.Config
+	logMetricChannel     chan data.LogMetrics
+	logTestChannel       chan string
+	rawSlowQueryChannel  chan *db.SlowQuery
+	rawTempFileChannel   chan *db.TempFile
+	rawVacuumChannel     chan *db.VacuumEvent
+	rawCheckpointChannel chan *db.CheckpointEvent
+	rawLockChannel       chan *db.LockEvent
+	rawLogErrorChannel   chan *db.LogError
+	rawPlanChannel       chan *db.LoggedPlan
+	logLinePrefixes      *db.LogLinePrefixRegistry
+	router               *gin.Engine
+	segmentBuffer        *SegmentBuffer
+	stats                *util.Stats
 }
 
-func NewServer(config config.Config, logMetricChannel chan data.LogMetrics, logTestChannel chan string, rawSlowQueryChannel chan *db.SlowQuery, stats *util.Stats) *Server {
+// channels the server sends parsed log lines to
+type ServerChannels struct {
+	LogMetrics     chan data.LogMetrics
+	LogTest        chan string
+	RawSlowQueries chan *db.SlowQuery
+	RawTempFiles   chan *db.TempFile
+	RawVacuums     chan *db.VacuumEvent
+	RawCheckpoints chan *db.CheckpointEvent
+	RawLocks       chan *db.LockEvent
+	RawLogErrors   chan *db.LogError
+	RawPlans       chan *db.LoggedPlan
+}
+
+func NewServer(config config.Config, channels ServerChannels, logLinePrefixes *db.LogLinePrefixRegistry, stats *util.Stats) *Server {
 	return &Server{
-		config:              config,
-		logMetricChannel:    logMetricChannel,
-		logTestChannel:      logTestChannel,
-		rawSlowQueryChannel: rawSlowQueryChannel,
-		stats:               stats,
+		config:               config,
+		logMetricChannel:     channels.LogMetrics,
+		logTestChannel:       channels.LogTest,
+		rawSlowQueryChannel:  channels.RawSlowQueries,
+		rawTempFileChannel:   channels.RawTempFiles,
+		rawVacuumChannel:     channels.RawVacuums,
+		rawCheckpointChannel: channels.RawCheckpoints,
+		rawLockChannel:       channels.RawLocks,
+		rawLogErrorChannel:   channels.RawLogErrors,
+		rawPlanChannel:       channels.RawPlans,
+		logLinePrefixes:      logLinePrefixes,
+		segmentBuffer:        NewSegmentBuffer(maxBufferedSegments, segmentBufferTTL),
+		stats:                stats,
 	}
 }
 
@@ -43,7 +73,7 @@ func (s *Server) Start() {
 	router.Use(gin.Recovery())
 
 	// authenticate that log messages are legitimate
-	router.Use(Authentication())
+	router.Use(Authentication(s.config, s.stats))
 
 	// ignore trusted proxies since we don't use any and there's a warning for this
 	router.SetTrustedProxies(nil)
@@ -55,6 +85,17 @@ func (s *Server) Start() {
 		c.Redirect(http.StatusFound, "https://postgresmonitor.com/app/setup/")
 	})
 
+	// flush segments that never received their remaining parts
+	go s.expireSegments()
+
+	// self-managed servers send logs over syslog instead of a logplex drain
+	s.StartSyslogListeners()
+	s.StartLogTailer()
+
+	if len(s.config.LogDrainTokens) == 0 {
+		logger.Warn("LOG_DRAIN_TOKENS is not set so all logplex drain requests will be rejected - set it to the token from heroku drains")
+	}
+
 	logger.Info("Starting /logs server", "port", s.config.Port)
 
 	// doesn't return
@@ -67,17 +108,84 @@ func (s *Server) PostLogs(c *gin.Context) {
 		return
 	}
 
-	line := string(body)
-	go s.processLogLine(line)
+	frames, err := decodeLogplexFrames(body)
+	if err != nil {
+		// fall back to newline delimited lines for drains that don't use octet counting
+		s.stats.Increment("logs.logplex.invalid_frames")
+		go s.processLogLine(string(body), c.GetString(serverNameContextKey))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	msgCount := c.GetHeader("Logplex-Msg-Count")
+	if !logplexMsgCountMatches(msgCount, frames) {
+		s.stats.Increment("logs.logplex.count_mismatch")
+		logger.Warn("Logplex message count mismatch", "header", msgCount, "frames", len(frames))
+	}
+
+	go s.processLogFrames(frames, c.GetString(serverNameContextKey))
 
 	c.Status(http.StatusOK)
 }
 
-func (s *Server) processLogLine(line string) {
+// serverName routes lines without a color to a server when the drain token is mapped to one
+func (s *Server) processLogFrames(frames []string, serverName string) {
+	s.stats.Increment("logs.received")
+
+	var completed []*SyslogLine
+	for _, frame := range frames {
+		if shouldHandleTestLogLine(frame) {
+			s.handleLogTest(frame)
+		}
+
+		if !shouldHandleLogLine(frame) {
+			continue
+		}
+
+		if s.config.LogPostgresLogs || s.config.IsDevelopment() {
+			logger.Info("Log line", "line", frame)
+		}
+
+		s.stats.Increment("logs.postgres")
+
+		line, part := parseSyslogFrame(frame)
+		if line == nil {
+			continue
+		}
+		routeSyslogLine(line, serverName)
+
+		// segments are only parsed once all of their parts have been received
+		completed = append(completed, s.segmentBuffer.Add(line, part)...)
+	}
+
+	s.handleSyslogLines(completed)
+}
+
+// runs forever
+func (s *Server) expireSegments() {
+	for {
+		time.Sleep(segmentBufferTTL / 2)
+		s.handleSyslogLines(s.segmentBuffer.Expire())
+	}
+}
+
+func (s *Server) handleSyslogLines(lines []*SyslogLine) {
+	s.setLogLinePrefixes(lines)
+	parsedLines := parseSyslogLines(lines)
+	if len(parsedLines) == 0 {
+		return
+	}
+
+	s.stats.IncrementBy("logs.handled", len(parsedLines))
+
+	s.handleParsedLogLines(parsedLines)
+}
+
+func (s *Server) processLogLine(line string, serverName string) {
 	s.stats.Increment("logs.received")
 
 	if shouldHandleLogLine(line) {
-		s.handleLogLine(line)
+		s.handleLogLine(line, serverName)
 	}
 
 	if shouldHandleTestLogLine(line) {
@@ -85,7 +193,7 @@ func (s *Server) processLogLine(line string) {
 	}
 }
 
-func (s *Server) handleLogLine(line string) {
+func (s *Server) handleLogLine(line string, serverName string) {
 	if s.config.LogPostgresLogs || s.config.IsDevelopment() {
 		logger.Info("Log line", "line", line)
 	}
@@ -93,7 +201,13 @@ func (s *Server) handleLogLine(line string) {
 	// any postgres log line or slow query that we currently handle
 	s.stats.Increment("logs.postgres")
 
-	parsedLines := parseLogLine(line)
+	syslogLines := parseSyslogLine(line)
+	for _, syslogLine := range syslogLines {
+		routeSyslogLine(syslogLine, serverName)
+	}
+	s.setLogLinePrefixes(syslogLines)
+
+	parsedLines := parseSyslogLines(syslogLines)
 	if parsedLines == nil || len(parsedLines) == 0 {
 		return
 	}
@@ -101,33 +215,66 @@ func (s *Server) handleLogLine(line string) {
 	// any postgres log line or slow query that we currently handle
 	s.stats.IncrementBy("logs.handled", len(parsedLines))
 
+	s.handleParsedLogLines(parsedLines)
+}
+
+// lines logged without a color are from the drain's primary database
+func routeSyslogLine(line *SyslogLine, serverName string) {
+	if line.color == "" && serverName != "" {
+		line.color = serverName
+	}
+}
+
+// lines are parsed with the log_line_prefix of the server they were logged by
+func (s *Server) setLogLinePrefixes(lines []*SyslogLine) {
+	for _, line := range lines {
+		line.prefix = findLogLinePrefix(s.logLinePrefixes, line.color)
+	}
+}
+
+// sends parsed metrics, queries and log events to their channels
+func (s *Server) handleParsedLogLines(parsedLines []*ParsedLogLine) {
 	for _, parsed := range parsedLines {
 		if len(parsed.Metrics) > 0 {
-			s.stats.Increment("logs.metric_lines")
-
-			select {
-			case s.logMetricChannel <- parsed.Metrics:
-				// sent
-			default:
-				s.stats.Increment("logs.metric_lines.dropped")
-				logger.Warn("Dropping log metrics: channel buffer full")
-			}
+			sendOrDrop(s.stats, s.logMetricChannel, parsed.Metrics, "logs.metric_lines", "log metrics")
 		}
-
 		if parsed.SlowQuery != nil {
-			s.stats.Increment("logs.slow_queries")
-
-			select {
-			case s.rawSlowQueryChannel <- parsed.SlowQuery:
-				// sent
-			default:
-				s.stats.Increment("logs.slow_queries.dropped")
-				logger.Warn("Dropping slow query: channel buffer full")
-			}
+			sendOrDrop(s.stats, s.rawSlowQueryChannel, parsed.SlowQuery, "logs.slow_queries", "slow query")
+		}
+		if parsed.TempFile != nil {
+			sendOrDrop(s.stats, s.rawTempFileChannel, parsed.TempFile, "logs.temp_files", "temp file")
+		}
+		if parsed.VacuumEvent != nil {
+			sendOrDrop(s.stats, s.rawVacuumChannel, parsed.VacuumEvent, "logs.vacuums", "vacuum event")
+		}
+		if parsed.CheckpointEvent != nil {
+			sendOrDrop(s.stats, s.rawCheckpointChannel, parsed.CheckpointEvent, "logs.checkpoints", "checkpoint event")
+		}
+		if parsed.LockEvent != nil {
+			sendOrDrop(s.stats, s.rawLockChannel, parsed.LockEvent, "logs.locks", "lock event")
+		}
+		if parsed.LogError != nil {
+			sendOrDrop(s.stats, s.rawLogErrorChannel, parsed.LogError, "logs.errors", "log error")
+		}
+		if parsed.LoggedPlan != nil {
+			sendOrDrop(s.stats, s.rawPlanChannel, parsed.LoggedPlan, "logs.plans", "logged plan")
 		}
 	}
 }
 
+// counts the value under stat and sends it without blocking or counts it as dropped when the channel is full
+func sendOrDrop[T any](stats *util.Stats, channel chan T, value T, stat string, name string) {
+	stats.Increment(stat)
+
+	select {
+	case channel <- value:
+		// sent
+	default:
+		stats.Increment(stat + ".dropped")
+		logger.Warn("Dropping " + name + ": channel buffer full")
+	}
+}
+
 func (s *Server) handleLogTest(line string) {
 	select {
 	case s.logTestChannel <- line:
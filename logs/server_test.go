@@ -0,0 +1,23 @@
+package logs
+
+import (
+	"agent/util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendOrDrop(t *testing.T) {
+	stats := &util.Stats{}
+	channel := make(chan string, 1)
+
+	sendOrDrop(stats, channel, "first", "logs.tests", "test")
+	sendOrDrop(stats, channel, "second", "logs.tests", "test")
+
+	assert.Equal(t, "first", <-channel)
+	assert.Equal(t, 0, len(channel))
+
+	counts := stats.ToMap()
+	assert.Equal(t, 2, counts["logs.tests"])
+	assert.Equal(t, 1, counts["logs.tests.dropped"])
+}
@@ -7,12 +7,26 @@ import (
 	"strings"
 )
 
-// error codes: https://www.postgresql.org/docs/current/errcodes-appendix.html
-var errorRegex = `(?P<error>\d+)\s*`
-var metadataRegex = `(?P<metadata>.*)\s*`
 var durationRegex = `LOG:\s+duration:\s+(?P<duration>\d+\.\d+) ms`
-var queryRegex = `\s+execute <\w+>: (?P<query>.*)`
-var sqlLogLineRegex = regexp.MustCompile(`sql_error_code = ` + errorRegex + metadataRegex + durationRegex + queryRegex)
+var queryRegex = `\s+(?:execute <[^>]+>|statement): (?P<query>.*)`
+
+// matched against the message after the log line prefix
+// multi-line queries keep their line breaks
+var sqlLogLineRegex = regexp.MustCompile(`(?s)^` + durationRegex + queryRegex)
+
+// temp files are logged when log_temp_files is enabled with the statement that created them in the next segment
+var tempFileRegex = `LOG:\s+temporary file: path "(?P<path>[^"]+)", size (?P<size>\d+)`
+var statementRegex = `(?:.*?STATEMENT:\s+(?P<query>.*))?`
+var tempFileLogLineRegex = regexp.MustCompile(`(?s)^` + tempFileRegex + statementRegex)
+
+// parsers for other log line formats that are tried in order when a line isn't a slow query
+var logEventParsers = []func(line *SyslogLine, message string, fields db.LogFields, timestamp int64) *ParsedLogLine{
+	parseTempFileSyslogLine,
+	parseVacuumSyslogLine,
+	parseCheckpointSyslogLine,
+	parseLockSyslogLine,
+	parseAutoExplainSyslogLine,
+}
 
 // NOTE: there are lots of different sql log line formats - ex. DETAIL:, ERROR: and STATEMENT:
 // for slow queries we only care about LOG: duration ...
@@ -20,19 +34,45 @@ var sqlLogLineRegex = regexp.MustCompile(`sql_error_code = ` + errorRegex + meta
 func parseSqlSyslogLine(line *SyslogLine) *ParsedLogLine {
 	timestamp := parseTimestamp(line.timestamp)
 
-	message := strings.TrimSpace(line.message)
+	// error codes: https://www.postgresql.org/docs/current/errcodes-appendix.html
+	fields, metadata, message, ok := parseLogLinePrefix(line, strings.TrimSpace(line.message))
+	if !ok {
+		return nil
+	}
+
 	captureGroups := matchRegexSqlMessage(message)
 
 	if len(captureGroups) == 0 {
+		// errors are counted even when another parser handles the line - ex. deadlock detected
+		logError := parseErrorSyslogLine(line, message, fields, timestamp)
+
+		for _, parse := range logEventParsers {
+			parsed := parse(line, message, fields, timestamp)
+			if parsed != nil {
+				parsed.LogError = logError
+				return parsed
+			}
+		}
+
+		if logError != nil {
+			return &ParsedLogLine{
+				LogError: logError,
+			}
+		}
 		return nil
 	}
 
 	duration, _ := strconv.ParseFloat(captureGroups["duration"], 64)
+
+	// the query ends at any entry that follows it - ex. DETAIL:  parameters: $1 = '1'
+	query, _ := splitLogEntries(line, captureGroups["query"])
+
 	slowQuery := &db.SlowQuery{
-		SqlErrorCode:     captureGroups["error"],
-		Metadata:         strings.TrimSpace(captureGroups["metadata"]),
+		SqlErrorCode:     fields.SqlState,
+		Metadata:         metadata,
 		DurationMs:       duration,
-		Raw:              captureGroups["query"],
+		Raw:              query,
+		Fields:           fields,
 		ServerConfigName: line.color,
 		MeasuredAt:       timestamp,
 	}
@@ -42,15 +82,40 @@ func parseSqlSyslogLine(line *SyslogLine) *ParsedLogLine {
 	}
 }
 
+func parseTempFileSyslogLine(line *SyslogLine, message string, fields db.LogFields, timestamp int64) *ParsedLogLine {
+	captureGroups := matchRegex(tempFileLogLineRegex, message)
+
+	if len(captureGroups) == 0 {
+		return nil
+	}
+
+	size, _ := strconv.ParseInt(captureGroups["size"], 10, 64)
+	tempFile := &db.TempFile{
+		SizeBytes:        size,
+		Raw:              strings.TrimSpace(captureGroups["query"]),
+		Fields:           fields,
+		ServerConfigName: line.color,
+		MeasuredAt:       timestamp,
+	}
+
+	return &ParsedLogLine{
+		TempFile: tempFile,
+	}
+}
+
 func matchRegexSqlMessage(message string) map[string]string {
+	return matchRegex(sqlLogLineRegex, message)
+}
+
+func matchRegex(regex *regexp.Regexp, message string) map[string]string {
 	captureGroups := make(map[string]string)
 
-	match := sqlLogLineRegex.FindStringSubmatch(message)
+	match := regex.FindStringSubmatch(message)
 	if len(match) == 0 {
 		return captureGroups
 	}
 
-	for i, name := range sqlLogLineRegex.SubexpNames() {
+	for i, name := range regex.SubexpNames() {
 		// the first match is the full message
 		if i != 0 && name != "" {
 			captureGroups[name] = match[i]
@@ -17,10 +17,19 @@ func TestParseSqlSyslogLineValid(t *testing.T) {
 
 	expected := &ParsedLogLine{
 		SlowQuery: &db.SlowQuery{
-			SqlErrorCode:     "00000",
-			Metadata:         "time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"278908\" proc_start_time=\"2022-08-11 03:18:10 UTC\" session_id=\"62f474f2.4417c\" vtid=\"34/62754118\" tid=\"0\" log_line=\"4\" database=\"dbiabc12i21234\" connection_source=\"[local]\" user=\"ueab123hn12abc\" application_name=\"sidekiq 6.2.10 app [6 of 16 busy] - 1.123.456.789:28429\"",
-			DurationMs:       2681.599,
-			Raw:              "SELECT \"users\".* FROM \"users\" INNER JOIN \"accounts\" ON \"accounts\".\"id\" = \"users\".\"account_id\" INNER JOIN \"subscriptions\" ON \"subscriptions\".\"account_id\" = \"accounts\".\"id\" WHERE (subscriptions.ended_at > '2022-08-11 03:22:09.289039') AND \"plans\".\"name\" = 'Free' GROUP BY \"users\".\"id\" HAVING (COUNT(alerts.id) >= 100) ORDER BY \"users\".\"id\" ASC LIMIT 1000 /*app:worker,job:AlertWorker*/",
+			SqlErrorCode: "00000",
+			Metadata:     "time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"278908\" proc_start_time=\"2022-08-11 03:18:10 UTC\" session_id=\"62f474f2.4417c\" vtid=\"34/62754118\" tid=\"0\" log_line=\"4\" database=\"dbiabc12i21234\" connection_source=\"[local]\" user=\"ueab123hn12abc\" application_name=\"sidekiq 6.2.10 app [6 of 16 busy] - 1.123.456.789:28429\"",
+			DurationMs:   2681.599,
+			Raw:          "SELECT \"users\".* FROM \"users\" INNER JOIN \"accounts\" ON \"accounts\".\"id\" = \"users\".\"account_id\" INNER JOIN \"subscriptions\" ON \"subscriptions\".\"account_id\" = \"accounts\".\"id\" WHERE (subscriptions.ended_at > '2022-08-11 03:22:09.289039') AND \"plans\".\"name\" = 'Free' GROUP BY \"users\".\"id\" HAVING (COUNT(alerts.id) >= 100) ORDER BY \"users\".\"id\" ASC LIMIT 1000 /*app:worker,job:AlertWorker*/",
+			Fields: db.LogFields{
+				Pid:         278908,
+				User:        "ueab123hn12abc",
+				Database:    "dbiabc12i21234",
+				Application: "sidekiq 6.2.10 app [6 of 16 busy] - 1.123.456.789:28429",
+				Client:      "[local]",
+				SqlState:    "00000",
+				SessionID:   "62f474f2.4417c",
+			},
 			ServerConfigName: "GREEN",
 			MeasuredAt:       1660188131,
 		},
@@ -33,7 +42,6 @@ func TestParseSqlSyslogLineValid(t *testing.T) {
 
 func TestParseSqlSyslogLineInvalid(t *testing.T) {
 	invalid_messages := []string{
-		"sql_error_code = 00000 LOG:  automatic analyze of table \"dbiabc12i21234.public.stats\" system usage: CPU: user: 0.46 s, system: 0.12 s, elapsed: 38.04 s",
 		"sql_error_code = 55P03 STATEMENT:  SELECT \"alerts\".* FROM \"alerts\" WHERE \"alerts\".\"id\" = 258324 LIMIT 1 FOR UPDATE NOWAIT /*app:worker.2,job:Job*/",
 		"sql_error_code = 00000 LOG:  restartpoint complete: wrote 123520 buffers (32.4%); 0 WAL file(s) added, 15 removed, 25 recycled; write=419.700 s, sync=0.016 s, total=419.834 s; sync files=301, longest=0.003 s, average=0.001 s; distance=689158 kB, estimate=804775 kB",
 		"sql_error_code = 00000 LOG:  recovery restart point at 32A8/A515CE48",
@@ -55,3 +63,68 @@ func TestParseSqlSyslogLineInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSqlSyslogLineTempFile(t *testing.T) {
+	// the temp file and statement segments are stitched together by the syslog parser
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   "sql_error_code = 00000 time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"278908\" LOG:  temporary file: path \"base/pgsql_tmp/pgsql_tmp278908.0\", size 104857600sql_error_code = 00000 time_ms = \"2022-08-11 03:22:11.987 UTC\" pid=\"278908\" STATEMENT:  SELECT * FROM users ORDER BY created_at DESC",
+		process:   "postgres.3811305",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	expected := &ParsedLogLine{
+		TempFile: &db.TempFile{
+			SizeBytes:        104857600,
+			Raw:              "SELECT * FROM users ORDER BY created_at DESC",
+			Fields:           db.LogFields{Pid: 278908, SqlState: "00000"},
+			ServerConfigName: "GREEN",
+			MeasuredAt:       1660188131,
+		},
+	}
+
+	assert.Equal(t, expected, parseSqlSyslogLine(line))
+}
+
+func TestParseSqlSyslogLineTempFileWithoutStatement(t *testing.T) {
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   "sql_error_code = 00000 LOG:  temporary file: path \"base/pgsql_tmp/pgsql_tmp278908.1\", size 8192",
+		process:   "postgres.3811305",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	parsed := parseSqlSyslogLine(line)
+
+	assert.Equal(t, int64(8192), parsed.TempFile.SizeBytes)
+	assert.Equal(t, "", parsed.TempFile.Raw)
+	assert.Nil(t, parsed.SlowQuery)
+}
+
+func TestParseSqlSyslogLineLogLinePrefix(t *testing.T) {
+	registry := &db.LogLinePrefixRegistry{}
+	registry.Register(&db.ServerID{ConfigName: "PREFIX"}, "%m [%p] %q%u@%d %a %h %e %Q ")
+
+	line := &SyslogLine{
+		color:     "PREFIX",
+		message:   "2022-08-11 03:22:11.987 UTC [278908] app@prod sidekiq 10.0.0.1 00000 -4218862395405245209 LOG:  duration: 2681.599 ms  statement: SELECT * FROM users",
+		process:   "postgres.278908",
+		timestamp: "2022-08-11T03:22:11+00:00",
+		prefix:    findLogLinePrefix(registry, "PREFIX"),
+	}
+
+	parsed := parseSqlSyslogLine(line)
+
+	assert.Equal(t, "SELECT * FROM users", parsed.SlowQuery.Raw)
+	assert.Equal(t, "00000", parsed.SlowQuery.SqlErrorCode)
+	assert.Equal(t, "2022-08-11 03:22:11.987 UTC [278908] app@prod sidekiq 10.0.0.1 00000 -4218862395405245209", parsed.SlowQuery.Metadata)
+	assert.Equal(t, db.LogFields{
+		Pid:         278908,
+		User:        "app",
+		Database:    "prod",
+		Application: "sidekiq",
+		Client:      "10.0.0.1",
+		SqlState:    "00000",
+		QueryId:     -4218862395405245209,
+	}, parsed.SlowQuery.Fields)
+}
@@ -0,0 +1,235 @@
+package logs
+
+import (
+	"agent/logger"
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// max udp datagram size
+	maxSyslogUDPMessageBytes = 65535
+
+	// octet counted frames larger than this are treated as malformed
+	maxSyslogFrameBytes = 1024 * 1024
+
+	// connections without any messages are closed after this long
+	syslogConnectionIdleTimeout = 10 * time.Minute
+
+	// accept retries back off up to this long - ex. when the process runs out of file descriptors
+	maxSyslogAcceptBackoff = time.Second
+)
+
+var errInvalidSyslogFrame = errors.New("invalid syslog frame")
+
+// starts any configured syslog listeners for self-managed servers
+func (s *Server) StartSyslogListeners() {
+	if s.config.SyslogUDPAddr != "" {
+		go s.listenSyslogUDP(s.config.SyslogUDPAddr)
+	}
+
+	if s.config.SyslogTCPAddr != "" {
+		listener, err := net.Listen("tcp", s.config.SyslogTCPAddr)
+		if err != nil {
+			logger.Error("Syslog TCP listener error", "err", err)
+		} else {
+			logger.Info("Starting syslog TCP listener", "addr", s.config.SyslogTCPAddr)
+			go s.acceptSyslogConnections(listener)
+		}
+	}
+
+	if s.config.SyslogTLSAddr != "" {
+		listener, err := s.listenSyslogTLS()
+		if err != nil {
+			logger.Error("Syslog TLS listener error", "err", err)
+		} else {
+			logger.Info("Starting syslog TLS listener", "addr", s.config.SyslogTLSAddr)
+			go s.acceptSyslogConnections(listener)
+		}
+	}
+}
+
+func (s *Server) listenSyslogTLS() (net.Listener, error) {
+	certificate, err := tls.LoadX509KeyPair(s.config.SyslogTLSCertFile, s.config.SyslogTLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return tls.Listen("tcp", s.config.SyslogTLSAddr, &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+func (s *Server) listenSyslogUDP(addr string) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		logger.Error("Syslog UDP listener error", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	logger.Info("Starting syslog UDP listener", "addr", addr)
+
+	buffer := make([]byte, maxSyslogUDPMessageBytes)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			logger.Error("Syslog UDP read error", "err", err)
+			continue
+		}
+
+		// each datagram is a single message
+		s.handleSyslogMessage(string(buffer[:n]))
+	}
+}
+
+func (s *Server) acceptSyslogConnections(listener net.Listener) {
+	defer listener.Close()
+
+	var backoff time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				logger.Info("Syslog listener closed", "addr", listener.Addr())
+				return
+			}
+
+			backoff = nextSyslogAcceptBackoff(backoff)
+			logger.Error("Syslog accept error", "err", err, "retryIn", backoff)
+			time.Sleep(backoff)
+			continue
+		}
+		backoff = 0
+
+		go s.readSyslogConnection(conn)
+	}
+}
+
+// doubles the delay between failed accepts so a persistent error doesn't spin the loop
+func nextSyslogAcceptBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return 5 * time.Millisecond
+	}
+	if backoff*2 > maxSyslogAcceptBackoff {
+		return maxSyslogAcceptBackoff
+	}
+	return backoff * 2
+}
+
+func (s *Server) readSyslogConnection(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(syslogConnectionIdleTimeout))
+
+		message, err := readSyslogFrame(reader)
+		if err != nil {
+			if err != io.EOF {
+				logger.Warn("Closing syslog connection", "err", err)
+			}
+			return
+		}
+
+		if message != "" {
+			s.handleSyslogMessage(message)
+		}
+	}
+}
+
+// reads a single message from a stream using octet counting (RFC 6587 3.4.1)
+// or falls back to newline delimited non-transparent framing (RFC 6587 3.4.2)
+func readSyslogFrame(reader *bufio.Reader) (string, error) {
+	// skip any trailers between frames
+	for {
+		next, err := reader.Peek(1)
+		if err != nil {
+			return "", err
+		}
+		if next[0] != '\n' && next[0] != '\r' && next[0] != ' ' && next[0] != 0 {
+			break
+		}
+		reader.ReadByte()
+	}
+
+	next, _ := reader.Peek(1)
+	if next[0] < '0' || next[0] > '9' {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+
+	size, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil || size <= 0 || size > maxSyslogFrameBytes {
+		return "", errInvalidSyslogFrame
+	}
+
+	frame := make([]byte, size)
+	_, err = io.ReadFull(reader, frame)
+	if err != nil {
+		return "", err
+	}
+
+	return string(frame), nil
+}
+
+func (s *Server) handleSyslogMessage(raw string) {
+	s.stats.Increment("logs.received")
+
+	if shouldHandleTestLogLine(raw) {
+		s.handleLogTest(raw)
+	}
+
+	message, ok := parseRFCSyslogMessage(raw, time.Now())
+	if !ok {
+		s.stats.Increment("logs.syslog.invalid")
+		return
+	}
+
+	configName, ok := s.syslogServerName(message)
+	if !ok {
+		s.stats.Increment("logs.syslog.unmapped")
+		return
+	}
+
+	line, part := message.toSyslogLine(configName)
+
+	if s.config.LogPostgresLogs || s.config.IsDevelopment() {
+		logger.Info("Syslog line", "configName", configName, "message", line.message)
+	}
+
+	s.stats.Increment("logs.postgres")
+
+	// postgres splits long and multi-line messages into [seq-N] chunks so segments are only parsed once complete
+	s.handleSyslogLines(s.segmentBuffer.Add(line, part))
+}
+
+// app names are more specific than hostnames since one host can run several postgres clusters
+func (s *Server) syslogServerName(message *RFCSyslogMessage) (string, bool) {
+	names := s.config.SyslogServerNames
+
+	if name, ok := names[message.AppName]; ok && message.AppName != "" {
+		return name, true
+	}
+	if name, ok := names[message.Hostname]; ok && message.Hostname != "" {
+		return name, true
+	}
+
+	name, ok := names["*"]
+	return name, ok
+}
@@ -0,0 +1,211 @@
+package logs
+
+import (
+	"agent/config"
+	"agent/data"
+	"agent/db"
+	"agent/util"
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSyslogFrameOctetCounting(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("11 hello\nworld5 howdy"))
+
+	frame, err := readSyslogFrame(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello\nworld", frame)
+
+	frame, err = readSyslogFrame(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "howdy", frame)
+
+	_, err = readSyslogFrame(reader)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadSyslogFrameNewlineDelimited(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("<134>1 first\r\n\n<134>1 second"))
+
+	frame, err := readSyslogFrame(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "<134>1 first", frame)
+
+	frame, err = readSyslogFrame(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "<134>1 second", frame)
+
+	_, err = readSyslogFrame(reader)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadSyslogFrameInvalid(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("99999999999 too large"))
+
+	_, err := readSyslogFrame(reader)
+	assert.Equal(t, errInvalidSyslogFrame, err)
+}
+
+func newTestSyslogServer(serverNames map[string]string) *Server {
+	return NewServer(
+		config.Config{SyslogServerNames: serverNames},
+		ServerChannels{
+			LogMetrics:     make(chan data.LogMetrics, 10),
+			LogTest:        make(chan string, 10),
+			RawSlowQueries: make(chan *db.SlowQuery, 10),
+			RawTempFiles:   make(chan *db.TempFile, 10),
+			RawVacuums:     make(chan *db.VacuumEvent, 10),
+			RawCheckpoints: make(chan *db.CheckpointEvent, 10),
+			RawLocks:       make(chan *db.LockEvent, 10),
+			RawLogErrors:   make(chan *db.LogError, 10),
+			RawPlans:       make(chan *db.LoggedPlan, 10),
+		},
+		&db.LogLinePrefixRegistry{},
+		&util.Stats{},
+	)
+}
+
+func TestHandleSyslogMessage(t *testing.T) {
+	server := newTestSyslogServer(map[string]string{"db-1.internal": "GREEN"})
+
+	server.handleSyslogMessage("<134>1 2022-08-11T03:22:11Z db-1.internal postgres 278908 - - [3-1] user=app,db=prod LOG:  duration: 2681.599 ms  statement: SELECT 1")
+
+	// the segment is buffered until another segment from the process or the ttl completes it
+	assert.Equal(t, 0, len(server.rawSlowQueryChannel))
+	expireTestSegments(server)
+
+	slowQuery := <-server.rawSlowQueryChannel
+	assert.Equal(t, "GREEN", slowQuery.ServerConfigName)
+	assert.Equal(t, 2681.599, slowQuery.DurationMs)
+	assert.Equal(t, "SELECT 1", slowQuery.Raw)
+	assert.Equal(t, "", slowQuery.SqlErrorCode)
+	assert.Equal(t, int64(1660188131), slowQuery.MeasuredAt)
+
+	stats := server.stats.ToMap()
+	assert.Equal(t, 1, stats["logs.slow_queries"])
+}
+
+func TestHandleSyslogMessageParts(t *testing.T) {
+	server := newTestSyslogServer(map[string]string{"db-1.internal": "GREEN"})
+
+	server.handleSyslogMessage("<134>1 2022-08-11T03:22:11Z db-1.internal postgres 278908 - - [4-1] LOG:  duration: 2681.599 ms  statement: SELECT *")
+	server.handleSyslogMessage("<134>1 2022-08-11T03:22:11Z db-1.internal postgres 278908 - - [4-2]  FROM users")
+
+	// the next segment from the same process completes the query
+	server.handleSyslogMessage("<134>1 2022-08-11T03:22:12Z db-1.internal postgres 278908 - - [5-1] LOG:  duration: 1.5 ms  statement: SELECT 2")
+
+	slowQuery := <-server.rawSlowQueryChannel
+	assert.Equal(t, "SELECT *\n FROM users", slowQuery.Raw)
+	assert.Equal(t, 0, len(server.rawSlowQueryChannel))
+
+	expireTestSegments(server)
+	slowQuery = <-server.rawSlowQueryChannel
+	assert.Equal(t, "SELECT 2", slowQuery.Raw)
+}
+
+func TestHandleSyslogMessageLogLinePrefix(t *testing.T) {
+	server := newTestSyslogServer(map[string]string{"db-1.internal": "GREEN"})
+	server.logLinePrefixes.Register(&db.ServerID{ConfigName: "GREEN"}, "[%p] %u@%d ")
+
+	server.handleSyslogMessage("<134>1 2022-08-11T03:22:11Z db-1.internal postgres 278908 - - [3-1] [278908] app@prod LOG:  duration: 2681.599 ms  statement: SELECT 1")
+	expireTestSegments(server)
+
+	slowQuery := <-server.rawSlowQueryChannel
+	assert.Equal(t, "SELECT 1", slowQuery.Raw)
+	assert.Equal(t, "app", slowQuery.Fields.User)
+	assert.Equal(t, "prod", slowQuery.Fields.Database)
+}
+
+func expireTestSegments(server *Server) {
+	server.segmentBuffer.now = func() time.Time { return time.Now().Add(segmentBufferTTL) }
+	server.handleSyslogLines(server.segmentBuffer.Expire())
+}
+
+func TestHandleSyslogMessageUnmapped(t *testing.T) {
+	server := newTestSyslogServer(map[string]string{"db-1.internal": "GREEN"})
+
+	server.handleSyslogMessage("<134>1 2022-08-11T03:22:11Z db-2.internal postgres 278908 - - LOG:  duration: 1.0 ms  statement: SELECT 1")
+	server.handleSyslogMessage("not syslog")
+
+	assert.Equal(t, 0, len(server.rawSlowQueryChannel))
+
+	stats := server.stats.ToMap()
+	assert.Equal(t, 1, stats["logs.syslog.unmapped"])
+	assert.Equal(t, 1, stats["logs.syslog.invalid"])
+}
+
+func TestSyslogServerName(t *testing.T) {
+	server := newTestSyslogServer(map[string]string{"db-1": "GREEN", "pg-reporting": "BLUE"})
+
+	name, ok := server.syslogServerName(&RFCSyslogMessage{Hostname: "db-1", AppName: "pg-reporting"})
+	assert.True(t, ok)
+	assert.Equal(t, "BLUE", name)
+
+	name, ok = server.syslogServerName(&RFCSyslogMessage{Hostname: "db-1", AppName: "postgres"})
+	assert.True(t, ok)
+	assert.Equal(t, "GREEN", name)
+
+	_, ok = server.syslogServerName(&RFCSyslogMessage{Hostname: "db-2", AppName: "postgres"})
+	assert.False(t, ok)
+
+	server.config.SyslogServerNames["*"] = "RED"
+	name, ok = server.syslogServerName(&RFCSyslogMessage{Hostname: "db-2", AppName: "postgres"})
+	assert.True(t, ok)
+	assert.Equal(t, "RED", name)
+}
+
+func TestReadSyslogConnection(t *testing.T) {
+	server := newTestSyslogServer(map[string]string{"*": "GREEN"})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go server.acceptSyslogConnections(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	message := "<134>Aug 11 03:22:11 db-1 postgres[278908]: LOG:  duration: 10.5 ms  statement: SELECT 1"
+	conn.Write([]byte(strconv.Itoa(len(message)) + " " + message))
+
+	select {
+	case slowQuery := <-server.rawSlowQueryChannel:
+		assert.Equal(t, "SELECT 1", slowQuery.Raw)
+	case <-time.After(5 * time.Second):
+		t.Error("Timed out waiting for slow query")
+	}
+}
+
+func TestAcceptSyslogConnectionsClosed(t *testing.T) {
+	server := newTestSyslogServer(map[string]string{"*": "GREEN"})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	done := make(chan bool)
+	go func() {
+		server.acceptSyslogConnections(listener)
+		done <- true
+	}()
+	listener.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Timed out waiting for accept loop to return")
+	}
+}
+
+func TestNextSyslogAcceptBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Millisecond, nextSyslogAcceptBackoff(0))
+	assert.Equal(t, 10*time.Millisecond, nextSyslogAcceptBackoff(5*time.Millisecond))
+	assert.Equal(t, maxSyslogAcceptBackoff, nextSyslogAcceptBackoff(800*time.Millisecond))
+	assert.Equal(t, maxSyslogAcceptBackoff, nextSyslogAcceptBackoff(maxSyslogAcceptBackoff))
+}
@@ -1,7 +1,9 @@
 package logs
 
 import (
+	"agent/db"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -13,9 +15,11 @@ import (
 // we parse out color and segment because multiline logs need their messages to be stitched together
 // and color and segment get in the way
 var processRegex = `(?P<process>\w+(\.|-)\w+) -`
-var optionalColorRegex = `\s?\[?(?P<color>\w+)?\]?`
-var optionalSegmentRegex = `\s?\[?(?P<segment>\d+)?(-\d+)?\]?`
-var logLineRegex = regexp.MustCompile(`\d+ <\d+>\d+ (?P<timestamp>.*) host app ` + processRegex + optionalColorRegex + optionalSegmentRegex + ` (?P<message>.*)`)
+var optionalColorRegex = `(?:\s?\[(?P<color>[A-Za-z_]\w*)\])?`
+var optionalSegmentRegex = `(?:\s?\[(?P<segment>\d+)(-(?P<part>\d+))?\])?`
+
+// the octet count is optional since logplex frames are decoded before parsing and messages can contain newlines
+var logLineRegex = regexp.MustCompile(`(?s)(?:\d+ )?<\d+>\d+ (?P<timestamp>.*) host app ` + processRegex + optionalColorRegex + optionalSegmentRegex + ` (?P<message>.*)`)
 
 // parsed postgres log line with minimal processing
 type SyslogLine struct {
@@ -24,6 +28,12 @@ type SyslogLine struct {
 	process   string // ex. postgres.12345
 	segment   string // ex. 301 from [301-1]
 	timestamp string // string unix timestamp
+
+	// set for structured csvlog and jsonlog entries that don't need their log_line_prefix parsed
+	fields *db.LogFields
+
+	// the server's log_line_prefix - set by the logs server before the line is parsed
+	prefix *LogLinePrefix
 }
 
 func parseSyslogLine(line string) []*SyslogLine {
@@ -83,6 +93,24 @@ func parseSyslogLine(line string) []*SyslogLine {
 	return syslogLines
 }
 
+// parses a single decoded logplex frame and returns its segment part - ex. 2 from [301-2]
+func parseSyslogFrame(frame string) (*SyslogLine, int) {
+	namedMatches := regexMatchSyslogLine(frame)
+	if namedMatches == nil || namedMatches["message"] == "" {
+		return nil, 0
+	}
+
+	part, _ := strconv.Atoi(namedMatches["part"])
+
+	return &SyslogLine{
+		color:     namedMatches["color"],
+		message:   namedMatches["message"],
+		process:   namedMatches["process"],
+		segment:   namedMatches["segment"],
+		timestamp: namedMatches["timestamp"],
+	}, part
+}
+
 func regexMatchSyslogLine(line string) map[string]string {
 	match := logLineRegex.FindStringSubmatch(line)
 	if match == nil {
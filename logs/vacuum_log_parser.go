@@ -0,0 +1,114 @@
+package logs
+
+import (
+	"agent/db"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//
+// These functions parse the automatic vacuum and analyze entries logged when log_autovacuum_min_duration is set.
+// Heroku splits each detail line into its own segment part so the details are matched anywhere in the message.
+//
+
+// ex. LOG:  automatic aggressive vacuum to prevent wraparound of table "db.public.events": index scans: 1
+var vacuumHeaderRegex = regexp.MustCompile(`^LOG:\s+automatic (?P<aggressive>aggressive )?(?P<kind>vacuum|analyze) (?P<wraparound>to prevent wraparound )?of table "(?P<table>[^"]+)"`)
+
+var vacuumIndexScansRegex = regexp.MustCompile(`index scans: (\d+)`)
+var vacuumPagesRegex = regexp.MustCompile(`pages: (\d+) removed, (\d+) remain`)
+var vacuumTuplesRegex = regexp.MustCompile(`tuples: (\d+) removed, (\d+) remain, (\d+) are dead but not yet removable`)
+
+// postgres 16 renamed misses to reads
+var vacuumBufferUsageRegex = regexp.MustCompile(`buffer usage: (\d+) hits, (\d+) (?:misses|reads), (\d+) dirtied`)
+var vacuumWalUsageRegex = regexp.MustCompile(`WAL usage: (\d+) records, (\d+) full page images, (\d+) bytes`)
+var vacuumRatesRegex = regexp.MustCompile(`avg read rate: ([\d.]+) MB/s, avg write rate: ([\d.]+) MB/s`)
+var vacuumSystemUsageRegex = regexp.MustCompile(`system usage: CPU: user: ([\d.]+) s, system: ([\d.]+) s, elapsed: ([\d.]+) s`)
+
+func parseVacuumSyslogLine(line *SyslogLine, message string, fields db.LogFields, timestamp int64) *ParsedLogLine {
+	captureGroups := matchRegex(vacuumHeaderRegex, message)
+	if len(captureGroups) == 0 {
+		return nil
+	}
+
+	database, schema, table := splitVacuumTableName(captureGroups["table"])
+
+	event := &db.VacuumEvent{
+		ServerConfigName: line.color,
+		Kind:             captureGroups["kind"],
+		Aggressive:       captureGroups["aggressive"] != "",
+		Wraparound:       captureGroups["wraparound"] != "",
+		Database:         database,
+		Schema:           schema,
+		Table:            table,
+		Fields:           fields,
+		MeasuredAt:       timestamp,
+	}
+
+	if values := matchInts(vacuumIndexScansRegex, message); values != nil {
+		event.IndexScans = values[0]
+	}
+	if values := matchInts(vacuumPagesRegex, message); values != nil {
+		event.PagesRemoved, event.PagesRemaining = values[0], values[1]
+	}
+	if values := matchInts(vacuumTuplesRegex, message); values != nil {
+		event.TuplesRemoved, event.TuplesRemaining, event.TuplesDeadNotRemovable = values[0], values[1], values[2]
+	}
+	if values := matchInts(vacuumBufferUsageRegex, message); values != nil {
+		event.BufferHits, event.BufferMisses, event.BufferDirtied = values[0], values[1], values[2]
+	}
+	if values := matchInts(vacuumWalUsageRegex, message); values != nil {
+		event.WalRecords, event.WalFullPageImages, event.WalBytes = values[0], values[1], values[2]
+	}
+	if values := matchFloats(vacuumRatesRegex, message); values != nil {
+		event.AvgReadRateMBs, event.AvgWriteRateMBs = values[0], values[1]
+	}
+	if values := matchFloats(vacuumSystemUsageRegex, message); values != nil {
+		event.CpuUserSeconds, event.CpuSystemSeconds, event.ElapsedSeconds = values[0], values[1], values[2]
+	}
+
+	return &ParsedLogLine{
+		VacuumEvent: event,
+	}
+}
+
+// splits a qualified table name - ex. db.public.events
+func splitVacuumTableName(name string) (string, string, string) {
+	parts := strings.SplitN(name, ".", 3)
+	switch len(parts) {
+	case 3:
+		return parts[0], parts[1], parts[2]
+	case 2:
+		return "", parts[0], parts[1]
+	default:
+		return "", "", name
+	}
+}
+
+func matchInts(regex *regexp.Regexp, message string) []int64 {
+	match := regex.FindStringSubmatch(message)
+	if match == nil {
+		return nil
+	}
+
+	var values []int64
+	for _, value := range match[1:] {
+		parsed, _ := strconv.ParseInt(value, 10, 64)
+		values = append(values, parsed)
+	}
+	return values
+}
+
+func matchFloats(regex *regexp.Regexp, message string) []float64 {
+	match := regex.FindStringSubmatch(message)
+	if match == nil {
+		return nil
+	}
+
+	var values []float64
+	for _, value := range match[1:] {
+		parsed, _ := strconv.ParseFloat(value, 64)
+		values = append(values, parsed)
+	}
+	return values
+}
@@ -0,0 +1,92 @@
+package logs
+
+import (
+	"agent/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVacuumSyslogLine(t *testing.T) {
+	// heroku segment parts are stitched together without newlines
+	line := &SyslogLine{
+		color: "GREEN",
+		message: "sql_error_code = 00000 LOG:  automatic vacuum of table \"dbiabc12i21234.public.events\": index scans: 1" +
+			"pages: 12 removed, 1234 remain, 1234 scanned (100.00% of total)" +
+			"tuples: 5000 removed, 100000 remain, 25 are dead but not yet removable" +
+			"index \"events_pkey\": pages: 276 in total, 0 newly deleted, 0 currently deleted, 0 reusable" +
+			"avg read rate: 1.250 MB/s, avg write rate: 0.500 MB/s" +
+			"buffer usage: 2556 hits, 10 misses, 4 dirtied" +
+			"WAL usage: 11 records, 2 full page images, 2290 bytes" +
+			"system usage: CPU: user: 0.01 s, system: 0.02 s, elapsed: 1.53 s",
+		process:   "postgres.3811305",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	parsed := parseSqlSyslogLine(line)
+
+	assert.Equal(t, &db.VacuumEvent{
+		ServerConfigName:       "GREEN",
+		Kind:                   db.VacuumEventKindVacuum,
+		Database:               "dbiabc12i21234",
+		Schema:                 "public",
+		Table:                  "events",
+		IndexScans:             1,
+		PagesRemoved:           12,
+		PagesRemaining:         1234,
+		TuplesRemoved:          5000,
+		TuplesRemaining:        100000,
+		TuplesDeadNotRemovable: 25,
+		BufferHits:             2556,
+		BufferMisses:           10,
+		BufferDirtied:          4,
+		WalRecords:             11,
+		WalFullPageImages:      2,
+		WalBytes:               2290,
+		AvgReadRateMBs:         1.25,
+		AvgWriteRateMBs:        0.5,
+		CpuUserSeconds:         0.01,
+		CpuSystemSeconds:       0.02,
+		ElapsedSeconds:         1.53,
+		Fields:                 db.LogFields{SqlState: "00000"},
+		MeasuredAt:             1660188131,
+	}, parsed.VacuumEvent)
+}
+
+func TestParseVacuumSyslogLineAggressiveWraparound(t *testing.T) {
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   "sql_error_code = 00000 LOG:  automatic aggressive vacuum to prevent wraparound of table \"prod.public.users\": index scans: 0\n\tbuffer usage: 100 hits, 5 reads, 0 dirtied",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	event := parseSqlSyslogLine(line).VacuumEvent
+
+	assert.True(t, event.Aggressive)
+	assert.True(t, event.Wraparound)
+	assert.Equal(t, "users", event.Table)
+	assert.Equal(t, int64(5), event.BufferMisses)
+}
+
+func TestParseAnalyzeSyslogLine(t *testing.T) {
+	line := &SyslogLine{
+		color:     "GREEN",
+		message:   "sql_error_code = 00000 LOG:  automatic analyze of table \"dbiabc12i21234.public.stats\" system usage: CPU: user: 0.46 s, system: 0.12 s, elapsed: 38.04 s",
+		timestamp: "2022-08-11T03:22:11+00:00",
+	}
+
+	event := parseSqlSyslogLine(line).VacuumEvent
+
+	assert.Equal(t, db.VacuumEventKindAnalyze, event.Kind)
+	assert.Equal(t, "stats", event.Table)
+	assert.Equal(t, 38.04, event.ElapsedSeconds)
+	assert.False(t, event.Aggressive)
+}
+
+func TestSplitVacuumTableName(t *testing.T) {
+	database, schema, table := splitVacuumTableName("prod.public.events")
+	assert.Equal(t, []string{"prod", "public", "events"}, []string{database, schema, table})
+
+	database, schema, table = splitVacuumTableName("public.events")
+	assert.Equal(t, []string{"", "public", "events"}, []string{database, schema, table})
+}
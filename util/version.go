@@ -26,6 +26,11 @@ func VersionGreaterThanOrEqual(v1 string, v2 string) bool {
 	return c >= 0
 }
 
+func VersionValid(v string) bool {
+	_, err := version.NewVersion(v)
+	return err == nil
+}
+
 // returns -2 for invalid versions
 func VersionCompare(v1 string, v2 string) (int, error) {
 	version1, err := version.NewVersion(v1)